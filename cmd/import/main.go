@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/erp_app/config"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/service"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// usage: import [-entity category|ingredient|menu|recipe] [-format csv|xlsx] [-dry-run] [-actor name] file
+func main() {
+	entity := flag.String("entity", "", "entity of a csv file or of the first xlsx sheet; empty imports xlsx sheets by name")
+	format := flag.String("format", "", "csv or xlsx, guessed from the file extension when empty")
+	dryRun := flag.Bool("dry-run", false, "report what would change without saving it")
+	actor := flag.String("actor", "import", "actor recorded in the audit log")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("expected exactly one file to import")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	db := database.SetDb(cfg.Database)
+
+	storage, err := libraries.NewStorage(cfg.Storage)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer file.Close()
+
+	if *format == "" {
+		*format = helper.SpreadsheetFormat(file.Name())
+	}
+
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	categoryRepository := repository.NewCategoryRepository(db)
+	ingredientRepository := repository.NewIngredientRepository(db)
+	menuRepository := repository.NewMenuRepository(db)
+	recipeRepository := repository.NewRecipeRepository(db)
+	transactionManager := repository.NewTransactionManager(db)
+
+	importService := service.NewImportService(
+		transactionManager,
+		categoryRepository,
+		ingredientRepository,
+		menuRepository,
+		recipeRepository,
+		service.NewCategoryService(transactionManager, categoryRepository, auditService, storage),
+		service.NewIngredientService(transactionManager, ingredientRepository, repository.NewIngredientGroupRepository(db), auditService),
+		service.NewMenuService(transactionManager, menuRepository, categoryRepository, auditService, storage),
+		service.NewRecipeService(transactionManager, recipeRepository, repository.NewRecipeVersionRepository(db), menuRepository, ingredientRepository, auditService, cfg.Recipe.DuplicateLines),
+	)
+
+	importResponse, err := importService.Import(request.ImportRequest{
+		Entity: *entity,
+		Format: *format,
+		DryRun: *dryRun,
+		Actor:  *actor,
+		File:   file,
+	})
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ENTITY\tROW\tACTION\tID\tERRORS")
+	for _, row := range importResponse.Rows {
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%d\t%s\n", row.Entity, row.Row, row.Action, row.Id, strings.Join(row.Errors, "; "))
+	}
+	writer.Flush()
+
+	mode := "imported"
+	if importResponse.DryRun {
+		mode = "dry run, nothing saved"
+	}
+
+	fmt.Printf("\n%s: %d created, %d updated, %d unchanged, %d rejected\n", mode, importResponse.Created, importResponse.Updated, importResponse.Unchanged, importResponse.Rejected)
+
+	if importResponse.Rejected > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"github.com/erp_app/config"
+	"github.com/erp_app/database"
+	"log"
+	"strconv"
+)
+
+// usage: migrate [up | down [steps]]
+func main() {
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	switch flag.Arg(0) {
+	case "", "up":
+		err = database.Migrate(db)
+	case "down":
+		steps := 1
+		if flag.Arg(1) != "" {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil {
+				log.Fatal("steps must be a number")
+			}
+		}
+		err = database.Rollback(db, steps)
+	default:
+		log.Fatal("unknown command " + flag.Arg(0) + ", expected up or down")
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
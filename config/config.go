@@ -0,0 +1,395 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Database Database `yaml:"database"`
+	Storage  Storage  `yaml:"storage"`
+	Recipe   Recipe   `yaml:"recipe"`
+}
+
+type Database struct {
+	Driver          string        `yaml:"driver"`
+	TestDriver      string        `yaml:"test_driver"`
+	AutoMigrate     bool          `yaml:"auto_migrate"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	TestName        string        `yaml:"test_name"`
+	TLS             string        `yaml:"tls"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	Timezone        string        `yaml:"timezone"`
+	LogLevel        string        `yaml:"log_level"`
+}
+
+// Storage is where uploaded images are kept. Files of the local driver are
+// written below Path and served from BaseUrl. The s3 driver writes to Bucket
+// on any S3 compatible Endpoint (MinIO for development) and serves from
+// BaseUrl when it is an absolute URL, from Endpoint/Bucket otherwise.
+type Storage struct {
+	Driver         string `yaml:"driver"`
+	Path           string `yaml:"path"`
+	BaseUrl        string `yaml:"base_url"`
+	Endpoint       string `yaml:"endpoint"`
+	Bucket         string `yaml:"bucket"`
+	Region         string `yaml:"region"`
+	AccessKey      string `yaml:"access_key"`
+	SecretKey      string `yaml:"secret_key"`
+	MaxUploadSize  int64  `yaml:"max_upload_size"`
+	ThumbnailWidth int    `yaml:"thumbnail_width"`
+}
+
+// Recipe holds the rules for recipe lines. DuplicateLines is what adding an
+// ingredient that is already in the recipe of a menu does: reject it, or
+// merge the quantity into the existing line.
+type Recipe struct {
+	DuplicateLines string `yaml:"duplicate_lines"`
+}
+
+var drivers = []string{"mysql", "postgres", "sqlite"}
+
+var tlsModes = []string{"false", "true", "skip-verify", "preferred"}
+
+var logLevels = []string{"silent", "error", "warn", "info"}
+
+var storageDrivers = []string{"local", "s3"}
+
+var duplicateLineModes = []string{"reject", "merge"}
+
+func Default() Config {
+	return Config{
+		Database: Database{
+			Driver:          "mysql",
+			TestDriver:      "sqlite",
+			Host:            "127.0.0.1",
+			User:            "root",
+			Name:            "erp",
+			TestName:        "erp_test",
+			TLS:             "false",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+			Timezone:        "Local",
+			LogLevel:        "info",
+		},
+		Storage: Storage{
+			Driver:         "local",
+			Path:           "public/uploads",
+			BaseUrl:        "/uploads",
+			Region:         "us-east-1",
+			MaxUploadSize:  5 << 20,
+			ThumbnailWidth: 320,
+		},
+		Recipe: Recipe{
+			DuplicateLines: "reject",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file named by
+// CONFIG_FILE (config.yaml when unset), the .env file and finally the process
+// environment, each layer overriding the previous one.
+func Load() (Config, error) {
+	cfg := Default()
+
+	err := godotenv.Load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("load .env: %w", err)
+	}
+
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile == "" {
+		configFile = "config.yaml"
+	}
+
+	err = loadYaml(configFile, &cfg)
+	if err != nil {
+		return cfg, err
+	}
+
+	err = loadEnv(&cfg)
+	if err != nil {
+		return cfg, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func loadYaml(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && os.Getenv("CONFIG_FILE") == "" {
+			return nil
+		}
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	err = yaml.Unmarshal(content, cfg)
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	db := &cfg.Database
+
+	setString("DB_DRIVER", &db.Driver)
+	setString("DB_TEST_DRIVER", &db.TestDriver)
+	setString("DB_HOST", &db.Host)
+	setString("DB_USER", &db.User)
+	setString("DB_PASSWORD", &db.Password)
+	setString("DB_NAME", &db.Name)
+	setString("DB_TEST_NAME", &db.TestName)
+	setString("DB_TLS", &db.TLS)
+	setString("DB_TIMEZONE", &db.Timezone)
+	setString("DB_LOG_LEVEL", &db.LogLevel)
+
+	err := setInt("DB_PORT", &db.Port)
+	if err != nil {
+		return err
+	}
+
+	err = setInt("DB_MAX_OPEN_CONNS", &db.MaxOpenConns)
+	if err != nil {
+		return err
+	}
+
+	err = setInt("DB_MAX_IDLE_CONNS", &db.MaxIdleConns)
+	if err != nil {
+		return err
+	}
+
+	err = setDuration("DB_CONN_MAX_LIFETIME", &db.ConnMaxLifetime)
+	if err != nil {
+		return err
+	}
+
+	err = setBool("DB_AUTO_MIGRATE", &db.AutoMigrate)
+	if err != nil {
+		return err
+	}
+
+	storage := &cfg.Storage
+
+	setString("STORAGE_DRIVER", &storage.Driver)
+	setString("STORAGE_PATH", &storage.Path)
+	setString("STORAGE_BASE_URL", &storage.BaseUrl)
+	setString("STORAGE_ENDPOINT", &storage.Endpoint)
+	setString("STORAGE_BUCKET", &storage.Bucket)
+	setString("STORAGE_REGION", &storage.Region)
+	setString("STORAGE_ACCESS_KEY", &storage.AccessKey)
+	setString("STORAGE_SECRET_KEY", &storage.SecretKey)
+
+	err = setInt64("STORAGE_MAX_UPLOAD_SIZE", &storage.MaxUploadSize)
+	if err != nil {
+		return err
+	}
+
+	err = setInt("STORAGE_THUMBNAIL_WIDTH", &storage.ThumbnailWidth)
+	if err != nil {
+		return err
+	}
+
+	setString("RECIPE_DUPLICATE_LINES", &cfg.Recipe.DuplicateLines)
+
+	return nil
+}
+
+func setString(key string, target *string) {
+	value, ok := os.LookupEnv(key)
+	if ok {
+		*target = value
+	}
+}
+
+func setInt(key string, target *int) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a number: %w", key, err)
+	}
+
+	*target = number
+	return nil
+}
+
+func setInt64(key string, target *int64) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s must be a number: %w", key, err)
+	}
+
+	*target = number
+	return nil
+}
+
+func setDuration(key string, target *time.Duration) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a duration such as 5m: %w", key, err)
+	}
+
+	*target = duration
+	return nil
+}
+
+func setBool(key string, target *bool) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be true or false: %w", key, err)
+	}
+
+	*target = flag
+	return nil
+}
+
+func (cfg Config) Validate() error {
+	var errorMessages []string
+	db := cfg.Database
+
+	if !contains(drivers, db.Driver) {
+		errorMessages = append(errorMessages, "database driver must be one of "+strings.Join(drivers, ", "))
+	}
+
+	if !contains(drivers, db.TestDriver) {
+		errorMessages = append(errorMessages, "database test driver must be one of "+strings.Join(drivers, ", "))
+	}
+
+	if db.Driver != "sqlite" && db.Host == "" {
+		errorMessages = append(errorMessages, "database host is required")
+	}
+
+	if db.Port < 0 || db.Port > 65535 {
+		errorMessages = append(errorMessages, "database port must be between 1 and 65535, or 0 for the driver default")
+	}
+
+	if db.Driver != "sqlite" && db.User == "" {
+		errorMessages = append(errorMessages, "database user is required")
+	}
+
+	if db.Name == "" {
+		errorMessages = append(errorMessages, "database name is required")
+	}
+
+	if !contains(tlsModes, db.TLS) {
+		errorMessages = append(errorMessages, "database tls must be one of "+strings.Join(tlsModes, ", "))
+	}
+
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 {
+		errorMessages = append(errorMessages, "database connection pool limits must not be negative")
+	}
+
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		errorMessages = append(errorMessages, "database max idle connections must not exceed max open connections")
+	}
+
+	if db.ConnMaxLifetime < 0 {
+		errorMessages = append(errorMessages, "database connection max lifetime must not be negative")
+	}
+
+	_, err := time.LoadLocation(db.Timezone)
+	if err != nil {
+		errorMessages = append(errorMessages, "database timezone is invalid: "+err.Error())
+	}
+
+	if !contains(logLevels, db.LogLevel) {
+		errorMessages = append(errorMessages, "database log level must be one of "+strings.Join(logLevels, ", "))
+	}
+
+	storage := cfg.Storage
+
+	if !contains(storageDrivers, storage.Driver) {
+		errorMessages = append(errorMessages, "storage driver must be one of "+strings.Join(storageDrivers, ", "))
+	}
+
+	if storage.Driver == "local" && storage.Path == "" {
+		errorMessages = append(errorMessages, "storage path is required")
+	}
+
+	if storage.Driver == "s3" {
+		endpoint, err := url.Parse(storage.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			errorMessages = append(errorMessages, "storage endpoint must be an http or https url")
+		}
+
+		if storage.Bucket == "" {
+			errorMessages = append(errorMessages, "storage bucket is required")
+		}
+
+		if storage.Region == "" {
+			errorMessages = append(errorMessages, "storage region is required")
+		}
+
+		if storage.AccessKey == "" || storage.SecretKey == "" {
+			errorMessages = append(errorMessages, "storage access key and secret key are required")
+		}
+	}
+
+	if storage.MaxUploadSize <= 0 {
+		errorMessages = append(errorMessages, "storage max upload size must be positive")
+	}
+
+	if storage.ThumbnailWidth < 16 || storage.ThumbnailWidth > 2048 {
+		errorMessages = append(errorMessages, "storage thumbnail width must be between 16 and 2048")
+	}
+
+	if !contains(duplicateLineModes, cfg.Recipe.DuplicateLines) {
+		errorMessages = append(errorMessages, "recipe duplicate lines must be one of "+strings.Join(duplicateLineModes, ", "))
+	}
+
+	if len(errorMessages) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(errorMessages, "; "))
+	}
+
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type AuditController struct {
+	auditService service.AuditService
+}
+
+func NewAuditController(auditService service.AuditService) *AuditController {
+	return &AuditController{auditService: auditService}
+}
+
+func (auditController *AuditController) GetAll(ctx echo.Context) error {
+	getAllAuditRequest := request.GetAllAuditRequest{}
+	err := ctx.Bind(&getAllAuditRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get audit log", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&getAllAuditRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get audit log", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	format := helper.ExportFormat(ctx)
+	if format != "" {
+		return export(ctx, format, "audit", []string{"id", "actor", "entity", "entity_id", "action", "changes", "created_at"}, func(write func(record interface{}, row []string) error) error {
+			return auditController.auditService.Stream(getAllAuditRequest, func(auditLog response.AuditResponse) error {
+				return write(auditLog, []string{exportInt(auditLog.Id), auditLog.Actor, auditLog.Entity, exportInt(auditLog.EntityId), auditLog.Action, string(auditLog.Changes), exportTime(auditLog.CreatedAt)})
+			})
+		})
+	}
+
+	listAuditResponse, err := auditController.auditService.GetAll(getAllAuditRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get audit log", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get audit log", listAuditResponse)
+	return ctx.JSON(200, apiResponse)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type BulkController struct {
+	bulkService service.BulkService
+}
+
+func NewBulkController(bulkService service.BulkService) *BulkController {
+	return &BulkController{bulkService: bulkService}
+}
+
+func (bulkController *BulkController) CreateCategories(ctx echo.Context) error {
+	return handleBulk[request.CreateRequestCategory](ctx, "create categories", bulkController.bulkService.CreateCategories)
+}
+
+func (bulkController *BulkController) UpdateCategories(ctx echo.Context) error {
+	return handleBulk[request.BulkUpdateCategoryItem](ctx, "update categories", bulkController.bulkService.UpdateCategories)
+}
+
+func (bulkController *BulkController) DeleteCategories(ctx echo.Context) error {
+	return handleBulk[request.BulkDeleteItem](ctx, "delete categories", bulkController.bulkService.DeleteCategories)
+}
+
+func (bulkController *BulkController) CreateIngredients(ctx echo.Context) error {
+	return handleBulk[request.CreateRequestIngredient](ctx, "create ingredients", bulkController.bulkService.CreateIngredients)
+}
+
+func (bulkController *BulkController) UpdateIngredients(ctx echo.Context) error {
+	return handleBulk[request.BulkUpdateIngredientItem](ctx, "update ingredients", bulkController.bulkService.UpdateIngredients)
+}
+
+func (bulkController *BulkController) DeleteIngredients(ctx echo.Context) error {
+	return handleBulk[request.BulkDeleteItem](ctx, "delete ingredients", bulkController.bulkService.DeleteIngredients)
+}
+
+func (bulkController *BulkController) CreateMenus(ctx echo.Context) error {
+	return handleBulk[request.CreateMenuRequest](ctx, "create menus", bulkController.bulkService.CreateMenus)
+}
+
+func (bulkController *BulkController) UpdateMenus(ctx echo.Context) error {
+	return handleBulk[request.BulkUpdateMenuItem](ctx, "update menus", bulkController.bulkService.UpdateMenus)
+}
+
+func (bulkController *BulkController) DeleteMenus(ctx echo.Context) error {
+	return handleBulk[request.BulkDeleteItem](ctx, "delete menus", bulkController.bulkService.DeleteMenus)
+}
+
+func (bulkController *BulkController) CreateRecipes(ctx echo.Context) error {
+	return handleBulk[request.BulkCreateRecipeItem](ctx, "create menu recipes", bulkController.bulkService.CreateRecipes)
+}
+
+func (bulkController *BulkController) UpdateRecipes(ctx echo.Context) error {
+	return handleBulk[request.BulkUpdateRecipeItem](ctx, "update menu recipes", bulkController.bulkService.UpdateRecipes)
+}
+
+func (bulkController *BulkController) DeleteRecipes(ctx echo.Context) error {
+	return handleBulk[request.BulkDeleteRecipeItem](ctx, "delete menu recipes", bulkController.bulkService.DeleteRecipes)
+}
+
+// handleBulk binds a bulk request, validates every item on its own so each
+// one gets its own errors, and runs it. A best-effort request with failed
+// items answers 207 Multi-Status.
+func handleBulk[T any](ctx echo.Context, action string, run func(bulkRequest request.BulkRequest[T]) (response.BulkResponse, error)) error {
+	bulkRequest := request.BulkRequest[T]{}
+	err := ctx.Bind(&bulkRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed bulk "+action, err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	bulkRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&bulkRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed bulk "+action, errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	bulkRequest.Errors = map[int][]string{}
+	for i := range bulkRequest.Items {
+		err = ctx.Validate(&bulkRequest.Items[i])
+		if err != nil {
+			bulkRequest.Errors[i] = helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		}
+	}
+
+	bulkResponse, err := run(bulkRequest)
+	if err != nil {
+		status := 400
+		if len(bulkRequest.Errors) > 0 {
+			status = 422
+		}
+
+		apiResponse := response.NewApiResponse("error", "failed bulk "+action, bulkResponse)
+		return ctx.JSON(status, apiResponse)
+	}
+
+	status := 200
+	if bulkResponse.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success bulk "+action, bulkResponse)
+	return ctx.JSON(status, apiResponse)
+}
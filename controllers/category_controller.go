@@ -35,6 +35,15 @@ func (categoryController *CategoryController) GetAll(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
+	format := helper.ExportFormat(ctx)
+	if format != "" {
+		return export(ctx, format, "categories", []string{"id", "name", "version", "created_at", "updated_at"}, func(write func(record interface{}, row []string) error) error {
+			return categoryController.CategoryService.Stream(getAllRequestCategory, func(category response.CategoryResponse) error {
+				return write(category, []string{exportInt(category.Id), category.Name, exportInt(category.Version), exportTime(category.CreatedAt), exportTime(category.UpdatedAt)})
+			})
+		})
+	}
+
 	listCategoryResponse, err := categoryController.CategoryService.GetAll(getAllRequestCategory)
 	if err != nil {
 		fmt.Println("error service")
@@ -46,6 +55,17 @@ func (categoryController *CategoryController) GetAll(ctx echo.Context) error {
 	return ctx.JSON(200, apiResponse)
 }
 
+func (categoryController *CategoryController) Tree(ctx echo.Context) error {
+	listCategoryTreeResponse, err := categoryController.CategoryService.Tree()
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get category tree", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get category tree", listCategoryTreeResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
 func (categoryController *CategoryController) Get(ctx echo.Context) error {
 	getDetailRequestCategory := request.GetDetailRequestCategory{}
 	err := ctx.Bind(&getDetailRequestCategory)
@@ -67,6 +87,7 @@ func (categoryController *CategoryController) Get(ctx echo.Context) error {
 		return ctx.JSON(400, apiResponse)
 	}
 
+	helper.SetETag(ctx, categoryResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success get detail category", categoryResponse)
 	return ctx.JSON(200, apiResponse)
 }
@@ -79,6 +100,8 @@ func (categoryController *CategoryController) Delete(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	deleteRequestCategory.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&deleteRequestCategory)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -86,10 +109,16 @@ func (categoryController *CategoryController) Delete(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
+	deleteRequestCategory.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete category", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
 	err = categoryController.CategoryService.Delete(deleteRequestCategory)
 	if err != nil {
 		apiResponse := response.NewApiResponse("error", "failed delete category", err.Error())
-		return ctx.JSON(400, apiResponse)
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
 	apiResponse := response.NewApiResponse("ok", "success delete category", nil)
@@ -104,6 +133,8 @@ func (categoryController *CategoryController) Create(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	createRequestCategory.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&createRequestCategory)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -113,10 +144,11 @@ func (categoryController *CategoryController) Create(ctx echo.Context) error {
 
 	categoryResponse, err := categoryController.CategoryService.Create(createRequestCategory)
 	if err != nil {
-		apiResponse := response.NewApiResponse("error", "failed create category", err.Error())
-		return ctx.JSON(400, apiResponse)
+		apiResponse := response.NewApiResponse("error", "failed create category", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	helper.SetETag(ctx, categoryResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success create category", categoryResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -129,6 +161,8 @@ func (categoryController *CategoryController) Update(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	updateRequestCategory.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&updateRequestCategory)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -136,12 +170,46 @@ func (categoryController *CategoryController) Update(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
-	categoryResponse, err := categoryController.CategoryService.Update(updateRequestCategory)
+	updateRequestCategory.Version, err = helper.IfMatch(ctx)
 	if err != nil {
 		apiResponse := response.NewApiResponse("error", "failed update category", err.Error())
-		return ctx.JSON(400, apiResponse)
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	categoryResponse, err := categoryController.CategoryService.Update(updateRequestCategory)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update category", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, categoryResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success update category", categoryResponse)
 	return ctx.JSON(201, apiResponse)
 }
+
+func (categoryController *CategoryController) Reorder(ctx echo.Context) error {
+	reorderCategoryRequest := request.ReorderCategoryRequest{}
+	err := ctx.Bind(&reorderCategoryRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed reorder category", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	reorderCategoryRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&reorderCategoryRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed reorder category", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	listCategoryResponse, err := categoryController.CategoryService.Reorder(reorderCategoryRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed reorder category", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success reorder category", listCategoryResponse)
+	return ctx.JSON(200, apiResponse)
+}
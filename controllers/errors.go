@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"net/http"
+)
+
+// errorStatus maps errors with a well known meaning to their HTTP status and
+// falls back to the status the handler used for every other error.
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, helper.ErrIfMatchRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, helper.ErrIfMatchInvalid), errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.As(err, new(*service.DuplicateNameError)), errors.Is(err, service.ErrRecipeLinesUnmergeable):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrRecipeVersionPast):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrRecipeNotInMenu), errors.Is(err, service.ErrRecipeVersionNotInMenu):
+		return http.StatusNotFound
+	case errors.Is(err, helper.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, helper.ErrImageType):
+		return http.StatusUnsupportedMediaType
+	default:
+		return fallback
+	}
+}
+
+// errorData is what an error response carries: the message, and for a name
+// that is already taken the id of the record that has it.
+func errorData(err error) interface{} {
+	duplicate := &service.DuplicateNameError{}
+	if errors.As(err, &duplicate) {
+		return response.DuplicateResponse{Error: err.Error(), ExistingId: duplicate.ExistingId}
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// export streams a list endpoint as csv, xlsx or ndjson. Once data has been
+// sent the status can no longer change, so a later error only cuts the
+// download short and is logged. Before that a bad filter is a 400 and any
+// other failure a 500.
+func export(ctx echo.Context, format string, name string, header []string, stream func(write func(record interface{}, row []string) error) error) error {
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, helper.ExportContentType(format))
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+"."+format))
+
+	exportWriter, err := helper.NewExportWriter(res, format, name, header)
+	if err == nil {
+		err = stream(exportWriter.Write)
+		if err == nil {
+			err = exportWriter.Close()
+		} else {
+			exportWriter.Discard()
+		}
+	}
+
+	if err != nil {
+		if !res.Committed {
+			res.Header().Del(echo.HeaderContentDisposition)
+			res.Header().Del(echo.HeaderContentType)
+			apiResponse := response.NewApiResponse("error", "failed export "+name, err.Error())
+			return ctx.JSON(exportErrorStatus(err), apiResponse)
+		}
+
+		ctx.Logger().Error(err)
+	}
+
+	return nil
+}
+
+// exportErrorStatus tells the errors of the request, which the list
+// endpoints answer with 400, from failures of the server.
+func exportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, helper.ErrUnsupportedFormat), errors.As(err, new(*time.ParseError)),
+		errors.Is(err, service.ErrUnknownAllergen), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusBadRequest
+	default:
+		return errorStatus(err, http.StatusInternalServerError)
+	}
+}
+
+func exportTime(value time.Time) string {
+	return value.Format(time.RFC3339)
+}
+
+func exportInt(value int) string {
+	return strconv.Itoa(value)
+}
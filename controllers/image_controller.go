@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+)
+
+// multipartOverhead is what an upload body may hold on top of the image: the
+// boundaries and headers of the form parts.
+const multipartOverhead = 16 << 10
+
+type ImageController struct {
+	imageService  service.ImageService
+	maxUploadSize int64
+}
+
+func NewImageController(imageService service.ImageService, maxUploadSize int64) *ImageController {
+	return &ImageController{
+		imageService:  imageService,
+		maxUploadSize: maxUploadSize,
+	}
+}
+
+func (imageController *ImageController) PutMenu(ctx echo.Context) error {
+	uploadImageRequest, status, err := imageController.bindUploadImage(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed upload menu image", errorMessage(err))
+		return ctx.JSON(status, apiResponse)
+	}
+
+	menuResponse, err := imageController.imageService.PutMenuImage(uploadImageRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed upload menu image", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, menuResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success upload menu image", menuResponse)
+	return ctx.JSON(201, apiResponse)
+}
+
+func (imageController *ImageController) DeleteMenu(ctx echo.Context) error {
+	deleteImageRequest, status, err := bindDeleteImage(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu image", errorMessage(err))
+		return ctx.JSON(status, apiResponse)
+	}
+
+	menuResponse, err := imageController.imageService.DeleteMenuImage(deleteImageRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu image", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, menuResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success delete menu image", menuResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (imageController *ImageController) PutCategory(ctx echo.Context) error {
+	uploadImageRequest, status, err := imageController.bindUploadImage(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed upload category image", errorMessage(err))
+		return ctx.JSON(status, apiResponse)
+	}
+
+	categoryResponse, err := imageController.imageService.PutCategoryImage(uploadImageRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed upload category image", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, categoryResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success upload category image", categoryResponse)
+	return ctx.JSON(201, apiResponse)
+}
+
+func (imageController *ImageController) DeleteCategory(ctx echo.Context) error {
+	deleteImageRequest, status, err := bindDeleteImage(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete category image", errorMessage(err))
+		return ctx.JSON(status, apiResponse)
+	}
+
+	categoryResponse, err := imageController.imageService.DeleteCategoryImage(deleteImageRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete category image", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, categoryResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success delete category image", categoryResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+// bindUploadImage binds the path only and streams the "image" part of the
+// multipart body, so the upload limit applies while reading instead of after
+// the whole form has been buffered. The body as a whole is capped as well, so
+// parts before the image cannot be used to send an unlimited amount of data.
+func (imageController *ImageController) bindUploadImage(ctx echo.Context) (request.UploadImageRequest, int, error) {
+	uploadImageRequest := request.UploadImageRequest{}
+	err := (&echo.DefaultBinder{}).BindPathParams(ctx, &uploadImageRequest)
+	if err != nil {
+		return uploadImageRequest, 500, err
+	}
+
+	uploadImageRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&uploadImageRequest)
+	if err != nil {
+		return uploadImageRequest, 422, err
+	}
+
+	uploadImageRequest.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		return uploadImageRequest, errorStatus(err, 400), err
+	}
+
+	limit := imageController.maxUploadSize + multipartOverhead
+	body := &uploadBody{ReadCloser: http.MaxBytesReader(ctx.Response(), ctx.Request().Body, limit), limit: limit}
+	ctx.Request().Body = body
+
+	reader, err := ctx.Request().MultipartReader()
+	if err != nil {
+		return uploadImageRequest, 400, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return uploadImageRequest, 400, helper.ErrImageRequired
+		}
+		if body.exceeded {
+			return uploadImageRequest, errorStatus(helper.ErrImageTooLarge, 400), helper.ErrImageTooLarge
+		}
+		if err != nil {
+			return uploadImageRequest, 400, err
+		}
+
+		if part.FormName() == "image" {
+			uploadImageRequest.Image = part
+			return uploadImageRequest, 0, nil
+		}
+	}
+}
+
+// uploadBody reports a request body cut off by http.MaxBytesReader as
+// helper.ErrImageTooLarge, since the multipart reader does not keep the
+// original error.
+type uploadBody struct {
+	io.ReadCloser
+	read     int64
+	limit    int64
+	exceeded bool
+}
+
+func (body *uploadBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.read += int64(n)
+	if err != nil && err != io.EOF && body.read >= body.limit {
+		body.exceeded = true
+		return n, helper.ErrImageTooLarge
+	}
+
+	return n, err
+}
+
+func bindDeleteImage(ctx echo.Context) (request.DeleteImageRequest, int, error) {
+	deleteImageRequest := request.DeleteImageRequest{}
+	err := ctx.Bind(&deleteImageRequest)
+	if err != nil {
+		return deleteImageRequest, 500, err
+	}
+
+	deleteImageRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&deleteImageRequest)
+	if err != nil {
+		return deleteImageRequest, 422, err
+	}
+
+	deleteImageRequest.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		return deleteImageRequest, errorStatus(err, 400), err
+	}
+
+	return deleteImageRequest, 0, nil
+}
+
+// errorMessage formats validation errors the way every handler reports them.
+func errorMessage(err error) interface{} {
+	if validationErrors, ok := err.(validator.ValidationErrors); ok {
+		return helper.FormatErrorValidation(validationErrors)
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type ImportController struct {
+	importService service.ImportService
+}
+
+func NewImportController(importService service.ImportService) *ImportController {
+	return &ImportController{importService: importService}
+}
+
+func (importController *ImportController) Import(ctx echo.Context) error {
+	importRequest := request.ImportRequest{}
+	err := (&echo.DefaultBinder{}).BindQueryParams(ctx, &importRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed import", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	importRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&importRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed import", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed import", "file is required")
+		return ctx.JSON(422, apiResponse)
+	}
+
+	if importRequest.Format == "" {
+		importRequest.Format = helper.SpreadsheetFormat(fileHeader.Filename)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed import", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+	defer file.Close()
+
+	importRequest.File = file
+
+	importResponse, err := importController.importService.Import(importRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed import", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success import", importResponse)
+	return ctx.JSON(200, apiResponse)
+}
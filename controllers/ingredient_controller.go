@@ -33,6 +33,15 @@ func (ingredientController *IngredientController) GetAll(ctx echo.Context) error
 		return ctx.JSON(422, apiResponse)
 	}
 
+	format := helper.ExportFormat(ctx)
+	if format != "" {
+		return export(ctx, format, "ingredients", []string{"id", "name", "version", "created_at", "updated_at"}, func(write func(record interface{}, row []string) error) error {
+			return ingredientController.IngredientService.Stream(getAllRequestIngredient, func(ingredient response.IngredientResponse) error {
+				return write(ingredient, []string{exportInt(ingredient.Id), ingredient.Name, exportInt(ingredient.Version), exportTime(ingredient.CreatedAt), exportTime(ingredient.UpdatedAt)})
+			})
+		})
+	}
+
 	listIngredientResponse, err := ingredientController.IngredientService.GetAll(getAllRequestIngredient)
 	if err != nil {
 		apiResponse := response.NewApiResponse("error", "failed get all ingredient", err.Error())
@@ -64,6 +73,7 @@ func (ingredientController *IngredientController) Get(ctx echo.Context) error {
 		return ctx.JSON(400, apiResponse)
 	}
 
+	helper.SetETag(ctx, ingredientResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success get detail ingredient", ingredientResponse)
 	return ctx.JSON(200, apiResponse)
 }
@@ -77,6 +87,8 @@ func (ingredientController *IngredientController) Delete(ctx echo.Context) error
 		return ctx.JSON(500, apiResponse)
 	}
 
+	deleteRequestIngredient.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&deleteRequestIngredient)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -84,11 +96,17 @@ func (ingredientController *IngredientController) Delete(ctx echo.Context) error
 		return ctx.JSON(422, apiResponse)
 	}
 
+	deleteRequestIngredient.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete ingredient", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
 	err = ingredientController.IngredientService.Delete(deleteRequestIngredient)
 	if err != nil {
 		fmt.Println("error service")
 		apiResponse := response.NewApiResponse("error", "failed delete ingredient", err.Error())
-		return ctx.JSON(400, apiResponse)
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
 	apiResponse := response.NewApiResponse("ok", "success delete ingredient", nil)
@@ -103,6 +121,8 @@ func (ingredientController *IngredientController) Create(ctx echo.Context) error
 		return ctx.JSON(500, apiResponse)
 	}
 
+	createRequestIngredient.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&createRequestIngredient)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -112,10 +132,11 @@ func (ingredientController *IngredientController) Create(ctx echo.Context) error
 
 	ingredientResponse, err := ingredientController.IngredientService.Create(createRequestIngredient)
 	if err != nil {
-		apiResponse := response.NewApiResponse("error", "failed create ingredient", err.Error())
-		return ctx.JSON(400, apiResponse)
+		apiResponse := response.NewApiResponse("error", "failed create ingredient", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	helper.SetETag(ctx, ingredientResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success create ingredient", ingredientResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -128,6 +149,8 @@ func (ingredientController *IngredientController) Update(ctx echo.Context) error
 		return ctx.JSON(500, apiResponse)
 	}
 
+	updateRequestIngredient.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&updateRequestIngredient)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -135,12 +158,19 @@ func (ingredientController *IngredientController) Update(ctx echo.Context) error
 		return ctx.JSON(422, apiResponse)
 	}
 
-	ingredientResponse, err := ingredientController.IngredientService.Update(updateRequestIngredient)
+	updateRequestIngredient.Version, err = helper.IfMatch(ctx)
 	if err != nil {
 		apiResponse := response.NewApiResponse("error", "failed update ingredient", err.Error())
-		return ctx.JSON(400, apiResponse)
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	ingredientResponse, err := ingredientController.IngredientService.Update(updateRequestIngredient)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update ingredient", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	helper.SetETag(ctx, ingredientResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success update ingredient", ingredientResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type IngredientGroupController struct {
+	ingredientGroupService service.IngredientGroupService
+}
+
+func NewIngredientGroupController(ingredientGroupService service.IngredientGroupService) *IngredientGroupController {
+	return &IngredientGroupController{ingredientGroupService: ingredientGroupService}
+}
+
+func (ingredientGroupController *IngredientGroupController) GetAll(ctx echo.Context) error {
+	getAllIngredientGroupRequest := request.GetAllIngredientGroupRequest{}
+	err := ctx.Bind(&getAllIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get all ingredient group", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&getAllIngredientGroupRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get all ingredient group", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	listIngredientGroupResponse, err := ingredientGroupController.ingredientGroupService.GetAll(getAllIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get all ingredient group", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get all ingredient group", listIngredientGroupResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (ingredientGroupController *IngredientGroupController) Get(ctx echo.Context) error {
+	getIngredientGroupRequest := request.GetIngredientGroupRequest{}
+	err := ctx.Bind(&getIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get detail ingredient group", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&getIngredientGroupRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get detail ingredient group", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	ingredientGroupResponse, err := ingredientGroupController.ingredientGroupService.Get(getIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get detail ingredient group", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	helper.SetETag(ctx, ingredientGroupResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success get detail ingredient group", ingredientGroupResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (ingredientGroupController *IngredientGroupController) Create(ctx echo.Context) error {
+	createIngredientGroupRequest := request.CreateIngredientGroupRequest{}
+	err := ctx.Bind(&createIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed create ingredient group", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	createIngredientGroupRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&createIngredientGroupRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed create ingredient group", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	ingredientGroupResponse, err := ingredientGroupController.ingredientGroupService.Create(createIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed create ingredient group", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	helper.SetETag(ctx, ingredientGroupResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success create ingredient group", ingredientGroupResponse)
+	return ctx.JSON(201, apiResponse)
+}
+
+func (ingredientGroupController *IngredientGroupController) Update(ctx echo.Context) error {
+	updateIngredientGroupRequest := request.UpdateIngredientGroupRequest{}
+	err := ctx.Bind(&updateIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update ingredient group", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	updateIngredientGroupRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&updateIngredientGroupRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed update ingredient group", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	updateIngredientGroupRequest.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update ingredient group", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	ingredientGroupResponse, err := ingredientGroupController.ingredientGroupService.Update(updateIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update ingredient group", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, ingredientGroupResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success update ingredient group", ingredientGroupResponse)
+	return ctx.JSON(201, apiResponse)
+}
+
+func (ingredientGroupController *IngredientGroupController) Delete(ctx echo.Context) error {
+	deleteIngredientGroupRequest := request.DeleteIngredientGroupRequest{}
+	err := ctx.Bind(&deleteIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete ingredient group", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	deleteIngredientGroupRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&deleteIngredientGroupRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed delete ingredient group", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	deleteIngredientGroupRequest.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete ingredient group", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	err = ingredientGroupController.ingredientGroupService.Delete(deleteIngredientGroupRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete ingredient group", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success delete ingredient group", nil)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (ingredientGroupController *IngredientGroupController) CountSheet(ctx echo.Context) error {
+	listCountSheetResponse, err := ingredientGroupController.ingredientGroupService.CountSheet()
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get stock count sheet", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get stock count sheet", listCountSheetResponse)
+	return ctx.JSON(200, apiResponse)
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type MenuCloneController struct {
+	menuCloneService service.MenuCloneService
+}
+
+func NewMenuCloneController(menuCloneService service.MenuCloneService) *MenuCloneController {
+	return &MenuCloneController{menuCloneService: menuCloneService}
+}
+
+func (menuCloneController *MenuCloneController) Clone(ctx echo.Context) error {
+	cloneMenuRequest := request.CloneMenuRequest{}
+	err := ctx.Bind(&cloneMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed clone menu", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	cloneMenuRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&cloneMenuRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed clone menu", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	menuResponse, err := menuCloneController.menuCloneService.Clone(cloneMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed clone menu", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, menuResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success clone menu", menuResponse)
+	return ctx.JSON(201, apiResponse)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/erp_app/helper"
 	"github.com/erp_app/request"
@@ -28,6 +29,8 @@ func (menuController *MenuController) Delete(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	deleteMenuRequest.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&deleteMenuRequest)
 	if err != nil {
 		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
@@ -35,10 +38,16 @@ func (menuController *MenuController) Delete(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
+	deleteMenuRequest.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
 	err = menuController.menuService.Delete(deleteMenuRequest)
 	if err != nil {
 		apiResponse := response.NewApiResponse("error", "failed delete menu", err.Error())
-		return ctx.JSON(400, apiResponse)
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
 	apiResponse := response.NewApiResponse("ok", "success delete menu", nil)
@@ -54,6 +63,8 @@ func (menuController *MenuController) Create(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	createMenuRequest.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&createMenuRequest)
 	if err != nil {
 		fmt.Println("error validation")
@@ -65,10 +76,11 @@ func (menuController *MenuController) Create(ctx echo.Context) error {
 	menuResponse, err := menuController.menuService.Create(createMenuRequest)
 	if err != nil {
 		fmt.Println("error service")
-		apiResponse := response.NewApiResponse("error", "failed create menu", err.Error())
-		return ctx.JSON(400, apiResponse)
+		apiResponse := response.NewApiResponse("error", "failed create menu", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	helper.SetETag(ctx, menuResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success create menu", menuResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -83,6 +95,8 @@ func (menuController *MenuController) Update(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	updateMenuRequest.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&updateMenuRequest)
 	if err != nil {
 		fmt.Println("error validation")
@@ -91,13 +105,20 @@ func (menuController *MenuController) Update(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
+	updateMenuRequest.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update menu", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
 	menuResponse, err := menuController.menuService.Update(updateMenuRequest)
 	if err != nil {
 		fmt.Println("error service")
-		apiResponse := response.NewApiResponse("error", "failed create menu", err.Error())
-		return ctx.JSON(400, apiResponse)
+		apiResponse := response.NewApiResponse("error", "failed create menu", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	helper.SetETag(ctx, menuResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success update menu", menuResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -123,6 +144,7 @@ func (menuController *MenuController) Get(ctx echo.Context) error {
 		return ctx.JSON(400, apiResponse)
 	}
 
+	helper.SetETag(ctx, menuResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success get detail menu", menuResponse)
 	return ctx.JSON(200, apiResponse)
 }
@@ -135,6 +157,41 @@ func (menuController *MenuController) GetAll(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	err = ctx.Validate(&getAllMenuRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get menu", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	format := helper.ExportFormat(ctx)
+	if format != "" {
+		return export(ctx, format, "menus", []string{"menu_id", "menu", "category_id", "category", "recipe_id", "ingredient_id", "ingredient", "qty", "updated_at"}, func(write func(record interface{}, row []string) error) error {
+			// one row per recipe line; a menu without recipe lines still gets a row
+			return menuController.menuService.Stream(getAllMenuRequest, func(menu response.MenuResponse) error {
+				menuColumns := []string{exportInt(menu.Id), menu.Name, exportInt(menu.CategoryId), menu.Category.Name}
+				if len(menu.Ingredients) == 0 {
+					return write(menu, append(menuColumns, "", "", "", "", exportTime(menu.UpdatedAt)))
+				}
+
+				for i, recipe := range menu.Ingredients {
+					var record interface{}
+					if i == 0 {
+						record = menu
+					}
+
+					row := append(append([]string{}, menuColumns...), exportInt(recipe.RecipeId), exportInt(recipe.Id), recipe.Name, recipe.Qty, exportTime(menu.UpdatedAt))
+					err := write(record, row)
+					if err != nil {
+						return err
+					}
+				}
+
+				return nil
+			})
+		})
+	}
+
 	menuResponse, err := menuController.menuService.GetAll(getAllMenuRequest)
 	if err != nil {
 		apiResponse := response.NewApiResponse("error", "failed get menu", err.Error())
@@ -144,3 +201,137 @@ func (menuController *MenuController) GetAll(ctx echo.Context) error {
 	apiResponse := response.NewApiResponse("ok", "success get menu", menuResponse)
 	return ctx.JSON(200, apiResponse)
 }
+
+func (menuController *MenuController) Scale(ctx echo.Context) error {
+	scaleMenuRequest := request.ScaleMenuRequest{}
+	err := ctx.Bind(&scaleMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed scale menu", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&scaleMenuRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed scale menu", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	scaledMenuResponse, err := menuController.menuService.Scale(scaleMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed scale menu", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success scale menu", scaledMenuResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (menuController *MenuController) RecipeCard(ctx echo.Context) error {
+	getMenuRequest := request.GetMenuRequest{}
+	err := ctx.Bind(&getMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print recipe card", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&getMenuRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed print recipe card", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	recipeCard, err := menuController.menuService.RecipeCard(getMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print recipe card", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	document := &bytes.Buffer{}
+	err = helper.WriteRecipeCard(document, recipeCard)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print recipe card", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=\"recipe-card-%d.pdf\"", getMenuRequest.Id))
+	return ctx.Blob(200, helper.MIMEApplicationPdf, document.Bytes())
+}
+
+func (menuController *MenuController) NutritionLabel(ctx echo.Context) error {
+	getMenuRequest := request.GetMenuRequest{}
+	err := ctx.Bind(&getMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print nutrition label", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&getMenuRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed print nutrition label", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	menuResponse, err := menuController.menuService.Get(getMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print nutrition label", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	document := &bytes.Buffer{}
+	err = helper.WriteNutritionLabel(document, menuResponse)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print nutrition label", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=\"nutrition-label-%d.pdf\"", getMenuRequest.Id))
+	return ctx.Blob(200, helper.MIMEApplicationPdf, document.Bytes())
+}
+
+func (menuController *MenuController) MenuBook(ctx echo.Context) error {
+	listRecipeCard, err := menuController.menuService.MenuBook()
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print menu book", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	document := &bytes.Buffer{}
+	err = helper.WriteMenuBook(document, listRecipeCard)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed print menu book", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, "inline; filename=\"menu-book.pdf\"")
+	return ctx.Blob(200, helper.MIMEApplicationPdf, document.Bytes())
+}
+
+func (menuController *MenuController) Reorder(ctx echo.Context) error {
+	reorderMenuRequest := request.ReorderMenuRequest{}
+	err := ctx.Bind(&reorderMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed reorder menu", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	reorderMenuRequest.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&reorderMenuRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed reorder menu", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	listMenuResponse, err := menuController.menuService.Reorder(reorderMenuRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed reorder menu", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success reorder menu", listMenuResponse)
+	return ctx.JSON(200, apiResponse)
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type PurchasingController struct {
+	purchasingService service.PurchasingService
+}
+
+func NewPurchasingController(purchasingService service.PurchasingService) *PurchasingController {
+	return &PurchasingController{purchasingService: purchasingService}
+}
+
+func (purchasingController *PurchasingController) Requirements(ctx echo.Context) error {
+	purchasingRequest := request.PurchasingRequest{}
+	err := ctx.Bind(&purchasingRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed compute purchasing requirements", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&purchasingRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed compute purchasing requirements", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	purchasingResponse, err := purchasingController.purchasingService.Requirements(purchasingRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed compute purchasing requirements", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success compute purchasing requirements", purchasingResponse)
+	return ctx.JSON(200, apiResponse)
+}
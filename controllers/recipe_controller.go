@@ -28,6 +28,8 @@ func (recipeController *RecipeController) Add(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	createRecipeRequest.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&createRecipeRequest)
 	if err != nil {
 		fmt.Println("error validation")
@@ -39,10 +41,11 @@ func (recipeController *RecipeController) Add(ctx echo.Context) error {
 	menuResponse, err := recipeController.recipeService.Create(createRecipeRequest)
 	if err != nil {
 		fmt.Println("error service")
-		apiResponse := response.NewApiResponse("error", "failed create menu recipes", err.Error())
-		return ctx.JSON(500, apiResponse)
+		apiResponse := response.NewApiResponse("error", "failed create menu recipes", errorData(err))
+		return ctx.JSON(errorStatus(err, 500), apiResponse)
 	}
 
+	helper.SetETag(ctx, menuResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success create menu recipes", menuResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -57,6 +60,8 @@ func (recipeController *RecipeController) Update(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	req.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&req)
 	if err != nil {
 		fmt.Println("error validation")
@@ -65,13 +70,20 @@ func (recipeController *RecipeController) Update(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update menu recipes", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
 	menuResponse, err := recipeController.recipeService.Update(req)
 	if err != nil {
 		fmt.Println("error service")
-		apiResponse := response.NewApiResponse("error", "failed update menu recipes", err.Error())
-		return ctx.JSON(400, apiResponse)
+		apiResponse := response.NewApiResponse("error", "failed update menu recipes", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
+	helper.SetETag(ctx, menuResponse.Version)
 	apiResponse := response.NewApiResponse("ok", "success create menu recipes", menuResponse)
 	return ctx.JSON(201, apiResponse)
 }
@@ -85,6 +97,8 @@ func (recipeController *RecipeController) Delete(ctx echo.Context) error {
 		return ctx.JSON(500, apiResponse)
 	}
 
+	req.Actor = helper.Actor(ctx)
+
 	err = ctx.Validate(&req)
 	if err != nil {
 		fmt.Println("error validation")
@@ -93,13 +107,54 @@ func (recipeController *RecipeController) Delete(ctx echo.Context) error {
 		return ctx.JSON(422, apiResponse)
 	}
 
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu recipes", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
 	err = recipeController.recipeService.Delete(req)
 	if err != nil {
 		fmt.Println("error service")
 		apiResponse := response.NewApiResponse("error", "failed delete menu recipes", err.Error())
-		return ctx.JSON(400, apiResponse)
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
 	}
 
 	apiResponse := response.NewApiResponse("ok", "success delete menu recipes", nil)
 	return ctx.JSON(201, apiResponse)
 }
+
+func (recipeController *RecipeController) Replace(ctx echo.Context) error {
+	req := request.ReplaceRecipeRequest{}
+
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed replace menu recipe", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed replace menu recipe", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed replace menu recipe", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	menu, err := recipeController.recipeService.Replace(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed replace menu recipe", errorData(err))
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, menu.Version)
+	apiResponse := response.NewApiResponse("ok", "success replace menu recipe", menu.Ingredients)
+	return ctx.JSON(201, apiResponse)
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type RecipeVersionController struct {
+	recipeVersionService service.RecipeVersionService
+}
+
+func NewRecipeVersionController(recipeVersionService service.RecipeVersionService) *RecipeVersionController {
+	return &RecipeVersionController{recipeVersionService: recipeVersionService}
+}
+
+func (recipeVersionController *RecipeVersionController) GetAll(ctx echo.Context) error {
+	req := request.GetAllRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get all recipe versions", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get all recipe versions", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	listRecipeVersionResponse, err := recipeVersionController.recipeVersionService.GetAll(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get all recipe versions", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get all recipe versions", listRecipeVersionResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Get(ctx echo.Context) error {
+	req := request.GetRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	recipeVersionResponse, err := recipeVersionController.recipeVersionService.Get(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, recipeVersionResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success get recipe version", recipeVersionResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Effective(ctx echo.Context) error {
+	req := request.GetEffectiveRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get effective recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get effective recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	recipeVersionResponse, err := recipeVersionController.recipeVersionService.Effective(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get effective recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get effective recipe version", recipeVersionResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Create(ctx echo.Context) error {
+	req := request.CreateRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed create recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed create recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	recipeVersionResponse, err := recipeVersionController.recipeVersionService.Create(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed create recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, recipeVersionResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success create recipe version", recipeVersionResponse)
+	return ctx.JSON(201, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Update(ctx echo.Context) error {
+	req := request.UpdateRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed update recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	recipeVersionResponse, err := recipeVersionController.recipeVersionService.Update(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, recipeVersionResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success update recipe version", recipeVersionResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Delete(ctx echo.Context) error {
+	req := request.DeleteRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed delete recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	err = recipeVersionController.recipeVersionService.Delete(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success delete recipe version", nil)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Activate(ctx echo.Context) error {
+	req := request.ActivateRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed activate recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed activate recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed activate recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	recipeVersionResponse, err := recipeVersionController.recipeVersionService.Activate(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed activate recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, recipeVersionResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success activate recipe version", recipeVersionResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Archive(ctx echo.Context) error {
+	req := request.ArchiveRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed archive recipe version", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed archive recipe version", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed archive recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	recipeVersionResponse, err := recipeVersionController.recipeVersionService.Archive(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed archive recipe version", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, recipeVersionResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success archive recipe version", recipeVersionResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (recipeVersionController *RecipeVersionController) Diff(ctx echo.Context) error {
+	req := request.DiffRecipeVersionRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed diff recipe versions", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed diff recipe versions", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	diffResponse, err := recipeVersionController.recipeVersionService.Diff(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed diff recipe versions", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success diff recipe versions", diffResponse)
+	return ctx.JSON(200, apiResponse)
+}
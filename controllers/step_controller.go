@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type StepController struct {
+	stepService service.StepService
+}
+
+func NewStepController(stepService service.StepService) *StepController {
+	return &StepController{stepService: stepService}
+}
+
+func (stepController *StepController) GetAll(ctx echo.Context) error {
+	req := request.GetAllStepRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get all menu steps", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed get all menu steps", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	listStepResponse, err := stepController.stepService.GetAll(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed get all menu steps", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success get all menu steps", listStepResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (stepController *StepController) Create(ctx echo.Context) error {
+	req := request.CreateStepRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed create menu step", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed create menu step", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	stepResponse, err := stepController.stepService.Create(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed create menu step", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	helper.SetETag(ctx, stepResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success create menu step", stepResponse)
+	return ctx.JSON(201, apiResponse)
+}
+
+func (stepController *StepController) Update(ctx echo.Context) error {
+	req := request.UpdateStepRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update menu step", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed update menu step", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update menu step", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	stepResponse, err := stepController.stepService.Update(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed update menu step", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	helper.SetETag(ctx, stepResponse.Version)
+	apiResponse := response.NewApiResponse("ok", "success update menu step", stepResponse)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (stepController *StepController) Delete(ctx echo.Context) error {
+	req := request.DeleteStepRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu step", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed delete menu step", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	req.Version, err = helper.IfMatch(ctx)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu step", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	err = stepController.stepService.Delete(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed delete menu step", err.Error())
+		return ctx.JSON(errorStatus(err, 400), apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success delete menu step", nil)
+	return ctx.JSON(200, apiResponse)
+}
+
+func (stepController *StepController) Reorder(ctx echo.Context) error {
+	req := request.ReorderStepRequest{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed reorder menu steps", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	req.Actor = helper.Actor(ctx)
+
+	err = ctx.Validate(&req)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed reorder menu steps", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	listStepResponse, err := stepController.stepService.Reorder(req)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed reorder menu steps", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success reorder menu steps", listStepResponse)
+	return ctx.JSON(200, apiResponse)
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/erp_app/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type SyncController struct {
+	syncService service.SyncService
+}
+
+func NewSyncController(syncService service.SyncService) *SyncController {
+	return &SyncController{syncService: syncService}
+}
+
+func (syncController *SyncController) Get(ctx echo.Context) error {
+	syncRequest := request.SyncRequest{}
+	err := ctx.Bind(&syncRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed sync master data", err.Error())
+		return ctx.JSON(500, apiResponse)
+	}
+
+	err = ctx.Validate(&syncRequest)
+	if err != nil {
+		errorValidation := helper.FormatErrorValidation(err.(validator.ValidationErrors))
+		apiResponse := response.NewApiResponse("error", "failed sync master data", errorValidation)
+		return ctx.JSON(422, apiResponse)
+	}
+
+	syncResponse, err := syncController.syncService.Get(syncRequest)
+	if err != nil {
+		apiResponse := response.NewApiResponse("error", "failed sync master data", err.Error())
+		return ctx.JSON(400, apiResponse)
+	}
+
+	apiResponse := response.NewApiResponse("ok", "success sync master data", syncResponse)
+	return ctx.JSON(200, apiResponse)
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"fmt"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// backfills prepare data that SQL cannot compute the same way the application
+// does. Each runs right before the up script of its version, which relies on
+// it, typically to add a unique index.
+var backfills = map[int64]func(db *gorm.DB) error{
+	20230710090100: backfillNameKeys,
+	20230712090000: mergeDuplicateRecipeLines,
+}
+
+type namedRow struct {
+	Id    int
+	Name  string
+	Scope int
+}
+
+// backfillNameKeys normalizes the names and fills their keys with
+// models.NameKey. Every record but the oldest of a duplicated name gets its id
+// appended to the key, so the unique indexes can be added.
+func backfillNameKeys(db *gorm.DB) error {
+	tables := []struct {
+		name  string
+		scope string
+	}{
+		{"categories", "0"},
+		{"ingredients", "0"},
+		{"menus", "category_id"},
+	}
+
+	for _, table := range tables {
+		var rows []namedRow
+		err := db.Table(table.name).Select("id, name, " + table.scope + " AS scope").Order("id").Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		taken := map[string]bool{}
+		for _, row := range rows {
+			key := models.NameKey(row.Name)
+			scopedKey := fmt.Sprintf("%d/%s", row.Scope, key)
+			if taken[scopedKey] {
+				key = fmt.Sprintf("%s#%d", key, row.Id)
+			}
+			taken[scopedKey] = true
+
+			err = db.Table(table.name).Where("id = ?", row.Id).UpdateColumns(map[string]interface{}{
+				"name":     models.NormalizeName(row.Name),
+				"name_key": key,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeDuplicateRecipeLines leaves one line per ingredient in the recipe of a
+// menu: the oldest, which the recipe versions already treat as the line. The
+// quantities of the others are added to it, the way the merge mode for
+// duplicate lines does. When any set of lines does not add up (a quantity
+// without an amount, different yields or units of different kinds) nothing
+// is changed and the migration fails with the menu and ingredient of each,
+// so they can be resolved by hand.
+func mergeDuplicateRecipeLines(db *gorm.DB) error {
+	var listRecipe []models.MenuIngredient
+	err := db.Table("recipes").Order("menu_id, ingredient_id, id").Find(&listRecipe).Error
+	if err != nil {
+		return err
+	}
+
+	var merges []recipeLineMerge
+	var conflicts []string
+	for start := 0; start < len(listRecipe); {
+		end := start + 1
+		for end < len(listRecipe) && listRecipe[end].MenuId == listRecipe[start].MenuId && listRecipe[end].IngredientId == listRecipe[start].IngredientId {
+			end++
+		}
+
+		if end-start > 1 {
+			merge, ok := mergeRecipeLines(listRecipe[start:end])
+			if ok {
+				merges = append(merges, merge)
+			} else {
+				conflicts = append(conflicts, fmt.Sprintf("menu %d ingredient %d", listRecipe[start].MenuId, listRecipe[start].IngredientId))
+			}
+		}
+
+		start = end
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("recipe lines that cannot be merged, keep one line for each of: %s", strings.Join(conflicts, ", "))
+	}
+
+	for _, merge := range merges {
+		err = db.Table("recipes").Where("id = ?", merge.keptId).UpdateColumn("qty", merge.qty).Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Table("recipes").Where("id IN ?", merge.duplicateIds).Delete(&models.MenuIngredient{}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type recipeLineMerge struct {
+	keptId       int
+	qty          string
+	duplicateIds []int
+}
+
+// mergeRecipeLines adds up the lines of one ingredient into the first,
+// reporting false when they do not add up to a single quantity.
+func mergeRecipeLines(lines []models.MenuIngredient) (recipeLineMerge, bool) {
+	kept := lines[0]
+	merge := recipeLineMerge{keptId: kept.Id}
+
+	total := helper.QuantityTotal{}
+	for _, line := range lines {
+		quantity, ok := helper.ParseQuantity(line.Qty)
+		if !ok || !sameYield(line.YieldPercent, kept.YieldPercent) {
+			return merge, false
+		}
+		total.Add(quantity)
+
+		if line.Id != kept.Id {
+			merge.duplicateIds = append(merge.duplicateIds, line.Id)
+		}
+	}
+
+	quantities := total.Quantities()
+	if len(quantities) != 1 {
+		return merge, false
+	}
+	merge.qty = quantities[0].String()
+
+	return merge, true
+}
+
+func sameYield(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
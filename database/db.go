@@ -1,30 +1,211 @@
 package database
 
 import (
+	"errors"
+	"github.com/erp_app/config"
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"log"
-	"os"
+	"net/url"
+	"strconv"
+	"time"
 )
 
-func SetDb() *gorm.DB {
-	dbName := os.Getenv("db_name")
-	dsn := "root:@tcp(127.0.0.1:3306)/" + dbName + "?charset=utf8mb4&parseTime=True&loc=Local"
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Info)})
+const memoryDatabase = ":memory:"
+
+var defaultPorts = map[string]int{
+	"mysql":    3306,
+	"postgres": 5432,
+}
+
+var postgresSslModes = map[string]string{
+	"false":       "disable",
+	"true":        "verify-full",
+	"skip-verify": "require",
+	"preferred":   "prefer",
+}
+
+func SetDb(cfg config.Database) *gorm.DB {
+	db, err := Open(cfg)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
+	if cfg.AutoMigrate {
+		err = Migrate(db)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
 	return db
 }
 
+// SetDbTest opens the database used by the test suite. With the default
+// sqlite test driver every call returns a fresh, fully migrated in-memory
+// database, so tests do not share state.
 func SetDbTest() *gorm.DB {
-	dsn := "root:@tcp(127.0.0.1:3306)/erp_test?charset=utf8mb4&parseTime=True&loc=Local"
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	return db
+	cfg.Database.Driver = cfg.Database.TestDriver
+	cfg.Database.Name = cfg.Database.TestName
+	cfg.Database.LogLevel = "silent"
+	cfg.Database.AutoMigrate = true
+
+	if cfg.Database.Driver == "sqlite" {
+		cfg.Database.Name = memoryDatabase
+	}
+
+	return SetDb(cfg.Database)
+}
+
+func Open(cfg config.Database) (*gorm.DB, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logLevel(cfg.LogLevel))})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// every connection to an in-memory sqlite database sees its own empty
+	// database, so the pool must never hold more than one connection
+	if cfg.Driver == "sqlite" && cfg.Name == memoryDatabase {
+		sqlDb.SetMaxOpenConns(1)
+		sqlDb.SetMaxIdleConns(1)
+		sqlDb.SetConnMaxLifetime(0)
+		return db, nil
+	}
+
+	sqlDb.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDb.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDb.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	return db, nil
+}
+
+func newDialector(cfg config.Database) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "mysql":
+		dsn, err := mysqlDsn(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(dsn), nil
+	case "postgres":
+		return postgres.Open(postgresDsn(cfg)), nil
+	case "sqlite":
+		return sqlite.Open(sqliteDsn(cfg)), nil
+	default:
+		return nil, errors.New("unsupported database driver " + cfg.Driver)
+	}
+}
+
+func mysqlDsn(cfg config.Database) (string, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return "", err
+	}
+
+	mysqlConfig := mysqldriver.NewConfig()
+	mysqlConfig.User = cfg.User
+	mysqlConfig.Passwd = cfg.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = address(cfg)
+	mysqlConfig.DBName = cfg.Name
+	mysqlConfig.ParseTime = true
+	mysqlConfig.Loc = location
+	mysqlConfig.Params = map[string]string{"charset": "utf8mb4"}
+
+	if cfg.TLS != "false" {
+		mysqlConfig.TLSConfig = cfg.TLS
+	}
+
+	return mysqlConfig.FormatDSN(), nil
+}
+
+func postgresDsn(cfg config.Database) string {
+	query := url.Values{}
+	query.Set("sslmode", postgresSslModes[cfg.TLS])
+	if cfg.Timezone != "Local" {
+		query.Set("TimeZone", cfg.Timezone)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     address(cfg),
+		Path:     "/" + cfg.Name,
+		RawQuery: query.Encode(),
+	}
+
+	return dsn.String()
+}
+
+func address(cfg config.Database) string {
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPorts[cfg.Driver]
+	}
+
+	return cfg.Host + ":" + strconv.Itoa(port)
+}
+
+func sqliteDsn(cfg config.Database) string {
+	if cfg.Name == memoryDatabase {
+		return memoryDatabase
+	}
+
+	return "file:" + cfg.Name + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+// Truncate empties the given tables and resets their id sequences.
+func Truncate(db *gorm.DB, tables ...string) error {
+	for _, table := range tables {
+		var err error
+		switch db.Dialector.Name() {
+		case "sqlite":
+			err = db.Exec("DELETE FROM " + table).Error
+			if err == nil {
+				err = db.Exec("DELETE FROM sqlite_sequence WHERE name = ?", table).Error
+			}
+		case "postgres":
+			err = db.Exec("TRUNCATE TABLE " + table + " RESTART IDENTITY CASCADE").Error
+		default:
+			err = db.Exec("TRUNCATE TABLE " + table).Error
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func logLevel(level string) logger.LogLevel {
+	switch level {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn":
+		return logger.Warn
+	default:
+		return logger.Info
+	}
 }
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+type schemaMigration struct {
+	Version int64
+	Dirty   bool
+}
+
+// Migrate applies every pending up migration of the connection's dialect.
+// Progress is kept in a golang-migrate compatible schema_migrations table so
+// databases migrated with the migrate CLI keep working.
+func Migrate(db *gorm.DB) error {
+	migrations, err := loadMigrations(db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+
+	current, err := currentVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.version <= current {
+			continue
+		}
+
+		err = runMigration(db, migration.version, migration.up, backfills[migration.version])
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Rollback reverts the given number of applied migrations, newest first.
+func Rollback(db *gorm.DB, steps int) error {
+	migrations, err := loadMigrations(db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+
+	current, err := currentVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		if migrations[i].version > current {
+			continue
+		}
+
+		var previous int64
+		if i > 0 {
+			previous = migrations[i-1].version
+		}
+
+		err = runMigration(db, previous, migrations[i].down, nil)
+		if err != nil {
+			return fmt.Errorf("rollback %s: %w", migrations[i].name, err)
+		}
+
+		steps--
+	}
+
+	return nil
+}
+
+func loadMigrations(dialect string) ([]migration, error) {
+	dir := "migrations/" + dialect
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %s", dialect)
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		versionText, rest, found := strings.Cut(name, "_")
+		if !found {
+			continue
+		}
+
+		version, err := strconv.ParseInt(versionText, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		content, err := migrationFiles.ReadFile(dir + "/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		item, ok := byVersion[version]
+		if !ok {
+			item = &migration{version: version}
+			byVersion[version] = item
+		}
+
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			item.name = strings.TrimSuffix(name, ".up.sql")
+			item.up = string(content)
+		case strings.HasSuffix(rest, ".down.sql"):
+			item.down = string(content)
+		}
+	}
+
+	var migrations []migration
+	for _, item := range byVersion {
+		migrations = append(migrations, *item)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+func currentVersion(db *gorm.DB) (int64, error) {
+	err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)").Error
+	if err != nil {
+		return 0, err
+	}
+
+	var versions []schemaMigration
+	err = db.Table("schema_migrations").Limit(1).Find(&versions).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	if versions[0].Dirty {
+		return 0, errors.New("database is dirty at version " + strconv.FormatInt(versions[0].Version, 10) + ", fix it manually before migrating")
+	}
+
+	return versions[0].Version, nil
+}
+
+func runMigration(db *gorm.DB, version int64, script string, backfill func(db *gorm.DB) error) error {
+	// a backfill runs before the version is marked dirty, so one that refuses
+	// the data leaves the database at its previous version to retry once the
+	// data is fixed
+	if backfill != nil {
+		err := backfill(db)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := setVersion(db, version, true)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range strings.Split(script, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		err = db.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return setVersion(db, version, false)
+}
+
+func setVersion(db *gorm.DB, version int64, dirty bool) error {
+	err := db.Exec("DELETE FROM schema_migrations").Error
+	if err != nil {
+		return err
+	}
+
+	if version == 0 && !dirty {
+		return nil
+	}
+
+	return db.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, dirty).Error
+}
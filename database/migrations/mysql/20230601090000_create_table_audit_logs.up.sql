@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id int(11) unsigned NOT NULL AUTO_INCREMENT,
+    actor varchar(255) NOT NULL,
+    entity_type varchar(50) NOT NULL,
+    entity_id int(11) unsigned NOT NULL,
+    action varchar(20) NOT NULL,
+    changes text NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY audit_logs_entity (entity_type, entity_id),
+    KEY audit_logs_created_at (created_at)
+) ENGINE=InnoDB;
@@ -0,0 +1,4 @@
+ALTER TABLE categories ADD COLUMN version int(11) unsigned NOT NULL DEFAULT 1;
+ALTER TABLE ingredients ADD COLUMN version int(11) unsigned NOT NULL DEFAULT 1;
+ALTER TABLE menus ADD COLUMN version int(11) unsigned NOT NULL DEFAULT 1;
+ALTER TABLE recipes ADD COLUMN version int(11) unsigned NOT NULL DEFAULT 1;
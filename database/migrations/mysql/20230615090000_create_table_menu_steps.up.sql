@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS menu_steps (
+    id int(11) unsigned NOT NULL AUTO_INCREMENT,
+    menu_id int(11) unsigned NOT NULL,
+    position int(11) unsigned NOT NULL,
+    text text NOT NULL,
+    duration_minutes int(11) unsigned NOT NULL DEFAULT 0,
+    station varchar(100) NOT NULL DEFAULT '',
+    image_url varchar(2048) NOT NULL DEFAULT '',
+    version int(11) unsigned NOT NULL DEFAULT 1,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY menu_steps_menu (menu_id, position)
+) ENGINE=InnoDB
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recipe_version_lines;
+DROP TABLE IF EXISTS recipe_versions
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS recipe_versions (
+    id int(11) unsigned NOT NULL AUTO_INCREMENT,
+    menu_id int(11) unsigned NOT NULL,
+    number int(11) unsigned NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'draft',
+    note varchar(255) NOT NULL DEFAULT '',
+    effective_from datetime NULL,
+    applied_at datetime NULL,
+    version int(11) unsigned NOT NULL DEFAULT 1,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY recipe_versions_menu_number (menu_id, number),
+    KEY recipe_versions_due (status, effective_from)
+) ENGINE=InnoDB;
+CREATE TABLE IF NOT EXISTS recipe_version_lines (
+    id int(11) unsigned NOT NULL AUTO_INCREMENT,
+    recipe_version_id int(11) unsigned NOT NULL,
+    ingredient_id int(11) unsigned NOT NULL,
+    qty varchar(255) NOT NULL,
+    PRIMARY KEY (id),
+    KEY recipe_version_lines_version (recipe_version_id)
+) ENGINE=InnoDB
@@ -0,0 +1,2 @@
+ALTER TABLE menus ADD COLUMN yield_qty varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE menus ADD COLUMN portions int(11) unsigned NOT NULL DEFAULT 1;
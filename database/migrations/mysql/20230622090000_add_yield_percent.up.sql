@@ -0,0 +1,2 @@
+ALTER TABLE ingredients ADD COLUMN yield_percent decimal(5,2) NOT NULL DEFAULT 100;
+ALTER TABLE recipes ADD COLUMN yield_percent decimal(5,2) NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE ingredients ADD COLUMN vegan tinyint(1) NOT NULL DEFAULT 0;
+ALTER TABLE ingredients ADD COLUMN halal tinyint(1) NOT NULL DEFAULT 0;
+ALTER TABLE ingredients ADD COLUMN gluten_free tinyint(1) NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS ingredient_allergens (
+    id int(11) unsigned NOT NULL AUTO_INCREMENT,
+    ingredient_id int(11) unsigned NOT NULL,
+    allergen varchar(50) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY ingredient_allergens_ingredient (ingredient_id, allergen),
+    KEY ingredient_allergens_allergen (allergen)
+) ENGINE=InnoDB
@@ -0,0 +1,5 @@
+ALTER TABLE ingredients ADD COLUMN calories decimal(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN protein decimal(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN fat decimal(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN carbs decimal(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN sodium decimal(10,2) NULL;
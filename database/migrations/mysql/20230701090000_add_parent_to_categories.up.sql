@@ -0,0 +1 @@
+ALTER TABLE categories ADD COLUMN parent_id int(11) unsigned NULL, ADD KEY categories_parent (parent_id)
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ingredient_groups (
+    id int(11) unsigned NOT NULL AUTO_INCREMENT,
+    name varchar(255) NOT NULL,
+    storage varchar(20) NOT NULL DEFAULT 'ambient',
+    min_temp decimal(5,1) NULL,
+    max_temp decimal(5,1) NULL,
+    version int(11) unsigned NOT NULL DEFAULT 1,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB;
+ALTER TABLE ingredients ADD COLUMN ingredient_group_id int(11) unsigned NULL, ADD KEY ingredients_group (ingredient_group_id);
+INSERT INTO ingredient_groups (name, storage, min_temp, max_temp) VALUES ('produce', 'chilled', 1, 5), ('dairy', 'chilled', 0, 4), ('dry goods', 'ambient', NULL, NULL), ('beverages', 'ambient', NULL, NULL)
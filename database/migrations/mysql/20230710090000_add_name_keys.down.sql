@@ -0,0 +1,3 @@
+ALTER TABLE categories DROP COLUMN name_key;
+ALTER TABLE ingredients DROP COLUMN name_key;
+ALTER TABLE menus DROP COLUMN name_key
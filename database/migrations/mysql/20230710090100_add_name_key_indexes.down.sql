@@ -0,0 +1,3 @@
+ALTER TABLE categories DROP INDEX categories_name_key;
+ALTER TABLE ingredients DROP INDEX ingredients_name_key;
+ALTER TABLE menus DROP INDEX menus_category_name_key
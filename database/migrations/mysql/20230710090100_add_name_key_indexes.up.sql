@@ -0,0 +1,3 @@
+ALTER TABLE categories ADD UNIQUE KEY categories_name_key (name_key);
+ALTER TABLE ingredients ADD UNIQUE KEY ingredients_name_key (name_key);
+ALTER TABLE menus ADD UNIQUE KEY menus_category_name_key (category_id, name_key)
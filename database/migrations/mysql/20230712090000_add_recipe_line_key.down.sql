@@ -0,0 +1 @@
+ALTER TABLE recipes DROP INDEX recipes_menu_ingredient
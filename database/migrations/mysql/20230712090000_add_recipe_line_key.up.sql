@@ -0,0 +1 @@
+ALTER TABLE recipes ADD UNIQUE KEY recipes_menu_ingredient (menu_id, ingredient_id)
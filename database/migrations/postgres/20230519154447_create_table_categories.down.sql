@@ -0,0 +1 @@
+DROP TABLE IF EXISTS categories
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id serial PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS menus (
+    id serial PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    category_id integer NOT NULL,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
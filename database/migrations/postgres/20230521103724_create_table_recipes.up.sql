@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS recipes (
+    id serial PRIMARY KEY,
+    menu_id integer NOT NULL,
+    ingredient_id integer NOT NULL,
+    qty varchar(255) NOT NULL,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
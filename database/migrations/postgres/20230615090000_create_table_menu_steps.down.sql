@@ -0,0 +1 @@
+DROP TABLE IF EXISTS menu_steps
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE ingredients DROP COLUMN yield_percent;
+ALTER TABLE recipes DROP COLUMN yield_percent;
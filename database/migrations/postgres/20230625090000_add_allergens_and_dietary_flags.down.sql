@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ingredient_allergens;
+ALTER TABLE ingredients DROP COLUMN vegan;
+ALTER TABLE ingredients DROP COLUMN halal;
+ALTER TABLE ingredients DROP COLUMN gluten_free
\ No newline at end of file
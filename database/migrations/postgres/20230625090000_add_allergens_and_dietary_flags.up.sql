@@ -0,0 +1,10 @@
+ALTER TABLE ingredients ADD COLUMN vegan boolean NOT NULL DEFAULT false;
+ALTER TABLE ingredients ADD COLUMN halal boolean NOT NULL DEFAULT false;
+ALTER TABLE ingredients ADD COLUMN gluten_free boolean NOT NULL DEFAULT false;
+CREATE TABLE IF NOT EXISTS ingredient_allergens (
+    id serial PRIMARY KEY,
+    ingredient_id integer NOT NULL,
+    allergen varchar(50) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ingredient_allergens_ingredient ON ingredient_allergens (ingredient_id, allergen);
+CREATE INDEX IF NOT EXISTS ingredient_allergens_allergen ON ingredient_allergens (allergen)
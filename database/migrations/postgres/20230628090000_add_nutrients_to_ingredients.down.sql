@@ -0,0 +1,5 @@
+ALTER TABLE ingredients DROP COLUMN calories;
+ALTER TABLE ingredients DROP COLUMN protein;
+ALTER TABLE ingredients DROP COLUMN fat;
+ALTER TABLE ingredients DROP COLUMN carbs;
+ALTER TABLE ingredients DROP COLUMN sodium
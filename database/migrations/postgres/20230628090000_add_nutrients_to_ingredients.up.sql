@@ -0,0 +1,5 @@
+ALTER TABLE ingredients ADD COLUMN calories numeric(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN protein numeric(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN fat numeric(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN carbs numeric(10,2) NULL;
+ALTER TABLE ingredients ADD COLUMN sodium numeric(10,2) NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE categories ADD COLUMN parent_id integer NULL;
+CREATE INDEX IF NOT EXISTS categories_parent ON categories (parent_id)
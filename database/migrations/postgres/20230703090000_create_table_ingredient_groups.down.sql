@@ -0,0 +1,2 @@
+ALTER TABLE ingredients DROP COLUMN ingredient_group_id;
+DROP TABLE IF EXISTS ingredient_groups
@@ -0,0 +1,2 @@
+ALTER TABLE categories ADD COLUMN image_key varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE menus ADD COLUMN image_key varchar(255) NOT NULL DEFAULT ''
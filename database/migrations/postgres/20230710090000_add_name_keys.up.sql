@@ -0,0 +1,3 @@
+ALTER TABLE categories ADD COLUMN name_key varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE ingredients ADD COLUMN name_key varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE menus ADD COLUMN name_key varchar(255) NOT NULL DEFAULT ''
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS categories_name_key;
+DROP INDEX IF EXISTS ingredients_name_key;
+DROP INDEX IF EXISTS menus_category_name_key
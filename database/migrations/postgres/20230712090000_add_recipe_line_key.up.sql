@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS recipes_menu_ingredient ON recipes (menu_id, ingredient_id)
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ingredients
\ No newline at end of file
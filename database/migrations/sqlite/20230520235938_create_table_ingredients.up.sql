@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS ingredients (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name varchar(255) NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
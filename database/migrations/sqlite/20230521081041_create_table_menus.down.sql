@@ -0,0 +1 @@
+DROP TABLE IF EXISTS menus
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS menus (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name varchar(255) NOT NULL,
+    category_id integer NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
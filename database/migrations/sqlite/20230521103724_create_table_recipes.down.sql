@@ -0,0 +1 @@
+DROP TABLE IF EXISTS recipes
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS recipes (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    menu_id integer NOT NULL,
+    ingredient_id integer NOT NULL,
+    qty varchar(255) NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
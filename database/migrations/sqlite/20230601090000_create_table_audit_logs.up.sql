@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    actor varchar(255) NOT NULL,
+    entity_type varchar(50) NOT NULL,
+    entity_id integer NOT NULL,
+    action varchar(20) NOT NULL,
+    changes text NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS audit_logs_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS audit_logs_created_at ON audit_logs (created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE categories DROP COLUMN version;
+ALTER TABLE ingredients DROP COLUMN version;
+ALTER TABLE menus DROP COLUMN version;
+ALTER TABLE recipes DROP COLUMN version;
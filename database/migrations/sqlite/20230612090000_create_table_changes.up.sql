@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS changes (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entity_type varchar(50) NOT NULL,
+    entity_id integer NOT NULL,
+    action varchar(20) NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO changes (entity_type, entity_id, action) SELECT 'category', id, 'create' FROM categories ORDER BY id;
+INSERT INTO changes (entity_type, entity_id, action) SELECT 'ingredient', id, 'create' FROM ingredients ORDER BY id;
+INSERT INTO changes (entity_type, entity_id, action) SELECT 'menu', id, 'create' FROM menus ORDER BY id;
+INSERT INTO changes (entity_type, entity_id, action) SELECT 'recipe', id, 'create' FROM recipes ORDER BY id;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS menu_steps (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    menu_id integer NOT NULL,
+    position integer NOT NULL,
+    text text NOT NULL,
+    duration_minutes integer NOT NULL DEFAULT 0,
+    station varchar(100) NOT NULL DEFAULT '',
+    image_url varchar(2048) NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS menu_steps_menu ON menu_steps (menu_id, position)
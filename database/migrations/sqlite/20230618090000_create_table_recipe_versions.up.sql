@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS recipe_versions (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    menu_id integer NOT NULL,
+    number integer NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'draft',
+    note varchar(255) NOT NULL DEFAULT '',
+    effective_from datetime NULL,
+    applied_at datetime NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS recipe_versions_menu_number ON recipe_versions (menu_id, number);
+CREATE INDEX IF NOT EXISTS recipe_versions_due ON recipe_versions (status, effective_from);
+CREATE TABLE IF NOT EXISTS recipe_version_lines (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    recipe_version_id integer NOT NULL,
+    ingredient_id integer NOT NULL,
+    qty varchar(255) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS recipe_version_lines_version ON recipe_version_lines (recipe_version_id)
@@ -0,0 +1,2 @@
+ALTER TABLE menus DROP COLUMN yield_qty;
+ALTER TABLE menus DROP COLUMN portions;
@@ -0,0 +1,5 @@
+ALTER TABLE ingredients ADD COLUMN calories real NULL;
+ALTER TABLE ingredients ADD COLUMN protein real NULL;
+ALTER TABLE ingredients ADD COLUMN fat real NULL;
+ALTER TABLE ingredients ADD COLUMN carbs real NULL;
+ALTER TABLE ingredients ADD COLUMN sodium real NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS ingredients_group;
+ALTER TABLE ingredients DROP COLUMN ingredient_group_id;
+DROP TABLE IF EXISTS ingredient_groups
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ingredient_groups (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name varchar(255) NOT NULL,
+    storage varchar(20) NOT NULL DEFAULT 'ambient',
+    min_temp real NULL,
+    max_temp real NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE ingredients ADD COLUMN ingredient_group_id integer NULL;
+CREATE INDEX IF NOT EXISTS ingredients_group ON ingredients (ingredient_group_id);
+INSERT INTO ingredient_groups (name, storage, min_temp, max_temp) VALUES ('produce', 'chilled', 1, 5), ('dairy', 'chilled', 0, 4), ('dry goods', 'ambient', NULL, NULL), ('beverages', 'ambient', NULL, NULL)
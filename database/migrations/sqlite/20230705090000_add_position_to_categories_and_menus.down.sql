@@ -0,0 +1,2 @@
+ALTER TABLE categories DROP COLUMN position;
+ALTER TABLE menus DROP COLUMN position
@@ -0,0 +1,4 @@
+ALTER TABLE categories ADD COLUMN position integer NOT NULL DEFAULT 0;
+ALTER TABLE menus ADD COLUMN position integer NOT NULL DEFAULT 0;
+UPDATE categories SET position = id;
+UPDATE menus SET position = id
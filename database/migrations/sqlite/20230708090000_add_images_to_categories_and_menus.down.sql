@@ -0,0 +1,2 @@
+ALTER TABLE categories DROP COLUMN image_key;
+ALTER TABLE menus DROP COLUMN image_key
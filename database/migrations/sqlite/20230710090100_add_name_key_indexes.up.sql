@@ -0,0 +1,3 @@
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (name_key);
+CREATE UNIQUE INDEX IF NOT EXISTS ingredients_name_key ON ingredients (name_key);
+CREATE UNIQUE INDEX IF NOT EXISTS menus_category_name_key ON menus (category_id, name_key)
@@ -0,0 +1,16 @@
+package helper
+
+import "github.com/labstack/echo/v4"
+
+const HeaderActor = "X-Actor"
+
+// Actor returns who is making the request, as sent by the client in the
+// X-Actor header.
+func Actor(ctx echo.Context) string {
+	actor := ctx.Request().Header.Get(HeaderActor)
+	if actor == "" {
+		return "anonymous"
+	}
+
+	return actor
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"encoding/json"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"time"
+)
+
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+var namingStrategy = schema.NamingStrategy{}
+
+// ChangeSet compares the column fields of two snapshots of the same model and
+// returns the changed ones as JSON keyed by column name. Either snapshot may
+// be nil for creates and deletes. Associations are not compared.
+func ChangeSet(before interface{}, after interface{}) (string, error) {
+	beforeFields := columnValues(before)
+	afterFields := columnValues(after)
+
+	changes := map[string]FieldChange{}
+	for column, value := range afterFields {
+		previous, ok := beforeFields[column]
+		if ok && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		changes[column] = FieldChange{Before: previous, After: value}
+	}
+
+	for column, value := range beforeFields {
+		_, ok := afterFields[column]
+		if !ok {
+			changes[column] = FieldChange{Before: value}
+		}
+	}
+
+	content, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func columnValues(model interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	if model == nil {
+		return values
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(model))
+	if value.Kind() != reflect.Struct {
+		return values
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() || !isColumn(field.Type) {
+			continue
+		}
+
+		values[namingStrategy.ColumnName("", field.Name)] = value.Field(i).Interface()
+	}
+
+	return values
+}
+
+func isColumn(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		return fieldType == reflect.TypeOf(time.Time{})
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return false
+	default:
+		return true
+	}
+}
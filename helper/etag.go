@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+var ErrIfMatchRequired = errors.New("the If-Match header is required, send the ETag of the record you are changing")
+
+var ErrIfMatchInvalid = errors.New("the If-Match header does not contain a valid ETag")
+
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func SetETag(ctx echo.Context, version int) {
+	ctx.Response().Header().Set(HeaderETag, ETag(version))
+}
+
+// Versions are the record versions named by an If-Match header. The wildcard
+// gives an empty list, which matches any version.
+type Versions []int
+
+// Match reports whether current is one of the versions.
+func (versions Versions) Match(current int) bool {
+	if len(versions) == 0 {
+		return true
+	}
+
+	for _, version := range versions {
+		if version == current {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch returns the record versions the client expects from the If-Match
+// header, which may list several ETags separated by commas. Weak ETags
+// (W/"3") are taken like strong ones, since a version names one state of the
+// record either way.
+func IfMatch(ctx echo.Context) (Versions, error) {
+	ifMatch := strings.TrimSpace(ctx.Request().Header.Get(HeaderIfMatch))
+	if ifMatch == "" {
+		return nil, ErrIfMatchRequired
+	}
+
+	if ifMatch == "*" {
+		return Versions{}, nil
+	}
+
+	var versions Versions
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, ErrIfMatchInvalid
+		}
+
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version < 1 {
+			return nil, ErrIfMatchInvalid
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,204 @@
+package helper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"mime"
+	"strings"
+)
+
+const (
+	FormatJson   = "json"
+	FormatNdjson = "ndjson"
+
+	MIMETextCSV           = "text/csv"
+	MIMEApplicationXlsx   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MIMEApplicationNdjson = "application/x-ndjson"
+)
+
+var exportContentTypes = map[string]string{
+	FormatCsv:    MIMETextCSV,
+	FormatXlsx:   MIMEApplicationXlsx,
+	FormatNdjson: MIMEApplicationNdjson,
+}
+
+// ExportFormat picks the export format of a list endpoint from the format
+// query parameter or, when it is absent, from the Accept header. An empty
+// result means the regular JSON envelope.
+func ExportFormat(ctx echo.Context) string {
+	format := ctx.QueryParam("format")
+	if format != "" {
+		if format == FormatJson {
+			return ""
+		}
+		return format
+	}
+
+	for _, accept := range strings.Split(ctx.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		for exportFormat, contentType := range exportContentTypes {
+			if mediaType == contentType {
+				return exportFormat
+			}
+		}
+
+		if mediaType == echo.MIMEApplicationJSON {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// ExportContentType returns the content type of an export format.
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// ExportWriter writes records one at a time. Spreadsheet formats write the
+// flat row; NDJSON writes the record itself as one JSON line and skips nil
+// records, so a record spread over several rows is written once. Close
+// finishes the file; Discard releases the writer of a failed export without
+// writing anything more.
+type ExportWriter interface {
+	Write(record interface{}, row []string) error
+	Close() error
+	Discard() error
+}
+
+func NewExportWriter(writer io.Writer, format string, sheet string, header []string) (ExportWriter, error) {
+	switch format {
+	case FormatCsv:
+		csvWriter := csv.NewWriter(writer)
+		err := csvWriter.Write(header)
+		if err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{writer: csvWriter}, nil
+	case FormatXlsx:
+		return newXlsxExportWriter(writer, sheet, header)
+	case FormatNdjson:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(writer)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+	rows   int
+}
+
+func (csvExportWriter *csvExportWriter) Write(record interface{}, row []string) error {
+	err := csvExportWriter.writer.Write(row)
+	if err != nil {
+		return err
+	}
+
+	// flush now and then so the client starts receiving data early
+	csvExportWriter.rows++
+	if csvExportWriter.rows%100 == 0 {
+		csvExportWriter.writer.Flush()
+		return csvExportWriter.writer.Error()
+	}
+
+	return nil
+}
+
+func (csvExportWriter *csvExportWriter) Close() error {
+	csvExportWriter.writer.Flush()
+	return csvExportWriter.writer.Error()
+}
+
+func (csvExportWriter *csvExportWriter) Discard() error {
+	return nil
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (ndjsonExportWriter *ndjsonExportWriter) Write(record interface{}, row []string) error {
+	if record == nil {
+		return nil
+	}
+
+	return ndjsonExportWriter.encoder.Encode(record)
+}
+
+func (ndjsonExportWriter *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+func (ndjsonExportWriter *ndjsonExportWriter) Discard() error {
+	return nil
+}
+
+// xlsxExportWriter uses the excelize stream writer, which spills rows to a
+// temporary file instead of keeping the sheet in memory. An xlsx file is a
+// zip archive, so nothing reaches the client before Close.
+type xlsxExportWriter struct {
+	writer       io.Writer
+	file         *excelize.File
+	streamWriter *excelize.StreamWriter
+	row          int
+}
+
+func newXlsxExportWriter(writer io.Writer, sheet string, header []string) (*xlsxExportWriter, error) {
+	file := excelize.NewFile()
+	file.SetSheetName("Sheet1", sheet)
+
+	streamWriter, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	xlsxWriter := &xlsxExportWriter{writer: writer, file: file, streamWriter: streamWriter}
+	err = xlsxWriter.Write(nil, header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return xlsxWriter, nil
+}
+
+func (xlsxExportWriter *xlsxExportWriter) Write(record interface{}, row []string) error {
+	xlsxExportWriter.row++
+
+	cell, err := excelize.CoordinatesToCellName(1, xlsxExportWriter.row)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(row))
+	for i, value := range row {
+		values[i] = value
+	}
+
+	return xlsxExportWriter.streamWriter.SetRow(cell, values)
+}
+
+func (xlsxExportWriter *xlsxExportWriter) Close() error {
+	defer xlsxExportWriter.file.Close()
+
+	err := xlsxExportWriter.streamWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	return xlsxExportWriter.file.Write(xlsxExportWriter.writer)
+}
+
+// Discard removes the temporary files of the stream writer.
+func (xlsxExportWriter *xlsxExportWriter) Discard() error {
+	return xlsxExportWriter.file.Close()
+}
@@ -0,0 +1,111 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+// maxImagePixels keeps a small file that decodes to a huge bitmap from
+// exhausting memory.
+const maxImagePixels = 40_000_000
+
+var (
+	ErrImageRequired   = errors.New("image is required")
+	ErrImageTooLarge   = errors.New("image is larger than the upload limit")
+	ErrImageType       = errors.New("image must be a jpeg, png, gif or webp file")
+	ErrImageInvalid    = errors.New("image could not be read")
+	ErrImageDimensions = errors.New("image dimensions are too large")
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// Image is an upload checked to be a picture, with a JPEG thumbnail of it.
+type Image struct {
+	Content     []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Thumbnail   []byte
+}
+
+// ReadImage reads an upload of at most maxSize bytes and checks its content
+// rather than trusting its name or the content type sent by the client. The
+// thumbnail is scaled down to thumbnailWidth; smaller images are not
+// enlarged. Transparent areas turn white, as JPEG has no transparency.
+func ReadImage(reader io.Reader, maxSize int64, thumbnailWidth int) (Image, error) {
+	content, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return Image{}, err
+	}
+
+	if int64(len(content)) > maxSize {
+		return Image{}, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(content)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		return Image{}, ErrImageType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return Image{}, ErrImageInvalid
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return Image{}, ErrImageInvalid
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		return Image{}, ErrImageDimensions
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return Image{}, ErrImageInvalid
+	}
+
+	width, height := config.Width, config.Height
+	if width > thumbnailWidth {
+		height = height * thumbnailWidth / width
+		width = thumbnailWidth
+		if height < 1 {
+			height = 1
+		}
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(thumbnail, thumbnail.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), source, source.Bounds(), draw.Over, nil)
+
+	encoded := &bytes.Buffer{}
+	err = jpeg.Encode(encoded, thumbnail, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return Image{}, err
+	}
+
+	return Image{
+		Content:     content,
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+		Thumbnail:   encoded.Bytes(),
+	}, nil
+}
@@ -0,0 +1,180 @@
+package helper
+
+import (
+	"github.com/erp_app/response"
+	"github.com/go-pdf/fpdf"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const MIMEApplicationPdf = "application/pdf"
+
+// RecipeCard is what a printed recipe card shows. Steps and Photo are
+// optional; PhotoType is the image type understood by fpdf (jpg, png, gif).
+type RecipeCard struct {
+	Menu      response.MenuResponse
+	Steps     []string
+	Photo     io.Reader
+	PhotoType string
+}
+
+type pdfDocument struct {
+	pdf       *fpdf.Fpdf
+	translate func(string) string
+}
+
+func newPdfDocument(title string) pdfDocument {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+
+	document := pdfDocument{pdf: pdf, translate: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(150, 5, document.translate(title), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo())+"/{nb}", "", 0, "R", false, 0, "")
+	})
+
+	return document
+}
+
+// WriteRecipeCard renders a single menu as a one page recipe card.
+func WriteRecipeCard(writer io.Writer, card RecipeCard) error {
+	document := newPdfDocument("Recipe card - " + card.Menu.Name)
+	document.pdf.AddPage()
+	document.recipe(card)
+
+	return document.pdf.Output(writer)
+}
+
+// WriteMenuBook renders the menus in the order given, which is the display
+// order of the POS, each category starting on a new page.
+func WriteMenuBook(writer io.Writer, cards []RecipeCard) error {
+	document := newPdfDocument("Menu book")
+
+	categoryId := 0
+	for i, card := range cards {
+		if i == 0 || card.Menu.CategoryId != categoryId {
+			categoryId = card.Menu.CategoryId
+			document.pdf.AddPage()
+			document.pdf.SetFont("Helvetica", "B", 22)
+			document.pdf.CellFormat(0, 12, document.translate(card.Menu.Category.Name), "B", 1, "L", false, 0, "")
+			document.pdf.Ln(6)
+		}
+
+		document.recipe(card)
+		document.pdf.Ln(8)
+	}
+
+	if len(cards) == 0 {
+		document.pdf.AddPage()
+		document.pdf.SetFont("Helvetica", "", 12)
+		document.pdf.CellFormat(0, 8, "No menus.", "", 1, "L", false, 0, "")
+	}
+
+	return document.pdf.Output(writer)
+}
+
+func (document pdfDocument) recipe(card RecipeCard) {
+	pdf := document.pdf
+	menu := card.Menu
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.MultiCell(0, 9, document.translate(menu.Name), "", "L", false)
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(0, 6, document.translate(menu.Category.Name), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(3)
+
+	if card.Photo != nil {
+		name := "menu-" + strconv.Itoa(menu.Id)
+		options := fpdf.ImageOptions{ImageType: card.PhotoType, ReadDpi: true}
+		info := pdf.RegisterImageOptionsReader(name, options, card.Photo)
+		if pdf.Ok() && info != nil {
+			pdf.ImageOptions(name, pdf.GetX(), pdf.GetY(), 80, 0, true, options, 0, "")
+			pdf.Ln(4)
+		} else {
+			// a broken photo should not cost the kitchen its recipe card
+			pdf.ClearError()
+		}
+	}
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 7, "Ingredients", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	if len(menu.Ingredients) == 0 {
+		pdf.CellFormat(0, 6, "-", "", 1, "L", false, 0, "")
+	}
+	for _, recipe := range menu.Ingredients {
+		pdf.CellFormat(120, 6, document.translate(recipe.Name), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, document.translate(recipe.Qty), "B", 1, "R", false, 0, "")
+	}
+
+	if len(card.Steps) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, "Preparation", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		for i, step := range card.Steps {
+			pdf.MultiCell(0, 6, document.translate(strconv.Itoa(i+1)+". "+step), "", "L", false)
+		}
+	}
+}
+
+// WriteNutritionLabel renders the nutrition of one portion of a menu as a
+// nutrition facts label.
+func WriteNutritionLabel(writer io.Writer, menu response.MenuResponse) error {
+	document := newPdfDocument("Nutrition facts - " + menu.Name)
+	pdf := document.pdf
+	pdf.AddPage()
+
+	nutrition := menu.Nutrition
+	width := 90.0
+	pdf.SetLineWidth(0.6)
+	pdf.Rect(pdf.GetX()-2, pdf.GetY()-2, width+4, 86, "D")
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(width, 10, "Nutrition Facts", "B", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.MultiCell(width, 6, document.translate(menu.Name), "", "L", false)
+	portion := "1 portion"
+	if menu.YieldQty != "" && menu.Portions > 0 {
+		portion += " (" + menu.YieldQty + " / " + strconv.Itoa(menu.Portions) + ")"
+	}
+	pdf.CellFormat(width, 6, document.translate("Serving size: "+portion), "B", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(width/2, 10, "Calories", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(width/2, 10, strconv.FormatFloat(nutrition.Calories, 'f', -1, 64)+" kcal", "B", 1, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 11)
+	for _, row := range []struct {
+		name   string
+		amount float64
+		unit   string
+	}{
+		{"Total fat", nutrition.Fat, "g"},
+		{"Sodium", nutrition.Sodium, "mg"},
+		{"Total carbohydrate", nutrition.Carbs, "g"},
+		{"Protein", nutrition.Protein, "g"},
+	} {
+		pdf.CellFormat(width/2, 7, row.name, "B", 0, "L", false, 0, "")
+		pdf.CellFormat(width/2, 7, strconv.FormatFloat(row.amount, 'f', -1, 64)+" "+row.unit, "B", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(2)
+	pdf.SetFont("Helvetica", "", 8)
+	if len(menu.Allergens) > 0 {
+		pdf.MultiCell(width, 4, document.translate("Contains: "+strings.ReplaceAll(strings.Join(menu.Allergens, ", "), "_", " ")), "", "L", false)
+	}
+	if !nutrition.Complete {
+		pdf.MultiCell(width, 4, document.translate("Incomplete, not counted: "+strings.Join(nutrition.Missing, ", ")), "", "L", false)
+	}
+
+	return pdf.Output(writer)
+}
@@ -0,0 +1,264 @@
+package helper
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a parsed recipe quantity such as "200 gr" or "1 1/2 cup". Unit
+// is the canonical unit name, or the unit as written when it is not known.
+type Quantity struct {
+	Amount float64
+	Unit   string
+}
+
+type quantityUnit struct {
+	name      string
+	dimension string
+	// base is the size of the unit in the base unit of its dimension (gram,
+	// milliliter or piece)
+	base float64
+}
+
+const (
+	dimensionMass   = "mass"
+	dimensionVolume = "volume"
+	dimensionCount  = "count"
+)
+
+var quantityUnits = map[string]quantityUnit{}
+
+var baseUnits = map[string]string{dimensionMass: "g", dimensionVolume: "ml"}
+
+func init() {
+	for _, unit := range []struct {
+		quantityUnit
+		aliases []string
+	}{
+		{quantityUnit{"mg", dimensionMass, 0.001}, []string{"mg", "milligram", "miligram"}},
+		{quantityUnit{"g", dimensionMass, 1}, []string{"g", "gr", "gram", "grams", "grm"}},
+		{quantityUnit{"kg", dimensionMass, 1000}, []string{"kg", "kilo", "kilogram", "kilograms"}},
+		{quantityUnit{"ml", dimensionVolume, 1}, []string{"ml", "milliliter", "millilitre", "mililiter"}},
+		{quantityUnit{"l", dimensionVolume, 1000}, []string{"l", "lt", "ltr", "liter", "litre", "liters", "litres"}},
+		{quantityUnit{"tsp", dimensionVolume, 5}, []string{"tsp", "sdt", "teaspoon", "teaspoons"}},
+		{quantityUnit{"tbsp", dimensionVolume, 15}, []string{"tbsp", "sdm", "tablespoon", "tablespoons"}},
+		{quantityUnit{"cup", dimensionVolume, 240}, []string{"cup", "cups", "gelas"}},
+	} {
+		for _, alias := range unit.aliases {
+			quantityUnits[alias] = unit.quantityUnit
+		}
+	}
+}
+
+var quantityPattern = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(?:\s+(\d+)/(\d+))?(?:/(\d+))?\s*(.*)$`)
+
+// thousandsPattern matches an amount grouped with commas, like "1,200" or
+// "12,500.5", up to the character after it so "1,2345" is not mistaken for one.
+var thousandsPattern = regexp.MustCompile(`^\d{1,3}(?:,\d{3})+(?:\.\d+)?(?:[^\d,.]|$)`)
+
+// ParseQuantity reads an amount followed by an optional unit. Decimals may
+// use a comma, except that a comma followed by exactly three digits groups
+// thousands, so "1,5 kg" is 1.5 kg but "1,200 g" is 1200 g. Simple fractions
+// ("1/2", "1 1/2") are understood. It returns false for quantities without a
+// leading amount, like "to taste".
+func ParseQuantity(value string) (Quantity, bool) {
+	value = strings.TrimSpace(value)
+	if grouped := thousandsPattern.FindString(value); grouped != "" {
+		value = strings.ReplaceAll(grouped, ",", "") + value[len(grouped):]
+	}
+
+	match := quantityPattern.FindStringSubmatch(value)
+	if match == nil {
+		return Quantity{}, false
+	}
+
+	amount, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return Quantity{}, false
+	}
+
+	switch {
+	case match[2] != "":
+		// "1 1/2"
+		numerator, _ := strconv.ParseFloat(match[2], 64)
+		denominator, _ := strconv.ParseFloat(match[3], 64)
+		if denominator == 0 {
+			return Quantity{}, false
+		}
+		amount += numerator / denominator
+	case match[4] != "":
+		// "1/2"
+		denominator, _ := strconv.ParseFloat(match[4], 64)
+		if denominator == 0 {
+			return Quantity{}, false
+		}
+		amount /= denominator
+	}
+
+	unit := strings.TrimSpace(match[5])
+	if known, ok := quantityUnits[strings.ToLower(unit)]; ok {
+		unit = known.name
+	}
+
+	return Quantity{Amount: amount, Unit: unit}, true
+}
+
+// Scale multiplies the quantity and expresses the result in the unit a cook
+// would use for that amount, so 800 g doubled becomes 1.6 kg and 2 tsp
+// tripled becomes 2 tbsp. The amount is rounded to a precision that suits
+// the unit.
+func (quantity Quantity) Scale(factor float64) Quantity {
+	return quantity.scale(factor, math.Round)
+}
+
+// ScaleUp is Scale rounding up, for quantities that must not fall short such
+// as what to buy.
+func (quantity Quantity) ScaleUp(factor float64) Quantity {
+	return quantity.scale(factor, math.Ceil)
+}
+
+func (quantity Quantity) scale(factor float64, round func(float64) float64) Quantity {
+	scaled := Quantity{Amount: quantity.Amount * factor, Unit: quantity.Unit}
+
+	unit, ok := quantityUnits[quantity.Unit]
+	if ok {
+		scaled = convertQuantity(scaled.Amount*unit.base, unit)
+	}
+
+	scaled.Amount = roundQuantity(scaled.Amount, scaled.Unit, round)
+	return scaled
+}
+
+// QuantityTotal adds up quantities of one ingredient. Known units are summed
+// by dimension in metric units; other units are summed per unit name.
+type QuantityTotal struct {
+	keys   []string
+	totals map[string]Quantity
+}
+
+func (total *QuantityTotal) Add(quantity Quantity) {
+	key := "unit:" + quantity.Unit
+	if unit, ok := quantityUnits[quantity.Unit]; ok {
+		key = unit.dimension
+		quantity = Quantity{Amount: quantity.Amount * unit.base, Unit: baseUnits[unit.dimension]}
+	}
+
+	if total.totals == nil {
+		total.totals = map[string]Quantity{}
+	}
+
+	current, ok := total.totals[key]
+	if !ok {
+		total.keys = append(total.keys, key)
+		current = Quantity{Unit: quantity.Unit}
+	}
+	current.Amount += quantity.Amount
+	total.totals[key] = current
+}
+
+// Quantities returns the totals in the order their units were first added,
+// rounded up.
+func (total *QuantityTotal) Quantities() []Quantity {
+	var quantities []Quantity
+	for _, key := range total.keys {
+		quantities = append(quantities, total.totals[key].ScaleUp(1))
+	}
+
+	return quantities
+}
+
+func (quantity Quantity) String() string {
+	amount := strconv.FormatFloat(quantity.Amount, 'f', -1, 64)
+	if quantity.Unit == "" {
+		return amount
+	}
+
+	return amount + " " + quantity.Unit
+}
+
+// Grams is the weight of the quantity. Volumes are weighed as water, which is
+// close enough for the liquids recipes measure by volume; pieces and unknown
+// units cannot be weighed.
+func (quantity Quantity) Grams() (float64, bool) {
+	unit, ok := quantityUnits[quantity.Unit]
+	if !ok || unit.dimension == dimensionCount {
+		return 0, false
+	}
+
+	return quantity.Amount * unit.base, true
+}
+
+func convertQuantity(base float64, original quantityUnit) Quantity {
+	switch original.dimension {
+	case dimensionMass:
+		switch {
+		case base >= 1000:
+			return Quantity{Amount: base / 1000, Unit: "kg"}
+		case base < 1:
+			return Quantity{Amount: base * 1000, Unit: "mg"}
+		default:
+			return Quantity{Amount: base, Unit: "g"}
+		}
+	case dimensionVolume:
+		// spoons and cups stay in kitchen measures, metric stays metric
+		if original.name == "ml" || original.name == "l" {
+			if base >= 1000 {
+				return Quantity{Amount: base / 1000, Unit: "l"}
+			}
+			return Quantity{Amount: base, Unit: "ml"}
+		}
+
+		switch {
+		case base < 15:
+			return Quantity{Amount: base / 5, Unit: "tsp"}
+		case base < 60:
+			return Quantity{Amount: base / 15, Unit: "tbsp"}
+		default:
+			return Quantity{Amount: base / 240, Unit: "cup"}
+		}
+	}
+
+	return Quantity{Amount: base / original.base, Unit: original.name}
+}
+
+func roundQuantity(amount float64, unit string, round func(float64) float64) float64 {
+	var step float64
+	switch unit {
+	case "kg", "l":
+		step = 0.01
+	case "g", "ml":
+		step = 1
+		if amount < 10 {
+			step = 0.1
+		}
+	case "mg":
+		step = 1
+	case "tsp", "tbsp", "cup":
+		step = 0.25
+	default:
+		// pieces, cloves, eggs and unknown units
+		step = 1
+		if amount < 10 {
+			step = 0.5
+		}
+	}
+
+	rounded := roundTo(amount, step, round)
+
+	// never round an ingredient away completely
+	if rounded == 0 && amount > 0 {
+		return roundTo(amount, 0.01, round)
+	}
+
+	return rounded
+}
+
+func roundTo(amount float64, step float64, round func(float64) float64) float64 {
+	// rounding the steps first keeps float noise such as 2.0000000000000004
+	// from being rounded up to the next step, and the last round drops noise
+	// such as 0.30000000000000004 from the result
+	steps := round(math.Round(amount/step*1e6) / 1e6)
+	return math.Round(steps*step*100) / 100
+}
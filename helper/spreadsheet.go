@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCsv  = "csv"
+	FormatXlsx = "xlsx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported file format, expected csv or xlsx")
+
+type Sheet struct {
+	Name string
+	Rows []SheetRow
+}
+
+// SheetRow holds the cells of one data row keyed by their normalized header.
+// Line is the row number as shown by a spreadsheet program.
+type SheetRow struct {
+	Line   int
+	Values map[string]string
+}
+
+// SpreadsheetFormat guesses the format of an uploaded file from its name.
+func SpreadsheetFormat(filename string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+}
+
+// ReadSpreadsheet reads every sheet of a csv or xlsx file. The first row of a
+// sheet is its header; empty rows are skipped. A csv file has a single sheet
+// without a name.
+func ReadSpreadsheet(reader io.Reader, format string) ([]Sheet, error) {
+	switch format {
+	case FormatCsv:
+		csvReader := csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		csvReader.TrimLeadingSpace = true
+
+		// the csv reader skips blank lines, so keep the line of every record
+		var records [][]string
+		var lines []int
+		for {
+			record, err := csvReader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			line, _ := csvReader.FieldPos(0)
+			records = append(records, record)
+			lines = append(lines, line)
+		}
+
+		return []Sheet{newSheet("", records, lines)}, nil
+	case FormatXlsx:
+		file, err := excelize.OpenReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		var sheets []Sheet
+		for _, name := range file.GetSheetList() {
+			records, err := file.GetRows(name)
+			if err != nil {
+				return nil, err
+			}
+
+			sheets = append(sheets, newSheet(name, records, nil))
+		}
+
+		return sheets, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func newSheet(name string, records [][]string, lines []int) Sheet {
+	sheet := Sheet{Name: name}
+	if len(records) == 0 {
+		return sheet
+	}
+
+	header := make([]string, len(records[0]))
+	for i, column := range records[0] {
+		header[i] = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))), " ", "_")
+	}
+
+	for i, record := range records[1:] {
+		values := map[string]string{}
+		empty := true
+		for j, cell := range record {
+			if j >= len(header) || header[j] == "" {
+				continue
+			}
+
+			cell = strings.TrimSpace(cell)
+			values[header[j]] = cell
+			if cell != "" {
+				empty = false
+			}
+		}
+
+		if empty {
+			continue
+		}
+
+		line := i + 2
+		if lines != nil {
+			line = lines[i+1]
+		}
+
+		sheet.Rows = append(sheet.Rows, SheetRow{Line: line, Values: values})
+	}
+
+	return sheet
+}
@@ -1,7 +1,6 @@
 package libraries
 
 import (
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -9,7 +8,9 @@ import (
 func SetRouter() *echo.Echo {
 	e := echo.New()
 	e.Static("/", "public")
-	e.Validator = &CustomValidator{Validator: validator.New()}
-	e.Use(middleware.CORS())
+	e.Validator = &CustomValidator{Validator: NewValidator()}
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		ExposeHeaders: []string{"ETag"},
+	}))
 	return e
 }
@@ -0,0 +1,222 @@
+package libraries
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/erp_app/config"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type s3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	baseUrl   string
+	client    *http.Client
+}
+
+// NewS3Storage stores files in a bucket of an S3 compatible service. Objects
+// are addressed path style (endpoint/bucket/key), which MinIO and the other
+// self hosted services accept, and requests are signed with AWS Signature
+// Version 4.
+func NewS3Storage(cfg config.Storage) (Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse storage endpoint: %w", err)
+	}
+
+	baseUrl := cfg.BaseUrl
+	if !strings.HasPrefix(baseUrl, "http://") && !strings.HasPrefix(baseUrl, "https://") {
+		baseUrl = endpoint.String() + "/" + cfg.Bucket
+	}
+
+	return &s3Storage{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		region:    cfg.Region,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		baseUrl:   strings.TrimSuffix(baseUrl, "/"),
+		client:    &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (s3Storage *s3Storage) Put(key string, content io.Reader, contentType string) error {
+	// the payload is hashed for the signature, uploads are small enough to
+	// hold in memory
+	payload, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	response, err := s3Storage.do(http.MethodPut, key, payload, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return s3Error(http.MethodPut, key, response)
+	}
+
+	return nil
+}
+
+func (s3Storage *s3Storage) Get(key string) (io.ReadCloser, error) {
+	response, err := s3Storage.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response.Body, nil
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+	default:
+		defer response.Body.Close()
+		return nil, s3Error(http.MethodGet, key, response)
+	}
+}
+
+func (s3Storage *s3Storage) Delete(key string) error {
+	response, err := s3Storage.do(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// deleting a missing object is not an error, as with the local driver
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		return s3Error(http.MethodDelete, key, response)
+	}
+
+	return nil
+}
+
+func (s3Storage *s3Storage) URL(key string) string {
+	return s3Storage.baseUrl + "/" + escapePath(key)
+}
+
+func (s3Storage *s3Storage) do(method string, key string, payload []byte, contentType string) (*http.Response, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidStorageKey
+	}
+
+	target := *s3Storage.endpoint
+	target.Path = s3Storage.endpoint.Path + "/" + s3Storage.bucket + "/" + key
+	target.RawPath = escapePath(target.Path)
+
+	request, err := http.NewRequest(method, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	s3Storage.sign(request, payload, time.Now())
+
+	return s3Storage.client.Do(request)
+}
+
+// sign adds the AWS Signature Version 4 headers to request.
+func (s3Storage *s3Storage) sign(request *http.Request, payload []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 request.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := request.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s3Storage.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+s3Storage.secretKey), date)
+	signingKey = hmacSha256(signingKey, s3Storage.region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s3Storage.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func s3Error(method string, key string, response *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	return fmt.Errorf("s3 %s %s: %s %s", method, key, response.Status, strings.TrimSpace(string(message)))
+}
+
+// escapePath percent encodes every byte of a slash separated path except the
+// unreserved characters, the way the signature expects it.
+func escapePath(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
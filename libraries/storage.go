@@ -0,0 +1,126 @@
+package libraries
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erp_app/config"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidStorageKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files under slash separated keys such as
+// "menus/1/photo.jpg". URL is where clients fetch a stored file.
+type Storage interface {
+	Put(key string, content io.Reader, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+func NewStorage(cfg config.Storage) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocalStorage(cfg.Path, cfg.BaseUrl), nil
+	case "s3":
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+type localStorage struct {
+	root    string
+	baseUrl string
+}
+
+// NewLocalStorage stores files on disk below root. The router serves the
+// public directory, so a root inside it needs no other web server.
+func NewLocalStorage(root string, baseUrl string) Storage {
+	return &localStorage{
+		root:    root,
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+	}
+}
+
+func (localStorage *localStorage) Put(key string, content io.Reader, contentType string) error {
+	target, err := localStorage.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	// write next to the target and rename, so a file is never served half
+	// written
+	file, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, content)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(file.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), target)
+}
+
+func (localStorage *localStorage) Get(key string) (io.ReadCloser, error) {
+	target, err := localStorage.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(target)
+}
+
+func (localStorage *localStorage) Delete(key string) error {
+	target, err := localStorage.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(target)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (localStorage *localStorage) URL(key string) string {
+	return localStorage.baseUrl + "/" + key
+}
+
+func (localStorage *localStorage) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidStorageKey
+	}
+
+	return filepath.Join(localStorage.root, filepath.FromSlash(key)), nil
+}
+
+// validKey rejects keys that could point outside the storage root.
+func validKey(key string) bool {
+	return key != "" && !path.IsAbs(key) && path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
@@ -1,11 +1,32 @@
 package libraries
 
-import "github.com/go-playground/validator/v10"
+import (
+	"github.com/erp_app/models"
+	"github.com/go-playground/validator/v10"
+)
 
 type CustomValidator struct {
 	Validator *validator.Validate
 }
 
+// NewValidator returns a validator with the tags the requests use on top of
+// the built-in ones.
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("allergen", isAllergen)
+	return validate
+}
+
+// isAllergen checks that a value is one of models.Allergens.
+func isAllergen(fl validator.FieldLevel) bool {
+	for _, allergen := range models.Allergens {
+		if fl.Field().String() == allergen {
+			return true
+		}
+	}
+	return false
+}
+
 func (cv *CustomValidator) Validate(i interface{}) error {
 	err := cv.Validator.Struct(i)
 	if err != nil {
@@ -1,39 +1,64 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"github.com/erp_app/config"
 	"github.com/erp_app/controllers"
 	"github.com/erp_app/database"
 	"github.com/erp_app/libraries"
 	"github.com/erp_app/repository"
 	"github.com/erp_app/service"
-	"github.com/joho/godotenv"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	err := godotenv.Load()
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal(err.Error())
 	}
-	
-	db := database.SetDb()
+
+	db := database.SetDb(cfg.Database)
 	router := libraries.SetRouter()
 
+	storage, err := libraries.NewStorage(cfg.Storage)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if cfg.Storage.Driver == "local" {
+		router.Static(cfg.Storage.BaseUrl, cfg.Storage.Path)
+	}
+
 	apiV1 := router.Group("/api/v1")
 
+	transactionManager := repository.NewTransactionManager(db)
+	auditRepository := repository.NewAuditRepository(db)
+	auditService := service.NewAuditService(auditRepository)
+	auditController := controllers.NewAuditController(auditService)
+
+	apiV1.GET("/audit", auditController.GetAll)
+
 	categoryRepository := repository.NewCategoryRepository(db)
-	categoryService := service.NewCategoryService(categoryRepository)
+	categoryService := service.NewCategoryService(transactionManager, categoryRepository, auditService, storage)
 	categoryController := controllers.NewCategoryController(categoryService)
 
 	apiV1Category := apiV1.Group("/category")
 	apiV1Category.GET("", categoryController.GetAll)
+	apiV1Category.GET("/tree", categoryController.Tree)
+	apiV1Category.PUT("/order", categoryController.Reorder)
 	apiV1Category.GET("/:id", categoryController.Get)
 	apiV1Category.POST("", categoryController.Create)
 	apiV1Category.PUT("/:id", categoryController.Update)
 	apiV1Category.DELETE("/:id", categoryController.Delete)
 
 	ingredientRepository := repository.NewIngredientRepository(db)
-	IngredientService := service.NewIngredientService(ingredientRepository)
+	ingredientGroupRepository := repository.NewIngredientGroupRepository(db)
+	IngredientService := service.NewIngredientService(transactionManager, ingredientRepository, ingredientGroupRepository, auditService)
 	ingredientController := controllers.NewIngredientController(IngredientService)
 
 	apiV1Ingredient := apiV1.Group("/ingredient")
@@ -43,22 +68,142 @@ func main() {
 	apiV1Ingredient.PUT("/:id", ingredientController.Update)
 	apiV1Ingredient.DELETE("/:id", ingredientController.Delete)
 
+	ingredientGroupService := service.NewIngredientGroupService(transactionManager, ingredientGroupRepository, ingredientRepository, auditService)
+	ingredientGroupController := controllers.NewIngredientGroupController(ingredientGroupService)
+
+	apiV1IngredientGroup := apiV1.Group("/ingredient-group")
+	apiV1IngredientGroup.GET("", ingredientGroupController.GetAll)
+	apiV1IngredientGroup.GET("/count-sheet", ingredientGroupController.CountSheet)
+	apiV1IngredientGroup.GET("/:id", ingredientGroupController.Get)
+	apiV1IngredientGroup.POST("", ingredientGroupController.Create)
+	apiV1IngredientGroup.PUT("/:id", ingredientGroupController.Update)
+	apiV1IngredientGroup.DELETE("/:id", ingredientGroupController.Delete)
+
 	menuRepository := repository.NewMenuRepository(db)
-	menuService := service.NewMenuService(menuRepository, categoryRepository)
+	menuService := service.NewMenuService(transactionManager, menuRepository, categoryRepository, auditService, storage)
 	menuController := controllers.NewMenuController(menuService)
 	recipeRepository := repository.NewRecipeRepository(db)
-	recipeService := service.NewRecipeService(recipeRepository, menuRepository, ingredientRepository)
+	recipeVersionRepository := repository.NewRecipeVersionRepository(db)
+	recipeService := service.NewRecipeService(transactionManager, recipeRepository, recipeVersionRepository, menuRepository, ingredientRepository, auditService, cfg.Recipe.DuplicateLines)
 	recipeController := controllers.NewRecipeController(recipeService)
+	stepRepository := repository.NewStepRepository(db)
+	stepService := service.NewStepService(transactionManager, stepRepository, menuRepository, auditService)
+	stepController := controllers.NewStepController(stepService)
 
 	apiV1Menu := apiV1.Group("/menu")
 	apiV1Menu.GET("", menuController.GetAll)
 	apiV1Menu.GET("/:id", menuController.Get)
+	apiV1Menu.GET("/:id/scale", menuController.Scale)
+	apiV1Menu.GET("/:id/recipe-card.pdf", menuController.RecipeCard)
+	apiV1Menu.GET("/:id/nutrition-label.pdf", menuController.NutritionLabel)
+	apiV1Menu.GET("/book.pdf", menuController.MenuBook)
 	apiV1Menu.POST("", menuController.Create)
+	apiV1Menu.PUT("/order", menuController.Reorder)
 	apiV1Menu.PUT("/:id", menuController.Update)
 	apiV1Menu.DELETE("/:id", menuController.Delete)
+	apiV1Menu.PUT("/:menu_id/recipe", recipeController.Replace)
 	apiV1Menu.POST("/:menu_id/recipe/", recipeController.Add)
 	apiV1Menu.PUT("/:menu_id/recipe/:id", recipeController.Update)
 	apiV1Menu.DELETE("/:menu_id/recipe/:id", recipeController.Delete)
+	apiV1Menu.GET("/:menu_id/steps", stepController.GetAll)
+	apiV1Menu.POST("/:menu_id/steps", stepController.Create)
+	apiV1Menu.PUT("/:menu_id/steps/order", stepController.Reorder)
+	apiV1Menu.PUT("/:menu_id/steps/:id", stepController.Update)
+	apiV1Menu.DELETE("/:menu_id/steps/:id", stepController.Delete)
+
+	bulkService := service.NewBulkService(transactionManager, categoryService, IngredientService, menuService, recipeService)
+	bulkController := controllers.NewBulkController(bulkService)
+
+	apiV1Category.POST("/bulk", bulkController.CreateCategories)
+	apiV1Category.PUT("/bulk", bulkController.UpdateCategories)
+	apiV1Category.DELETE("/bulk", bulkController.DeleteCategories)
+	apiV1Ingredient.POST("/bulk", bulkController.CreateIngredients)
+	apiV1Ingredient.PUT("/bulk", bulkController.UpdateIngredients)
+	apiV1Ingredient.DELETE("/bulk", bulkController.DeleteIngredients)
+	apiV1Menu.POST("/bulk", bulkController.CreateMenus)
+	apiV1Menu.PUT("/bulk", bulkController.UpdateMenus)
+	apiV1Menu.DELETE("/bulk", bulkController.DeleteMenus)
+
+	apiV1Recipe := apiV1.Group("/recipe")
+	apiV1Recipe.POST("/bulk", bulkController.CreateRecipes)
+	apiV1Recipe.PUT("/bulk", bulkController.UpdateRecipes)
+	apiV1Recipe.DELETE("/bulk", bulkController.DeleteRecipes)
+
+	importService := service.NewImportService(transactionManager, categoryRepository, ingredientRepository, menuRepository, recipeRepository, categoryService, IngredientService, menuService, recipeService)
+	importController := controllers.NewImportController(importService)
+
+	apiV1.POST("/import", importController.Import)
+
+	changeRepository := repository.NewChangeRepository(db)
+	syncService := service.NewSyncService(changeRepository, categoryRepository, ingredientGroupRepository, ingredientRepository, menuRepository, recipeRepository, storage)
+	syncController := controllers.NewSyncController(syncService)
 
-	router.Logger.Fatal(router.Start(":8000"))
+	apiV1.GET("/sync", syncController.Get)
+
+	recipeVersionService := service.NewRecipeVersionService(transactionManager, recipeVersionRepository, menuRepository, ingredientRepository, auditService)
+	recipeVersionController := controllers.NewRecipeVersionController(recipeVersionService)
+
+	apiV1Menu.GET("/:menu_id/versions", recipeVersionController.GetAll)
+	apiV1Menu.POST("/:menu_id/versions", recipeVersionController.Create)
+	apiV1Menu.GET("/:menu_id/versions/effective", recipeVersionController.Effective)
+	apiV1Menu.GET("/:menu_id/versions/:id", recipeVersionController.Get)
+	apiV1Menu.PUT("/:menu_id/versions/:id", recipeVersionController.Update)
+	apiV1Menu.DELETE("/:menu_id/versions/:id", recipeVersionController.Delete)
+	apiV1Menu.POST("/:menu_id/versions/:id/activate", recipeVersionController.Activate)
+	apiV1Menu.POST("/:menu_id/versions/:id/archive", recipeVersionController.Archive)
+	apiV1Menu.GET("/:menu_id/versions/:id/diff", recipeVersionController.Diff)
+
+	purchasingService := service.NewPurchasingService(menuRepository)
+	purchasingController := controllers.NewPurchasingController(purchasingService)
+
+	apiV1.POST("/purchasing/requirements", purchasingController.Requirements)
+
+	menuCloneService := service.NewMenuCloneService(transactionManager, menuRepository, categoryRepository, ingredientRepository, recipeRepository, stepRepository, auditService, storage)
+	menuCloneController := controllers.NewMenuCloneController(menuCloneService)
+
+	apiV1Menu.POST("/:id/clone", menuCloneController.Clone)
+
+	imageService := service.NewImageService(transactionManager, storage, menuRepository, categoryRepository, auditService, cfg.Storage.MaxUploadSize, cfg.Storage.ThumbnailWidth)
+	imageController := controllers.NewImageController(imageService, cfg.Storage.MaxUploadSize)
+
+	apiV1Menu.PUT("/:id/image", imageController.PutMenu)
+	apiV1Menu.DELETE("/:id/image", imageController.DeleteMenu)
+	apiV1Category.PUT("/:id/image", imageController.PutCategory)
+	apiV1Category.DELETE("/:id/image", imageController.DeleteCategory)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// recipe versions staged for a later date are applied once they are due
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				_, err := recipeVersionService.ActivateDue(now)
+				if err != nil {
+					log.Println(err.Error())
+				}
+			}
+		}
+	}()
+
+	go func() {
+		err := router.Start(":8000")
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			router.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = router.Shutdown(shutdownCtx)
+	if err != nil {
+		router.Logger.Fatal(err)
+	}
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type AuditLog struct {
+	Id         int
+	Actor      string
+	EntityType string
+	EntityId   int
+	Action     string
+	Changes    string
+	CreatedAt  time.Time
+}
+
+func (auditLog *AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
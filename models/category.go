@@ -1,10 +1,33 @@
 package models
 
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
 type Category struct {
-	Id   int
-	Name string
+	Id        int
+	Name      string
+	NameKey   string
+	ParentId  *int
+	Position  int
+	ImageKey  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   int `gorm:"default:1"`
 }
 
 func (category *Category) TableName() string {
 	return "categories"
 }
+
+// BeforeSave keeps the name normalized and its key in step with it. The key
+// is only recomputed when the name changed, so legacy duplicates can still be
+// edited.
+func (category *Category) BeforeSave(tx *gorm.DB) error {
+	category.Name = NormalizeName(category.Name)
+	if !SameNameKey(category.NameKey, category.Id, category.Name) {
+		category.NameKey = NameKey(category.Name)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Change is one entry of the master-data change sequence. Its id only ever
+// grows, which makes it usable as a sync cursor.
+type Change struct {
+	Id         int64
+	EntityType string
+	EntityId   int
+	Action     string
+	CreatedAt  time.Time
+}
+
+func (change *Change) TableName() string {
+	return "changes"
+}
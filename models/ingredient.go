@@ -1,10 +1,46 @@
 package models
 
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
 type Ingredient struct {
-	Id   int
-	Name string
+	Id                int
+	Name              string
+	NameKey           string
+	IngredientGroupId *int
+	IngredientGroup   *IngredientGroup
+	// YieldPercent is the edible share of the ingredient as purchased, after
+	// trimming and waste
+	YieldPercent float64 `gorm:"default:100"`
+	Vegan        bool
+	Halal        bool
+	GlutenFree   bool
+	Allergens    []IngredientAllergen
+	// nutrients per 100 g edible portion: calories in kcal, sodium in mg and
+	// the rest in grams, nil when unknown
+	Calories  *float64
+	Protein   *float64
+	Fat       *float64
+	Carbs     *float64
+	Sodium    *float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   int `gorm:"default:1"`
 }
 
 func (ingredient *Ingredient) TableName() string {
 	return "ingredients"
 }
+
+// BeforeSave keeps the name normalized and its key in step with it. The key
+// is only recomputed when the name changed, so legacy duplicates can still be
+// edited.
+func (ingredient *Ingredient) BeforeSave(tx *gorm.DB) error {
+	ingredient.Name = NormalizeName(ingredient.Name)
+	if !SameNameKey(ingredient.NameKey, ingredient.Id, ingredient.Name) {
+		ingredient.NameKey = NameKey(ingredient.Name)
+	}
+	return nil
+}
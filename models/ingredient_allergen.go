@@ -0,0 +1,15 @@
+package models
+
+// Allergens are the allergens an ingredient can be tagged with, following
+// the major food allergen lists used on menus.
+var Allergens = []string{"peanut", "tree_nut", "gluten", "milk", "egg", "fish", "shellfish", "mollusc", "soy", "sesame", "celery", "mustard", "lupin", "sulphite"}
+
+type IngredientAllergen struct {
+	Id           int
+	IngredientId int
+	Allergen     string
+}
+
+func (ingredientAllergen *IngredientAllergen) TableName() string {
+	return "ingredient_allergens"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	StorageAmbient = "ambient"
+	StorageChilled = "chilled"
+	StorageFrozen  = "frozen"
+)
+
+// IngredientGroup sorts ingredients the way the store room does, each group
+// with the conditions its ingredients are kept in. Temperatures are in
+// degrees Celsius.
+type IngredientGroup struct {
+	Id        int
+	Name      string
+	Storage   string `gorm:"default:ambient"`
+	MinTemp   *float64
+	MaxTemp   *float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   int `gorm:"default:1"`
+}
+
+func (group *IngredientGroup) TableName() string {
+	return "ingredient_groups"
+}
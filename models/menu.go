@@ -1,13 +1,38 @@
 package models
 
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
 type Menu struct {
 	Id          int
 	Name        string
+	NameKey     string
 	CategoryId  int
 	Category    Category
+	Position    int
+	YieldQty    string
+	Portions    int `gorm:"default:1"`
+	ImageKey    string
 	Ingredients []MenuIngredient
+	Steps       []MenuStep
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Version     int `gorm:"default:1"`
 }
 
 func (menu *Menu) TableName() string {
 	return "menus"
 }
+
+// BeforeSave keeps the name normalized and its key in step with it. The key
+// is only recomputed when the name changed, so legacy duplicates can still be
+// edited.
+func (menu *Menu) BeforeSave(tx *gorm.DB) error {
+	menu.Name = NormalizeName(menu.Name)
+	if !SameNameKey(menu.NameKey, menu.Id, menu.Name) {
+		menu.NameKey = NameKey(menu.Name)
+	}
+	return nil
+}
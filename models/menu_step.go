@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type MenuStep struct {
+	Id              int
+	MenuId          int
+	Position        int
+	Text            string
+	DurationMinutes int
+	Station         string
+	ImageUrl        string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Version         int `gorm:"default:1"`
+}
+
+func (step *MenuStep) TableName() string {
+	return "menu_steps"
+}
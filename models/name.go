@@ -0,0 +1,25 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NormalizeName trims a name and collapses the whitespace inside it, so
+// " garam  halus " is stored as "garam halus".
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NameKey is the form names are compared in for uniqueness: normalized and
+// lower case.
+func NameKey(name string) string {
+	return strings.ToLower(NormalizeName(name))
+}
+
+// SameNameKey reports whether key, the stored name key of record id, already
+// stands for name. Legacy duplicates keep the "#id" suffix the name key
+// migration gave them until they are renamed.
+func SameNameKey(key string, id int, name string) bool {
+	return strings.TrimSuffix(key, "#"+strconv.Itoa(id)) == NameKey(name)
+}
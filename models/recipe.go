@@ -1,11 +1,18 @@
 package models
 
+import "time"
+
 type MenuIngredient struct {
 	Id           int
 	MenuId       int
 	IngredientId int
 	Qty          string
+	// YieldPercent overrides the yield of the ingredient for this line
+	YieldPercent *float64
 	Ingredient   Ingredient
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Version      int `gorm:"default:1"`
 }
 
 func (recipe *MenuIngredient) TableName() string {
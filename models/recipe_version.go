@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	RecipeVersionDraft    = "draft"
+	RecipeVersionActive   = "active"
+	RecipeVersionArchived = "archived"
+)
+
+// RecipeVersion is a snapshot of the formula of a menu. An active version
+// takes effect at EffectiveFrom; AppliedAt is set once its lines have been
+// copied to the live recipes of the menu.
+type RecipeVersion struct {
+	Id            int
+	MenuId        int
+	Number        int
+	Status        string `gorm:"default:draft"`
+	Note          string
+	EffectiveFrom *time.Time
+	AppliedAt     *time.Time
+	Lines         []RecipeVersionLine
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Version       int `gorm:"default:1"`
+}
+
+func (recipeVersion *RecipeVersion) TableName() string {
+	return "recipe_versions"
+}
+
+type RecipeVersionLine struct {
+	Id              int
+	RecipeVersionId int
+	IngredientId    int
+	Qty             string
+	Ingredient      Ingredient
+}
+
+func (line *RecipeVersionLine) TableName() string {
+	return "recipe_version_lines"
+}
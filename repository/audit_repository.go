@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type AuditRepository interface {
+	Create(auditLog models.AuditLog) (models.AuditLog, error)
+	All(entityType string, entityId int, from time.Time, to time.Time) ([]models.AuditLog, error)
+	Each(entityType string, entityId int, from time.Time, to time.Time, fn func(auditLog models.AuditLog) error) error
+	WithTx(tx *gorm.DB) AuditRepository
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+func (auditRepository *auditRepository) WithTx(tx *gorm.DB) AuditRepository {
+	return NewAuditRepository(tx)
+}
+
+func (auditRepository *auditRepository) Create(auditLog models.AuditLog) (models.AuditLog, error) {
+	err := auditRepository.db.Create(&auditLog).Error
+	if err != nil {
+		return auditLog, err
+	}
+
+	return auditLog, nil
+}
+
+func (auditRepository *auditRepository) All(entityType string, entityId int, from time.Time, to time.Time) ([]models.AuditLog, error) {
+	var listAuditLog []models.AuditLog
+
+	err := auditRepository.allQuery(entityType, entityId, from, to).Order("created_at desc").Order("id desc").Find(&listAuditLog).Error
+
+	if err != nil {
+		return listAuditLog, err
+	}
+
+	return listAuditLog, nil
+}
+
+// Each walks the same audit logs as All, in the same newest first order. Logs
+// written while the export runs are left out, so they cannot shift the pages.
+func (auditRepository *auditRepository) Each(entityType string, entityId int, from time.Time, to time.Time, fn func(auditLog models.AuditLog) error) error {
+	var lastId int
+	err := auditRepository.db.Model(&models.AuditLog{}).Select("COALESCE(MAX(id), 0)").Scan(&lastId).Error
+	if err != nil {
+		return err
+	}
+
+	query := auditRepository.allQuery(entityType, entityId, from, to).Where("id <= ?", lastId).Order("created_at desc").Order("id desc")
+
+	return eachPage(query, fn)
+}
+
+func (auditRepository *auditRepository) allQuery(entityType string, entityId int, from time.Time, to time.Time) *gorm.DB {
+	query := auditRepository.db
+
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+
+	if entityId != 0 {
+		query = query.Where("entity_id = ?", entityId)
+	}
+
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+
+	return query
+}
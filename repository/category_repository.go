@@ -1,16 +1,30 @@
 package repository
 
 import (
+	"errors"
 	"github.com/erp_app/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
+var ErrCategoryOrderMismatch = errors.New("category_ids must list every category under the parent exactly once")
+
 type CategoryRepository interface {
-	All(name string) ([]models.Category, error)
+	All(name string, updatedSince time.Time) ([]models.Category, error)
+	Each(name string, updatedSince time.Time, fn func(category models.Category) error) error
 	Find(id int) (models.Category, error)
+	FindForUpdate(id int) (models.Category, error)
+	FindByName(name string) (models.Category, error)
+	AllByIds(ids []int) ([]models.Category, error)
+	DescendantIds(id int) ([]int, error)
+	CountChildren(id int) (int64, error)
+	NextPosition(parentId *int) (int, error)
+	Reorder(parentId *int, categoryIds []int) ([]models.Category, error)
 	Create(category models.Category) (models.Category, error)
 	Update(category models.Category) (models.Category, error)
 	Delete(category models.Category) error
+	WithTx(tx *gorm.DB) CategoryRepository
 }
 
 type categoryRepository struct {
@@ -23,15 +37,14 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 	}
 }
 
-func (categoryRepository *categoryRepository) All(name string) ([]models.Category, error) {
-	var listCategories []models.Category
-	query := categoryRepository.db
+func (categoryRepository *categoryRepository) WithTx(tx *gorm.DB) CategoryRepository {
+	return NewCategoryRepository(tx)
+}
 
-	if name != "" {
-		query = query.Where("name Like ?", "%"+name+"%")
-	}
+func (categoryRepository *categoryRepository) All(name string, updatedSince time.Time) ([]models.Category, error) {
+	var listCategories []models.Category
 
-	err := query.Find(&listCategories).Error
+	err := categoryRepository.allQuery(name, updatedSince).Scopes(orderByTree).Find(&listCategories).Error
 
 	if err != nil {
 		return listCategories, err
@@ -40,6 +53,24 @@ func (categoryRepository *categoryRepository) All(name string) ([]models.Categor
 	return listCategories, nil
 }
 
+func (categoryRepository *categoryRepository) Each(name string, updatedSince time.Time, fn func(category models.Category) error) error {
+	return eachPage(categoryRepository.allQuery(name, updatedSince).Scopes(orderByTree), fn)
+}
+
+func (categoryRepository *categoryRepository) allQuery(name string, updatedSince time.Time) *gorm.DB {
+	query := categoryRepository.db
+
+	if name != "" {
+		query = whereContains(query, "name", name)
+	}
+
+	if !updatedSince.IsZero() {
+		query = query.Where("updated_at >= ?", updatedSince)
+	}
+
+	return query
+}
+
 func (categoryRepository *categoryRepository) Find(id int) (models.Category, error) {
 	category := models.Category{}
 	err := categoryRepository.db.First(&category, id).Error
@@ -50,8 +81,49 @@ func (categoryRepository *categoryRepository) Find(id int) (models.Category, err
 	return category, nil
 }
 
+// FindForUpdate loads the category and locks its row until the transaction
+// ends, so concurrent moves in the tree see each other's parent.
+func (categoryRepository *categoryRepository) FindForUpdate(id int) (models.Category, error) {
+	category := models.Category{}
+	err := categoryRepository.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&category, id).Error
+	if err != nil {
+		return category, err
+	}
+
+	return category, nil
+}
+
+// FindByName looks up a category by its name, ignoring case and extra
+// whitespace.
+func (categoryRepository *categoryRepository) FindByName(name string) (models.Category, error) {
+	category := models.Category{}
+	err := categoryRepository.db.Where("name_key = ?", models.NameKey(name)).First(&category).Error
+	if err != nil {
+		return category, err
+	}
+
+	return category, nil
+}
+
+// Create appends the category after the last one under the same parent.
 func (categoryRepository *categoryRepository) Create(category models.Category) (models.Category, error) {
-	err := categoryRepository.db.Create(&category).Error
+	category.Version = 1
+
+	err := categoryRepository.db.Transaction(func(tx *gorm.DB) error {
+		position, err := NewCategoryRepository(tx).NextPosition(category.ParentId)
+		if err != nil {
+			return err
+		}
+
+		category.Position = position
+
+		err = tx.Create(&category).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "category", category.Id, models.AuditActionCreate)
+	})
 	if err != nil {
 		return category, err
 	}
@@ -60,7 +132,17 @@ func (categoryRepository *categoryRepository) Create(category models.Category) (
 }
 
 func (categoryRepository *categoryRepository) Update(category models.Category) (models.Category, error) {
-	err := categoryRepository.db.Save(&category).Error
+	version := category.Version
+	category.Version = version + 1
+
+	err := categoryRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &category, version)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "category", category.Id, models.AuditActionUpdate)
+	})
 	if err != nil {
 		return category, err
 	}
@@ -69,10 +151,131 @@ func (categoryRepository *categoryRepository) Update(category models.Category) (
 }
 
 func (categoryRepository *categoryRepository) Delete(category models.Category) error {
-	err := categoryRepository.db.Delete(&category).Error
+	err := categoryRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := deleteVersioned(tx, &category, category.Version)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "category", category.Id, models.AuditActionDelete)
+	})
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (categoryRepository *categoryRepository) AllByIds(ids []int) ([]models.Category, error) {
+	var listCategory []models.Category
+
+	if len(ids) == 0 {
+		return listCategory, nil
+	}
+
+	err := categoryRepository.db.Where("id IN ?", ids).Find(&listCategory).Error
+
+	if err != nil {
+		return listCategory, err
+	}
+
+	return listCategory, nil
+}
+
+// DescendantIds returns the id of the category and of every category below
+// it. UNION rather than UNION ALL keeps a cycle in the data from recursing
+// forever.
+func (categoryRepository *categoryRepository) DescendantIds(id int) ([]int, error) {
+	var ids []int
+
+	err := categoryRepository.db.Raw("WITH RECURSIVE tree (id) AS (SELECT id FROM categories WHERE id = ? UNION SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id) SELECT id FROM tree", id).Scan(&ids).Error
+	if err != nil {
+		return ids, err
+	}
+
+	return ids, nil
+}
+
+func (categoryRepository *categoryRepository) CountChildren(id int) (int64, error) {
+	var count int64
+
+	err := categoryRepository.db.Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// orderByTree lists categories depth first, each parent followed by its
+// children in position order.
+func orderByTree(db *gorm.DB) *gorm.DB {
+	return joinCategoryTree(db, "category_tree.id = categories.id").Order("category_tree.sort_path").Order("categories.id")
+}
+
+func whereParent(query *gorm.DB, parentId *int) *gorm.DB {
+	if parentId == nil {
+		return query.Where("parent_id IS NULL")
+	}
+
+	return query.Where("parent_id = ?", *parentId)
+}
+
+// NextPosition is the position after the last category under the parent.
+func (categoryRepository *categoryRepository) NextPosition(parentId *int) (int, error) {
+	var last int
+
+	err := whereParent(categoryRepository.db.Model(&models.Category{}), parentId).Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return last + 1, nil
+}
+
+// Reorder gives the categories under a parent the order of categoryIds,
+// which must name each of them exactly once.
+func (categoryRepository *categoryRepository) Reorder(parentId *int, categoryIds []int) ([]models.Category, error) {
+	var listCategory []models.Category
+
+	err := categoryRepository.db.Transaction(func(tx *gorm.DB) error {
+		var current []models.Category
+		err := whereParent(tx, parentId).Scopes(orderByPosition).Find(&current).Error
+		if err != nil {
+			return err
+		}
+
+		positions, err := orderPositions(len(current), categoryIds, ErrCategoryOrderMismatch)
+		if err != nil {
+			return err
+		}
+
+		for _, category := range current {
+			position, ok := positions[category.Id]
+			if !ok {
+				return ErrCategoryOrderMismatch
+			}
+
+			if position == category.Position {
+				continue
+			}
+
+			err = tx.Model(&category).Updates(map[string]interface{}{"position": position, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+
+			err = recordChange(tx, "category", category.Id, models.AuditActionUpdate)
+			if err != nil {
+				return err
+			}
+		}
+
+		return whereParent(tx, parentId).Scopes(orderByPosition).Find(&listCategory).Error
+	})
+	if err != nil {
+		return listCategory, err
+	}
+
+	return listCategory, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ChangeRepository interface {
+	Since(seq int64, limit int, settledBefore time.Time) ([]models.Change, error)
+}
+
+type changeRepository struct {
+	db *gorm.DB
+}
+
+func NewChangeRepository(db *gorm.DB) ChangeRepository {
+	return &changeRepository{
+		db: db,
+	}
+}
+
+// Since returns the changes after seq in id order. Ids are handed out when a
+// change is written but become visible when its transaction commits, so a
+// gap in the ids may be a write that is still running. Since stops before a
+// gap until the change after it was written before settledBefore; by then
+// the missing id belongs to a transaction that rolled back.
+func (changeRepository *changeRepository) Since(seq int64, limit int, settledBefore time.Time) ([]models.Change, error) {
+	var listChange []models.Change
+
+	err := changeRepository.db.Where("id > ?", seq).Order("id").Limit(limit).Find(&listChange).Error
+
+	if err != nil {
+		return listChange, err
+	}
+
+	next := seq + 1
+	for i, change := range listChange {
+		if change.Id != next && change.CreatedAt.After(settledBefore) {
+			return listChange[:i], nil
+		}
+		next = change.Id + 1
+	}
+
+	return listChange, nil
+}
+
+// recordChange appends an entry to the change sequence. Repositories call it
+// in the same transaction as the write so the sequence never misses a change.
+func recordChange(tx *gorm.DB, entityType string, entityId int, action string) error {
+	return tx.Create(&models.Change{EntityType: entityType, EntityId: entityId, Action: action}).Error
+}
@@ -0,0 +1,5 @@
+package repository
+
+import "errors"
+
+var ErrVersionConflict = errors.New("the record has been changed by someone else, reload it and try again")
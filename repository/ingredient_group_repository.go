@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+)
+
+type IngredientGroupRepository interface {
+	All(name string) ([]models.IngredientGroup, error)
+	Find(id int) (models.IngredientGroup, error)
+	AllByIds(ids []int) ([]models.IngredientGroup, error)
+	Create(group models.IngredientGroup) (models.IngredientGroup, error)
+	Update(group models.IngredientGroup) (models.IngredientGroup, error)
+	Delete(group models.IngredientGroup) error
+	CountIngredients(id int) (int64, error)
+	WithTx(tx *gorm.DB) IngredientGroupRepository
+}
+
+type ingredientGroupRepository struct {
+	db *gorm.DB
+}
+
+func NewIngredientGroupRepository(db *gorm.DB) IngredientGroupRepository {
+	return &ingredientGroupRepository{
+		db: db,
+	}
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) WithTx(tx *gorm.DB) IngredientGroupRepository {
+	return NewIngredientGroupRepository(tx)
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) All(name string) ([]models.IngredientGroup, error) {
+	var listGroup []models.IngredientGroup
+
+	query := ingredientGroupRepository.db
+	if name != "" {
+		query = whereContains(query, "name", name)
+	}
+
+	err := query.Order("name").Find(&listGroup).Error
+	if err != nil {
+		return listGroup, err
+	}
+
+	return listGroup, nil
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) Find(id int) (models.IngredientGroup, error) {
+	group := models.IngredientGroup{}
+	err := ingredientGroupRepository.db.First(&group, id).Error
+	if err != nil {
+		return group, err
+	}
+
+	return group, nil
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) AllByIds(ids []int) ([]models.IngredientGroup, error) {
+	var listGroup []models.IngredientGroup
+
+	if len(ids) == 0 {
+		return listGroup, nil
+	}
+
+	err := ingredientGroupRepository.db.Where("id IN ?", ids).Find(&listGroup).Error
+	if err != nil {
+		return listGroup, err
+	}
+
+	return listGroup, nil
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) Create(group models.IngredientGroup) (models.IngredientGroup, error) {
+	group.Version = 1
+
+	err := ingredientGroupRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&group).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "ingredient_group", group.Id, models.AuditActionCreate)
+	})
+	if err != nil {
+		return group, err
+	}
+
+	return group, nil
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) Update(group models.IngredientGroup) (models.IngredientGroup, error) {
+	version := group.Version
+	group.Version = version + 1
+
+	err := ingredientGroupRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &group, version)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "ingredient_group", group.Id, models.AuditActionUpdate)
+	})
+	if err != nil {
+		return group, err
+	}
+
+	return group, nil
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) Delete(group models.IngredientGroup) error {
+	err := ingredientGroupRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := deleteVersioned(tx, &group, group.Version)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "ingredient_group", group.Id, models.AuditActionDelete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ingredientGroupRepository *ingredientGroupRepository) CountIngredients(id int) (int64, error) {
+	var count int64
+
+	err := ingredientGroupRepository.db.Model(&models.Ingredient{}).Where("ingredient_group_id = ?", id).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
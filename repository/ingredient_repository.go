@@ -3,14 +3,20 @@ package repository
 import (
 	"github.com/erp_app/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type IngredientRepository interface {
-	All(name string) ([]models.Ingredient, error)
+	All(name string, updatedSince time.Time, groupId int) ([]models.Ingredient, error)
+	Each(name string, updatedSince time.Time, groupId int, fn func(ingredient models.Ingredient) error) error
 	Find(id int) (models.Ingredient, error)
+	FindByName(name string) (models.Ingredient, error)
+	AllByIds(ids []int) ([]models.Ingredient, error)
 	Create(ingredient models.Ingredient) (models.Ingredient, error)
 	Update(ingredient models.Ingredient) (models.Ingredient, error)
 	Delete(ingredient models.Ingredient) error
+	WithTx(tx *gorm.DB) IngredientRepository
 }
 
 type ingredientRepository struct {
@@ -23,26 +29,70 @@ func NewIngredientRepository(db *gorm.DB) IngredientRepository {
 	}
 }
 
-func (ingredientRepository *ingredientRepository) All(name string) ([]models.Ingredient, error) {
+func (ingredientRepository *ingredientRepository) WithTx(tx *gorm.DB) IngredientRepository {
+	return NewIngredientRepository(tx)
+}
+
+func (ingredientRepository *ingredientRepository) All(name string, updatedSince time.Time, groupId int) ([]models.Ingredient, error) {
 	var listIngredient []models.Ingredient
+
+	err := ingredientRepository.allQuery(name, updatedSince, groupId).Preload("Allergens").Preload("IngredientGroup").Find(&listIngredient).Error
+
+	if err != nil {
+		return listIngredient, err
+	}
+
+	return listIngredient, nil
+}
+
+func (ingredientRepository *ingredientRepository) Each(name string, updatedSince time.Time, groupId int, fn func(ingredient models.Ingredient) error) error {
+	var batch []models.Ingredient
+
+	return ingredientRepository.allQuery(name, updatedSince, groupId).Preload("Allergens").Preload("IngredientGroup").FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, ingredient := range batch {
+			err := fn(ingredient)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}).Error
+}
+
+func (ingredientRepository *ingredientRepository) allQuery(name string, updatedSince time.Time, groupId int) *gorm.DB {
 	query := ingredientRepository.db
 
 	if name != "" {
-		query = query.Where("name Like ?", "%"+name+"%")
+		query = whereContains(query, "name", name)
 	}
 
-	err := query.Find(&listIngredient).Error
+	if !updatedSince.IsZero() {
+		query = query.Where("updated_at >= ?", updatedSince)
+	}
 
-	if err != nil {
-		return listIngredient, err
+	if groupId != 0 {
+		query = query.Where("ingredient_group_id = ?", groupId)
 	}
 
-	return listIngredient, nil
+	return query
 }
 
 func (ingredientRepository *ingredientRepository) Find(id int) (models.Ingredient, error) {
 	ingredient := models.Ingredient{}
-	err := ingredientRepository.db.First(&ingredient, id).Error
+	err := ingredientRepository.db.Preload("Allergens").Preload("IngredientGroup").First(&ingredient, id).Error
+	if err != nil {
+		return ingredient, err
+	}
+
+	return ingredient, nil
+}
+
+// FindByName looks up a ingredient by its name, ignoring case and extra
+// whitespace.
+func (ingredientRepository *ingredientRepository) FindByName(name string) (models.Ingredient, error) {
+	ingredient := models.Ingredient{}
+	err := ingredientRepository.db.Preload("Allergens").Preload("IngredientGroup").Where("name_key = ?", models.NameKey(name)).First(&ingredient).Error
 	if err != nil {
 		return ingredient, err
 	}
@@ -51,7 +101,21 @@ func (ingredientRepository *ingredientRepository) Find(id int) (models.Ingredien
 }
 
 func (ingredientRepository *ingredientRepository) Create(ingredient models.Ingredient) (models.Ingredient, error) {
-	err := ingredientRepository.db.Create(&ingredient).Error
+	ingredient.Version = 1
+
+	err := ingredientRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit(clause.Associations).Create(&ingredient).Error
+		if err != nil {
+			return err
+		}
+
+		err = replaceAllergens(tx, &ingredient)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "ingredient", ingredient.Id, models.AuditActionCreate)
+	})
 	if err != nil {
 		return ingredient, err
 	}
@@ -60,7 +124,27 @@ func (ingredientRepository *ingredientRepository) Create(ingredient models.Ingre
 }
 
 func (ingredientRepository *ingredientRepository) Update(ingredient models.Ingredient) (models.Ingredient, error) {
-	err := ingredientRepository.db.Save(&ingredient).Error
+	version := ingredient.Version
+	ingredient.Version = version + 1
+
+	err := ingredientRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &ingredient, version)
+		if err != nil {
+			return err
+		}
+
+		err = replaceAllergens(tx, &ingredient)
+		if err != nil {
+			return err
+		}
+
+		err = recordChange(tx, "ingredient", ingredient.Id, models.AuditActionUpdate)
+		if err != nil {
+			return err
+		}
+
+		return touchMenusUsing(tx, ingredient.Id)
+	})
 	if err != nil {
 		return ingredient, err
 	}
@@ -69,10 +153,83 @@ func (ingredientRepository *ingredientRepository) Update(ingredient models.Ingre
 }
 
 func (ingredientRepository *ingredientRepository) Delete(ingredient models.Ingredient) error {
-	err := ingredientRepository.db.Delete(&ingredient).Error
+	err := ingredientRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := deleteVersioned(tx, &ingredient, ingredient.Version)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("ingredient_id = ?", ingredient.Id).Delete(&models.IngredientAllergen{}).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "ingredient", ingredient.Id, models.AuditActionDelete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ingredientRepository *ingredientRepository) AllByIds(ids []int) ([]models.Ingredient, error) {
+	var listIngredient []models.Ingredient
+
+	if len(ids) == 0 {
+		return listIngredient, nil
+	}
+
+	err := ingredientRepository.db.Preload("Allergens").Preload("IngredientGroup").Where("id IN ?", ids).Find(&listIngredient).Error
+
+	if err != nil {
+		return listIngredient, err
+	}
+
+	return listIngredient, nil
+}
+
+// touchMenusUsing marks every menu with a recipe line for the ingredient as
+// changed, since the allergens, dietary flags and nutrition rolled up on those
+// menus come from their ingredients.
+func touchMenusUsing(tx *gorm.DB, ingredientId int) error {
+	var menuIds []int
+	err := tx.Model(&models.MenuIngredient{}).Where("ingredient_id = ?", ingredientId).Distinct().Pluck("menu_id", &menuIds).Error
 	if err != nil {
 		return err
 	}
 
+	for _, menuId := range menuIds {
+		err = touchMenu(tx, menuId)
+		if err != nil {
+			return err
+		}
+
+		err = recordChange(tx, "menu", menuId, models.AuditActionUpdate)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// replaceAllergens stores the allergens of the ingredient in place of the
+// ones it had.
+func replaceAllergens(tx *gorm.DB, ingredient *models.Ingredient) error {
+	err := tx.Where("ingredient_id = ?", ingredient.Id).Delete(&models.IngredientAllergen{}).Error
+	if err != nil {
+		return err
+	}
+
+	if len(ingredient.Allergens) == 0 {
+		return nil
+	}
+
+	for i := range ingredient.Allergens {
+		ingredient.Allergens[i].Id = 0
+		ingredient.Allergens[i].IngredientId = ingredient.Id
+	}
+
+	return tx.Create(&ingredient.Allergens).Error
+}
@@ -1,16 +1,27 @@
 package repository
 
 import (
+	"errors"
 	"github.com/erp_app/models"
 	"gorm.io/gorm"
+	"time"
 )
 
+var ErrMenuOrderMismatch = errors.New("menu_ids must list every menu of the category exactly once")
+
 type MenuRepository interface {
 	Create(menu models.Menu) (models.Menu, error)
 	Update(menu models.Menu) (models.Menu, error)
 	Find(id int) (models.Menu, error)
-	All(name string) ([]models.Menu, error)
+	AllByName(name string) ([]models.Menu, error)
+	FindInCategory(categoryId int, name string) (models.Menu, error)
+	AllByIds(ids []int) ([]models.Menu, error)
+	All(name string, updatedSince time.Time, excludeAllergens []string, categoryIds []int) ([]models.Menu, error)
+	Each(name string, updatedSince time.Time, excludeAllergens []string, categoryIds []int, fn func(menu models.Menu) error) error
 	Delete(ingredient models.Menu) error
+	NextPosition(categoryId int) (int, error)
+	Reorder(categoryId int, menuIds []int) ([]models.Menu, error)
+	WithTx(tx *gorm.DB) MenuRepository
 }
 
 type menuRepository struct {
@@ -23,8 +34,53 @@ func NewMenuRepository(db *gorm.DB) MenuRepository {
 	}
 }
 
+func (menuRepository *menuRepository) WithTx(tx *gorm.DB) MenuRepository {
+	return NewMenuRepository(tx)
+}
+
+// AllByName lists the menus with the name, ignoring case and extra
+// whitespace. Menu names are only unique within a category, so there may be
+// one per category.
+func (menuRepository *menuRepository) AllByName(name string) ([]models.Menu, error) {
+	var listMenu []models.Menu
+
+	err := menuRepository.db.Where("name_key = ?", models.NameKey(name)).Order("id").Find(&listMenu).Error
+	if err != nil {
+		return listMenu, err
+	}
+
+	return listMenu, nil
+}
+
+func (menuRepository *menuRepository) FindInCategory(categoryId int, name string) (models.Menu, error) {
+	menu := models.Menu{}
+	err := menuRepository.db.Where("category_id = ? AND name_key = ?", categoryId, models.NameKey(name)).First(&menu).Error
+	if err != nil {
+		return menu, err
+	}
+
+	return menu, nil
+}
+
+// Create appends the menu after the last menu of its category.
 func (menuRepository *menuRepository) Create(menu models.Menu) (models.Menu, error) {
-	err := menuRepository.db.Create(&menu).Error
+	menu.Version = 1
+
+	err := menuRepository.db.Transaction(func(tx *gorm.DB) error {
+		position, err := NewMenuRepository(tx).NextPosition(menu.CategoryId)
+		if err != nil {
+			return err
+		}
+
+		menu.Position = position
+
+		err = tx.Create(&menu).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "menu", menu.Id, models.AuditActionCreate)
+	})
 	if err != nil {
 		return menu, err
 	}
@@ -33,7 +89,17 @@ func (menuRepository *menuRepository) Create(menu models.Menu) (models.Menu, err
 }
 
 func (menuRepository *menuRepository) Update(menu models.Menu) (models.Menu, error) {
-	err := menuRepository.db.Save(&menu).Error
+	version := menu.Version
+	menu.Version = version + 1
+
+	err := menuRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &menu, version)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "menu", menu.Id, models.AuditActionUpdate)
+	})
 	if err != nil {
 		return menu, err
 	}
@@ -43,7 +109,7 @@ func (menuRepository *menuRepository) Update(menu models.Menu) (models.Menu, err
 
 func (menuRepository *menuRepository) Find(id int) (models.Menu, error) {
 	menu := models.Menu{}
-	err := menuRepository.db.Preload("Category").Preload("Ingredients").Preload("Ingredients.Ingredient").First(&menu, id).Error
+	err := menuRepository.db.Preload("Category").Preload("Ingredients", orderById).Preload("Ingredients.Ingredient").Preload("Ingredients.Ingredient.Allergens").Preload("Ingredients.Ingredient.IngredientGroup").Preload("Steps", orderByPosition).First(&menu, id).Error
 	if err != nil {
 		return menu, err
 	}
@@ -51,28 +117,154 @@ func (menuRepository *menuRepository) Find(id int) (models.Menu, error) {
 	return menu, nil
 }
 
-func (menuRepository *menuRepository) All(name string) ([]models.Menu, error) {
+func (menuRepository *menuRepository) All(name string, updatedSince time.Time, excludeAllergens []string, categoryIds []int) ([]models.Menu, error) {
 	var listMenu []models.Menu
+
+	err := menuRepository.allQuery(name, updatedSince, excludeAllergens, categoryIds).Scopes(orderByDisplay).Preload("Category").Preload("Ingredients", orderById).Preload("Ingredients.Ingredient").Preload("Ingredients.Ingredient.Allergens").Preload("Steps", orderByPosition).Find(&listMenu).Error
+
+	if err != nil {
+		return listMenu, err
+	}
+
+	return listMenu, nil
+}
+
+func (menuRepository *menuRepository) Each(name string, updatedSince time.Time, excludeAllergens []string, categoryIds []int, fn func(menu models.Menu) error) error {
+	query := menuRepository.allQuery(name, updatedSince, excludeAllergens, categoryIds).Scopes(orderByDisplay).Preload("Category").Preload("Ingredients", orderById).Preload("Ingredients.Ingredient").Preload("Ingredients.Ingredient.Allergens")
+
+	return eachPage(query, fn)
+}
+
+func (menuRepository *menuRepository) allQuery(name string, updatedSince time.Time, excludeAllergens []string, categoryIds []int) *gorm.DB {
 	query := menuRepository.db
 
 	if name != "" {
-		query = query.Where("name Like ?", "%"+name+"%")
+		query = whereContains(query, "name", name)
 	}
 
-	err := query.Preload("Category").Preload("Ingredients").Preload("Ingredients.Ingredient").Find(&listMenu).Error
+	if !updatedSince.IsZero() {
+		query = query.Where("updated_at >= ?", updatedSince)
+	}
 
-	if err != nil {
-		return listMenu, err
+	if len(excludeAllergens) > 0 {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM recipes JOIN ingredient_allergens ON ingredient_allergens.ingredient_id = recipes.ingredient_id WHERE recipes.menu_id = menus.id AND ingredient_allergens.allergen IN ?)", excludeAllergens)
 	}
 
-	return listMenu, nil
+	if categoryIds != nil {
+		query = query.Where("category_id IN ?", categoryIds)
+	}
+
+	return query
 }
 
 func (menuRepository *menuRepository) Delete(menu models.Menu) error {
-	err := menuRepository.db.Delete(&menu).Error
+	err := menuRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := deleteVersioned(tx, &menu, menu.Version)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("menu_id = ?", menu.Id).Delete(&models.MenuStep{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("recipe_version_id IN (?)", tx.Model(&models.RecipeVersion{}).Select("id").Where("menu_id = ?", menu.Id)).Delete(&models.RecipeVersionLine{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("menu_id = ?", menu.Id).Delete(&models.RecipeVersion{}).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, "menu", menu.Id, models.AuditActionDelete)
+	})
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (menuRepository *menuRepository) AllByIds(ids []int) ([]models.Menu, error) {
+	var listMenu []models.Menu
+
+	if len(ids) == 0 {
+		return listMenu, nil
+	}
+
+	err := menuRepository.db.Preload("Category").Preload("Ingredients", orderById).Preload("Ingredients.Ingredient").Preload("Ingredients.Ingredient.Allergens").Where("id IN ?", ids).Find(&listMenu).Error
+
+	if err != nil {
+		return listMenu, err
+	}
+
+	return listMenu, nil
+}
+
+// orderByDisplay sorts menus the way the POS shows them: by where their
+// category sits in the category tree, then by their own position within it.
+func orderByDisplay(db *gorm.DB) *gorm.DB {
+	return joinCategoryTree(db, "category_tree.id = menus.category_id").Order("category_tree.sort_path").Order("menus.position").Order("menus.id")
+}
+
+// NextPosition is the position after the last menu of the category.
+func (menuRepository *menuRepository) NextPosition(categoryId int) (int, error) {
+	var last int
+
+	err := menuRepository.db.Model(&models.Menu{}).Where("category_id = ?", categoryId).Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return last + 1, nil
+}
+
+// Reorder gives the menus of a category the order of menuIds, which must name
+// each of them exactly once.
+func (menuRepository *menuRepository) Reorder(categoryId int, menuIds []int) ([]models.Menu, error) {
+	var listMenu []models.Menu
+
+	err := menuRepository.db.Transaction(func(tx *gorm.DB) error {
+		var current []models.Menu
+		err := tx.Where("category_id = ?", categoryId).Find(&current).Error
+		if err != nil {
+			return err
+		}
+
+		positions, err := orderPositions(len(current), menuIds, ErrMenuOrderMismatch)
+		if err != nil {
+			return err
+		}
+
+		for _, menu := range current {
+			position, ok := positions[menu.Id]
+			if !ok {
+				return ErrMenuOrderMismatch
+			}
+
+			if position == menu.Position {
+				continue
+			}
+
+			err = tx.Model(&menu).Updates(map[string]interface{}{"position": position, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+
+			err = recordChange(tx, "menu", menu.Id, models.AuditActionUpdate)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Preload("Category").Where("category_id = ?", categoryId).Scopes(orderByPosition).Find(&listMenu).Error
+	})
+	if err != nil {
+		return listMenu, err
+	}
+
+	return listMenu, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// whereContains filters column by a case-insensitive substring match that
+// behaves the same on mysql, postgres and sqlite. Wildcards typed by the user
+// are matched literally.
+func whereContains(query *gorm.DB, column string, value string) *gorm.DB {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(value)) + "%"
+	return query.Where("LOWER("+column+") LIKE ? ESCAPE '!'", pattern)
+}
+
+// updateVersioned saves every column of model, but only while the stored row
+// still has the version the caller read. The caller bumps the version on the
+// model beforehand; ErrVersionConflict is returned when someone else won.
+func updateVersioned(db *gorm.DB, model interface{}, version int) error {
+	result := db.Model(model).Select("*").Omit(clause.Associations).Where("version = ?", version).Updates(model)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// deleteVersioned deletes model only while the stored row still has the
+// given version.
+func deleteVersioned(db *gorm.DB, model interface{}, version int) error {
+	result := db.Where("version = ?", version).Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// exportBatchSize is the number of rows the Each methods load at a time, so
+// exports never hold a whole table in memory.
+const exportBatchSize = 500
+
+// eachPage loads the rows of query exportBatchSize at a time in the order of
+// the query and calls fn for each. Unlike FindInBatches, which pages by id,
+// it keeps orders such as orderByDisplay.
+func eachPage[T any](query *gorm.DB, fn func(item T) error) error {
+	query = query.Session(&gorm.Session{})
+
+	for offset := 0; ; offset += exportBatchSize {
+		var batch []T
+		err := query.Offset(offset).Limit(exportBatchSize).Find(&batch).Error
+		if err != nil {
+			return err
+		}
+
+		for _, item := range batch {
+			err = fn(item)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position").Order("id")
+}
+
+// orderById keeps preloaded recipe lines in the order they were added.
+func orderById(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// orderPositions maps each id to its 1-based position in ids, failing with
+// mismatch when ids holds duplicates or not exactly count entries.
+func orderPositions(count int, ids []int, mismatch error) (map[int]int, error) {
+	positions := map[int]int{}
+	if len(ids) != count {
+		return positions, mismatch
+	}
+
+	for i, id := range ids {
+		if _, duplicate := positions[id]; duplicate {
+			return positions, mismatch
+		}
+		positions[id] = i + 1
+	}
+
+	return positions, nil
+}
+
+// joinCategoryTree joins category_tree, which gives every category a
+// sort_path: the position and id of each of its ancestors and of itself,
+// zero padded so that sorting the text walks the tree depth first with
+// siblings in position order.
+func joinCategoryTree(db *gorm.DB, on string) *gorm.DB {
+	var segment, tail string
+	switch db.Dialector.Name() {
+	case "sqlite":
+		segment = "printf('%010d.%010d', categories.position, categories.id)"
+		tail = "category_tree.sort_path || '/' || " + segment
+	case "postgres":
+		segment = "LPAD(CAST(categories.position AS TEXT), 10, '0') || '.' || LPAD(CAST(categories.id AS TEXT), 10, '0')"
+		tail = "category_tree.sort_path || '/' || " + segment
+	default:
+		// the column type of a recursive cte comes from the anchor, so widen it
+		segment = "CONCAT(LPAD(categories.position, 10, '0'), '.', LPAD(categories.id, 10, '0'))"
+		tail = "CONCAT(category_tree.sort_path, '/', " + segment + ")"
+		segment = "CAST(" + segment + " AS CHAR(2000))"
+	}
+
+	return db.Joins("LEFT JOIN (WITH RECURSIVE category_tree (id, sort_path) AS (" +
+		"SELECT categories.id, " + segment + " FROM categories WHERE categories.parent_id IS NULL " +
+		"UNION ALL SELECT categories.id, " + tail + " FROM categories JOIN category_tree ON categories.parent_id = category_tree.id" +
+		") SELECT id, sort_path FROM category_tree) AS category_tree ON " + on)
+}
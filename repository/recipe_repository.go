@@ -4,14 +4,19 @@ import (
 	"fmt"
 	"github.com/erp_app/models"
 	"gorm.io/gorm"
+	"time"
 )
 
 type RecipeRepository interface {
 	Create(recipe models.MenuIngredient) (models.MenuIngredient, error)
 	Update(recipe models.MenuIngredient) (models.MenuIngredient, error)
 	Find(id int) (models.MenuIngredient, error)
+	FindByMenuAndIngredient(menuId int, ingredientId int) (models.MenuIngredient, error)
+	AllByIds(ids []int) ([]models.MenuIngredient, error)
+	AllByMenu(menuId int) ([]models.MenuIngredient, error)
 	All() ([]models.MenuIngredient, error)
 	Delete(recipe models.MenuIngredient) error
+	WithTx(tx *gorm.DB) RecipeRepository
 }
 
 type recipeRepository struct {
@@ -24,8 +29,26 @@ func NewRecipeRepository(db *gorm.DB) RecipeRepository {
 	}
 }
 
+func (recipeRepository *recipeRepository) WithTx(tx *gorm.DB) RecipeRepository {
+	return NewRecipeRepository(tx)
+}
+
 func (recipeRepository *recipeRepository) Create(recipe models.MenuIngredient) (models.MenuIngredient, error) {
-	err := recipeRepository.db.Create(&recipe).Error
+	recipe.Version = 1
+
+	err := recipeRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&recipe).Error
+		if err != nil {
+			return err
+		}
+
+		err = recordChange(tx, "recipe", recipe.Id, models.AuditActionCreate)
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, recipe.MenuId)
+	})
 	if err != nil {
 		return recipe, err
 	}
@@ -34,7 +57,22 @@ func (recipeRepository *recipeRepository) Create(recipe models.MenuIngredient) (
 }
 
 func (recipeRepository *recipeRepository) Update(recipe models.MenuIngredient) (models.MenuIngredient, error) {
-	err := recipeRepository.db.Save(&recipe).Error
+	version := recipe.Version
+	recipe.Version = version + 1
+
+	err := recipeRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &recipe, version)
+		if err != nil {
+			return err
+		}
+
+		err = recordChange(tx, "recipe", recipe.Id, models.AuditActionUpdate)
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, recipe.MenuId)
+	})
 	if err != nil {
 		return recipe, err
 	}
@@ -55,6 +93,16 @@ func (recipeRepository *recipeRepository) Find(id int) (models.MenuIngredient, e
 	return recipe, nil
 }
 
+func (recipeRepository *recipeRepository) FindByMenuAndIngredient(menuId int, ingredientId int) (models.MenuIngredient, error) {
+	recipe := models.MenuIngredient{}
+	err := recipeRepository.db.Preload("Ingredient").Where("menu_id = ? AND ingredient_id = ?", menuId, ingredientId).Order("id").First(&recipe).Error
+	if err != nil {
+		return recipe, err
+	}
+
+	return recipe, nil
+}
+
 func (recipeRepository *recipeRepository) All() ([]models.MenuIngredient, error) {
 	var listRecipe []models.MenuIngredient
 
@@ -68,10 +116,56 @@ func (recipeRepository *recipeRepository) All() ([]models.MenuIngredient, error)
 }
 
 func (recipeRepository *recipeRepository) Delete(recipe models.MenuIngredient) error {
-	err := recipeRepository.db.Delete(&recipe).Error
+	err := recipeRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := deleteVersioned(tx, &recipe, recipe.Version)
+		if err != nil {
+			return err
+		}
+
+		err = recordChange(tx, "recipe", recipe.Id, models.AuditActionDelete)
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, recipe.MenuId)
+	})
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// touchMenu bumps the updated_at of the menu owning a recipe line, so clients
+// syncing menus by updated_since also pick up recipe changes.
+func touchMenu(tx *gorm.DB, menuId int) error {
+	return tx.Model(&models.Menu{}).Where("id = ?", menuId).UpdateColumn("updated_at", time.Now()).Error
+}
+
+func (recipeRepository *recipeRepository) AllByIds(ids []int) ([]models.MenuIngredient, error) {
+	var listRecipe []models.MenuIngredient
+
+	if len(ids) == 0 {
+		return listRecipe, nil
+	}
+
+	err := recipeRepository.db.Preload("Ingredient").Where("id IN ?", ids).Find(&listRecipe).Error
+
+	if err != nil {
+		return listRecipe, err
+	}
+
+	return listRecipe, nil
+}
+
+func (recipeRepository *recipeRepository) AllByMenu(menuId int) ([]models.MenuIngredient, error) {
+	var listRecipe []models.MenuIngredient
+
+	err := recipeRepository.db.Preload("Ingredient").Where("menu_id = ?", menuId).Order("id").Find(&listRecipe).Error
+
+	if err != nil {
+		return listRecipe, err
+	}
+
+	return listRecipe, nil
+}
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type RecipeVersionRepository interface {
+	All(menuId int) ([]models.RecipeVersion, error)
+	Find(id int) (models.RecipeVersion, error)
+	FindEffective(menuId int, at time.Time) (models.RecipeVersion, error)
+	FindPrevious(recipeVersion models.RecipeVersion) (models.RecipeVersion, error)
+	CountReleased(menuId int) (int64, error)
+	Due(now time.Time) ([]models.RecipeVersion, error)
+	LockDue(id int, now time.Time) (models.RecipeVersion, error)
+	Create(recipeVersion models.RecipeVersion) (models.RecipeVersion, error)
+	CreateBaseline(recipeVersion models.RecipeVersion) (models.RecipeVersion, error)
+	Update(recipeVersion models.RecipeVersion) (models.RecipeVersion, error)
+	Delete(recipeVersion models.RecipeVersion) error
+	Apply(recipeVersion models.RecipeVersion, now time.Time) (models.RecipeVersion, error)
+	Snapshot(menuId int, note string, now time.Time) (models.RecipeVersion, error)
+	WithTx(tx *gorm.DB) RecipeVersionRepository
+}
+
+type recipeVersionRepository struct {
+	db *gorm.DB
+}
+
+func NewRecipeVersionRepository(db *gorm.DB) RecipeVersionRepository {
+	return &recipeVersionRepository{
+		db: db,
+	}
+}
+
+func (recipeVersionRepository *recipeVersionRepository) WithTx(tx *gorm.DB) RecipeVersionRepository {
+	return NewRecipeVersionRepository(tx)
+}
+
+func (recipeVersionRepository *recipeVersionRepository) preload() *gorm.DB {
+	return recipeVersionRepository.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Lines.Ingredient")
+}
+
+func (recipeVersionRepository *recipeVersionRepository) All(menuId int) ([]models.RecipeVersion, error) {
+	var listRecipeVersion []models.RecipeVersion
+
+	err := recipeVersionRepository.preload().Where("menu_id = ?", menuId).Order("number").Find(&listRecipeVersion).Error
+
+	if err != nil {
+		return listRecipeVersion, err
+	}
+
+	return listRecipeVersion, nil
+}
+
+func (recipeVersionRepository *recipeVersionRepository) Find(id int) (models.RecipeVersion, error) {
+	recipeVersion := models.RecipeVersion{}
+	err := recipeVersionRepository.preload().First(&recipeVersion, id).Error
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+// FindEffective returns the version that was in effect for the menu at the
+// given time: the released version with the latest effective date not after it.
+func (recipeVersionRepository *recipeVersionRepository) FindEffective(menuId int, at time.Time) (models.RecipeVersion, error) {
+	recipeVersion := models.RecipeVersion{}
+	err := recipeVersionRepository.preload().
+		Where("menu_id = ? AND status <> ? AND applied_at IS NOT NULL AND effective_from <= ?", menuId, models.RecipeVersionDraft, at).
+		Order("effective_from DESC").Order("number DESC").
+		First(&recipeVersion).Error
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+func (recipeVersionRepository *recipeVersionRepository) FindPrevious(recipeVersion models.RecipeVersion) (models.RecipeVersion, error) {
+	previous := models.RecipeVersion{}
+	err := recipeVersionRepository.preload().
+		Where("menu_id = ? AND number < ?", recipeVersion.MenuId, recipeVersion.Number).
+		Order("number DESC").
+		First(&previous).Error
+	if err != nil {
+		return previous, err
+	}
+
+	return previous, nil
+}
+
+// CountReleased counts the versions of a menu that were ever activated.
+func (recipeVersionRepository *recipeVersionRepository) CountReleased(menuId int) (int64, error) {
+	var count int64
+	err := recipeVersionRepository.db.Model(&models.RecipeVersion{}).Where("menu_id = ? AND effective_from IS NOT NULL", menuId).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Due lists the active versions whose effective date has passed but whose
+// lines have not been applied yet, oldest first.
+func (recipeVersionRepository *recipeVersionRepository) Due(now time.Time) ([]models.RecipeVersion, error) {
+	var listRecipeVersion []models.RecipeVersion
+
+	err := recipeVersionRepository.preload().
+		Where("status = ? AND applied_at IS NULL AND effective_from <= ?", models.RecipeVersionActive, now).
+		Order("effective_from").Order("number").
+		Find(&listRecipeVersion).Error
+
+	if err != nil {
+		return listRecipeVersion, err
+	}
+
+	return listRecipeVersion, nil
+}
+
+// LockDue locks a due version until the transaction ends. A version another
+// server has locked or has applied in the meantime is not found.
+func (recipeVersionRepository *recipeVersionRepository) LockDue(id int, now time.Time) (models.RecipeVersion, error) {
+	recipeVersion := models.RecipeVersion{}
+	err := recipeVersionRepository.preload().
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id = ? AND status = ? AND applied_at IS NULL AND effective_from <= ?", id, models.RecipeVersionActive, now).
+		First(&recipeVersion).Error
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+// Create numbers the version after the last version of its menu and stores
+// it with its lines.
+func (recipeVersionRepository *recipeVersionRepository) Create(recipeVersion models.RecipeVersion) (models.RecipeVersion, error) {
+	recipeVersion.Version = 1
+
+	err := recipeVersionRepository.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&models.RecipeVersion{}).Where("menu_id = ?", recipeVersion.MenuId).Select("COALESCE(MAX(number), 0)").Scan(&last).Error
+		if err != nil {
+			return err
+		}
+
+		recipeVersion.Number = last + 1
+
+		err = tx.Omit(clause.Associations).Create(&recipeVersion).Error
+		if err != nil {
+			return err
+		}
+
+		return createRecipeVersionLines(tx, &recipeVersion)
+	})
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+// CreateBaseline stores the version as number 0, which is reserved for the
+// recipe a menu had before its first version was activated.
+func (recipeVersionRepository *recipeVersionRepository) CreateBaseline(recipeVersion models.RecipeVersion) (models.RecipeVersion, error) {
+	recipeVersion.Version = 1
+	recipeVersion.Number = 0
+
+	err := recipeVersionRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit(clause.Associations).Create(&recipeVersion).Error
+		if err != nil {
+			return err
+		}
+
+		return createRecipeVersionLines(tx, &recipeVersion)
+	})
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+// Update saves the version and replaces its lines.
+func (recipeVersionRepository *recipeVersionRepository) Update(recipeVersion models.RecipeVersion) (models.RecipeVersion, error) {
+	version := recipeVersion.Version
+	recipeVersion.Version = version + 1
+
+	err := recipeVersionRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &recipeVersion, version)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("recipe_version_id = ?", recipeVersion.Id).Delete(&models.RecipeVersionLine{}).Error
+		if err != nil {
+			return err
+		}
+
+		return createRecipeVersionLines(tx, &recipeVersion)
+	})
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+func (recipeVersionRepository *recipeVersionRepository) Delete(recipeVersion models.RecipeVersion) error {
+	err := recipeVersionRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := deleteVersioned(tx, &recipeVersion, recipeVersion.Version)
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("recipe_version_id = ?", recipeVersion.Id).Delete(&models.RecipeVersionLine{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Apply makes the lines of the version the live recipe of its menu and
+// archives the version it replaces. Recipe lines are updated in place where
+// the ingredient stays, so their ids survive and sync clients see ordinary
+// changes.
+func (recipeVersionRepository *recipeVersionRepository) Apply(recipeVersion models.RecipeVersion, now time.Time) (models.RecipeVersion, error) {
+	err := recipeVersionRepository.db.Transaction(func(tx *gorm.DB) error {
+		var listRecipe []models.MenuIngredient
+		err := tx.Where("menu_id = ?", recipeVersion.MenuId).Order("id").Find(&listRecipe).Error
+		if err != nil {
+			return err
+		}
+
+		live := map[int]models.MenuIngredient{}
+		for _, recipe := range listRecipe {
+			live[recipe.IngredientId] = recipe
+		}
+
+		for _, line := range recipeVersion.Lines {
+			recipe, ok := live[line.IngredientId]
+			delete(live, line.IngredientId)
+
+			if !ok {
+				recipe = models.MenuIngredient{MenuId: recipeVersion.MenuId, IngredientId: line.IngredientId, Qty: line.Qty, Version: 1}
+				err = tx.Omit(clause.Associations).Create(&recipe).Error
+				if err != nil {
+					return err
+				}
+
+				err = recordChange(tx, "recipe", recipe.Id, models.AuditActionCreate)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			if recipe.Qty == line.Qty {
+				continue
+			}
+
+			recipe.Qty = line.Qty
+			recipe.Version++
+			err = updateVersioned(tx, &recipe, recipe.Version-1)
+			if err != nil {
+				return err
+			}
+
+			err = recordChange(tx, "recipe", recipe.Id, models.AuditActionUpdate)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, recipe := range live {
+			err = deleteRecipeLine(tx, recipe)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = archiveReplaced(tx, recipeVersion, now)
+		if err != nil {
+			return err
+		}
+
+		version := recipeVersion.Version
+		recipeVersion.Version = version + 1
+		recipeVersion.Status = models.RecipeVersionActive
+		recipeVersion.AppliedAt = &now
+		err = updateVersioned(tx, &recipeVersion, version)
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, recipeVersion.MenuId)
+	})
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+// Snapshot stores the live recipe of a menu as a new version that is in
+// effect from now. It records recipe lines that were edited directly, so the
+// history keeps ending with the formula the menu really has.
+func (recipeVersionRepository *recipeVersionRepository) Snapshot(menuId int, note string, now time.Time) (models.RecipeVersion, error) {
+	recipeVersion := models.RecipeVersion{
+		MenuId:        menuId,
+		Status:        models.RecipeVersionActive,
+		Note:          note,
+		EffectiveFrom: &now,
+		AppliedAt:     &now,
+	}
+
+	err := recipeVersionRepository.db.Transaction(func(tx *gorm.DB) error {
+		var listRecipe []models.MenuIngredient
+		err := tx.Preload("Ingredient").Where("menu_id = ?", menuId).Order("id").Find(&listRecipe).Error
+		if err != nil {
+			return err
+		}
+
+		for _, recipe := range listRecipe {
+			recipeVersion.Lines = append(recipeVersion.Lines, models.RecipeVersionLine{IngredientId: recipe.IngredientId, Qty: recipe.Qty, Ingredient: recipe.Ingredient})
+		}
+
+		recipeVersion, err = NewRecipeVersionRepository(tx).Create(recipeVersion)
+		if err != nil {
+			return err
+		}
+
+		return archiveReplaced(tx, recipeVersion, now)
+	})
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	return recipeVersion, nil
+}
+
+// archiveReplaced archives the versions of the menu that were in effect
+// before the given one was applied.
+func archiveReplaced(tx *gorm.DB, recipeVersion models.RecipeVersion, now time.Time) error {
+	return tx.Model(&models.RecipeVersion{}).
+		Where("menu_id = ? AND id <> ? AND status = ? AND applied_at IS NOT NULL", recipeVersion.MenuId, recipeVersion.Id, models.RecipeVersionActive).
+		Updates(map[string]interface{}{"status": models.RecipeVersionArchived, "version": gorm.Expr("version + 1"), "updated_at": now}).Error
+}
+
+func createRecipeVersionLines(tx *gorm.DB, recipeVersion *models.RecipeVersion) error {
+	for i := range recipeVersion.Lines {
+		recipeVersion.Lines[i].Id = 0
+		recipeVersion.Lines[i].RecipeVersionId = recipeVersion.Id
+	}
+
+	if len(recipeVersion.Lines) == 0 {
+		return nil
+	}
+
+	return tx.Omit(clause.Associations).Create(&recipeVersion.Lines).Error
+}
+
+func deleteRecipeLine(tx *gorm.DB, recipe models.MenuIngredient) error {
+	err := tx.Delete(&recipe).Error
+	if err != nil {
+		return err
+	}
+
+	return recordChange(tx, "recipe", recipe.Id, models.AuditActionDelete)
+}
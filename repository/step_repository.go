@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"errors"
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrStepOrderMismatch = errors.New("step_ids must list every step of the menu exactly once")
+
+type StepRepository interface {
+	All(menuId int) ([]models.MenuStep, error)
+	Find(id int) (models.MenuStep, error)
+	Create(step models.MenuStep) (models.MenuStep, error)
+	Update(step models.MenuStep) (models.MenuStep, error)
+	Delete(step models.MenuStep) error
+	Reorder(menuId int, stepIds []int) ([]models.MenuStep, error)
+	WithTx(tx *gorm.DB) StepRepository
+}
+
+type stepRepository struct {
+	db *gorm.DB
+}
+
+func NewStepRepository(db *gorm.DB) StepRepository {
+	return &stepRepository{
+		db: db,
+	}
+}
+
+func (stepRepository *stepRepository) WithTx(tx *gorm.DB) StepRepository {
+	return NewStepRepository(tx)
+}
+
+func (stepRepository *stepRepository) All(menuId int) ([]models.MenuStep, error) {
+	var listStep []models.MenuStep
+
+	err := orderByPosition(stepRepository.db.Where("menu_id = ?", menuId)).Find(&listStep).Error
+
+	if err != nil {
+		return listStep, err
+	}
+
+	return listStep, nil
+}
+
+func (stepRepository *stepRepository) Find(id int) (models.MenuStep, error) {
+	step := models.MenuStep{}
+	err := stepRepository.db.First(&step, id).Error
+	if err != nil {
+		return step, err
+	}
+
+	return step, nil
+}
+
+// Create appends the step after the last step of its menu. Writes that move
+// positions lock the menu first, so two steps never get the same position.
+func (stepRepository *stepRepository) Create(step models.MenuStep) (models.MenuStep, error) {
+	step.Version = 1
+
+	err := stepRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := lockMenu(tx, step.MenuId)
+		if err != nil {
+			return err
+		}
+
+		var last int
+		err = tx.Model(&models.MenuStep{}).Where("menu_id = ?", step.MenuId).Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+		if err != nil {
+			return err
+		}
+
+		step.Position = last + 1
+
+		err = tx.Create(&step).Error
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, step.MenuId)
+	})
+	if err != nil {
+		return step, err
+	}
+
+	return step, nil
+}
+
+func (stepRepository *stepRepository) Update(step models.MenuStep) (models.MenuStep, error) {
+	version := step.Version
+	step.Version = version + 1
+
+	err := stepRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := updateVersioned(tx, &step, version)
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, step.MenuId)
+	})
+	if err != nil {
+		return step, err
+	}
+
+	return step, nil
+}
+
+// Delete removes the step and closes the gap it leaves in the positions.
+func (stepRepository *stepRepository) Delete(step models.MenuStep) error {
+	err := stepRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := lockMenu(tx, step.MenuId)
+		if err != nil {
+			return err
+		}
+
+		err = deleteVersioned(tx, &step, step.Version)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.MenuStep{}).Where("menu_id = ? AND position > ?", step.MenuId, step.Position).UpdateColumn("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+
+		return touchMenu(tx, step.MenuId)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reorder gives the steps of a menu the order of stepIds, which must name
+// every step of the menu exactly once.
+func (stepRepository *stepRepository) Reorder(menuId int, stepIds []int) ([]models.MenuStep, error) {
+	var listStep []models.MenuStep
+
+	err := stepRepository.db.Transaction(func(tx *gorm.DB) error {
+		err := lockMenu(tx, menuId)
+		if err != nil {
+			return err
+		}
+
+		current, err := NewStepRepository(tx).All(menuId)
+		if err != nil {
+			return err
+		}
+
+		positions, err := orderPositions(len(current), stepIds, ErrStepOrderMismatch)
+		if err != nil {
+			return err
+		}
+
+		for _, step := range current {
+			position, ok := positions[step.Id]
+			if !ok {
+				return ErrStepOrderMismatch
+			}
+
+			if position == step.Position {
+				continue
+			}
+
+			err = tx.Model(&step).Updates(map[string]interface{}{"position": position, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = touchMenu(tx, menuId)
+		if err != nil {
+			return err
+		}
+
+		listStep, err = NewStepRepository(tx).All(menuId)
+		return err
+	})
+	if err != nil {
+		return listStep, err
+	}
+
+	return listStep, nil
+}
+
+// lockMenu holds the row of the menu until the transaction ends, so the steps
+// of a menu are positioned by one writer at a time.
+func lockMenu(tx *gorm.DB, menuId int) error {
+	menu := models.Menu{}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&menu, menuId).Error
+}
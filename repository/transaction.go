@@ -0,0 +1,69 @@
+package repository
+
+import "gorm.io/gorm"
+
+// TransactionManager runs a function in a database transaction. Services pass
+// the transaction to the WithTx method of the repositories they use.
+type TransactionManager interface {
+	Transaction(fn func(tx *gorm.DB) error) error
+	// AfterCommit runs fn once the outermost transaction commits, or right away
+	// when there is no transaction. Use it for side effects outside the
+	// database that must not happen if the transaction rolls back.
+	AfterCommit(fn func())
+}
+
+type transactionManager struct {
+	db *gorm.DB
+}
+
+func NewTransactionManager(db *gorm.DB) TransactionManager {
+	return &transactionManager{
+		db: db,
+	}
+}
+
+const afterCommitKey = "transaction_manager:after_commit"
+
+type afterCommitHooks struct {
+	hooks []func()
+}
+
+func (transactionManager *transactionManager) Transaction(fn func(tx *gorm.DB) error) error {
+	parent, nested := transactionManager.afterCommitHooks()
+	hooks := &afterCommitHooks{}
+	err := transactionManager.db.Transaction(func(tx *gorm.DB) error {
+		// Set returns an instance that keeps its conditions; the session makes
+		// every query start clean again.
+		return fn(tx.Set(afterCommitKey, hooks).Session(&gorm.Session{}))
+	})
+	if err != nil {
+		return err
+	}
+	// A nested transaction is only a savepoint, so its hooks wait for the
+	// outer transaction.
+	if nested {
+		parent.hooks = append(parent.hooks, hooks.hooks...)
+		return nil
+	}
+	for _, hook := range hooks.hooks {
+		hook()
+	}
+	return nil
+}
+
+func (transactionManager *transactionManager) AfterCommit(fn func()) {
+	if hooks, ok := transactionManager.afterCommitHooks(); ok {
+		hooks.hooks = append(hooks.hooks, fn)
+		return
+	}
+	fn()
+}
+
+func (transactionManager *transactionManager) afterCommitHooks() (*afterCommitHooks, bool) {
+	value, ok := transactionManager.db.Get(afterCommitKey)
+	if !ok {
+		return nil, false
+	}
+	hooks, ok := value.(*afterCommitHooks)
+	return hooks, ok
+}
@@ -0,0 +1,9 @@
+package request
+
+type GetAllAuditRequest struct {
+	Entity string `query:"entity" validate:"omitempty,oneof=category ingredient ingredient_group menu recipe menu_step recipe_version"`
+	Id     int    `query:"id" validate:"omitempty,gte=1"`
+	From   string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To     string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	Format string `query:"format" validate:"omitempty,oneof=json csv xlsx ndjson"`
+}
@@ -0,0 +1,59 @@
+package request
+
+// BulkRequest carries the items of a bulk endpoint. Items are validated one
+// by one by the controller, which stores the failures in Errors by index.
+type BulkRequest[T any] struct {
+	BestEffort bool             `json:"best_effort"`
+	Items      []T              `json:"items" validate:"required,min=1,max=1000"`
+	Actor      string           `json:"-"`
+	Errors     map[int][]string `json:"-"`
+}
+
+type BulkUpdateCategoryItem struct {
+	Id      int    `json:"id" validate:"required,gte=1"`
+	Name    string `json:"name" validate:"required"`
+	Version int    `json:"version" validate:"required,gte=1"`
+}
+
+type BulkUpdateIngredientItem struct {
+	Id           int      `json:"id" validate:"required,gte=1"`
+	Name         string   `json:"name" validate:"required"`
+	YieldPercent *float64 `json:"yield_percent" validate:"omitempty,gt=0,lte=100"`
+	Version      int      `json:"version" validate:"required,gte=1"`
+}
+
+type BulkUpdateMenuItem struct {
+	Id         int     `json:"id" validate:"required,gte=1"`
+	Name       string  `json:"name" validate:"required"`
+	CategoryId int     `json:"category_id" validate:"required,gte=1"`
+	YieldQty   *string `json:"yield_qty" validate:"omitempty,max=255"`
+	Portions   *int    `json:"portions" validate:"omitempty,gte=1"`
+	Version    int     `json:"version" validate:"required,gte=1"`
+}
+
+type BulkCreateRecipeItem struct {
+	MenuId       int      `json:"menu_id" validate:"required,gte=1"`
+	IngredientId int      `json:"ingredient_id" validate:"required,gte=1"`
+	Qty          string   `json:"qty" validate:"required"`
+	YieldPercent *float64 `json:"yield_percent" validate:"omitempty,gt=0,lte=100"`
+}
+
+type BulkUpdateRecipeItem struct {
+	Id           int      `json:"id" validate:"required,gte=1"`
+	MenuId       int      `json:"menu_id" validate:"required,gte=1"`
+	IngredientId int      `json:"ingredient_id" validate:"required,gte=1"`
+	Qty          string   `json:"qty" validate:"required"`
+	YieldPercent *float64 `json:"yield_percent" validate:"omitempty,gte=0,lte=100"`
+	Version      int      `json:"version" validate:"required,gte=1"`
+}
+
+type BulkDeleteItem struct {
+	Id      int `json:"id" validate:"required,gte=1"`
+	Version int `json:"version" validate:"required,gte=1"`
+}
+
+type BulkDeleteRecipeItem struct {
+	Id      int `json:"id" validate:"required,gte=1"`
+	MenuId  int `json:"menu_id" validate:"required,gte=1"`
+	Version int `json:"version" validate:"required,gte=1"`
+}
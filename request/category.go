@@ -1,12 +1,21 @@
 package request
 
+import "github.com/erp_app/helper"
+
 type CreateRequestCategory struct {
-	Name string `json:"name" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	ParentId *int   `json:"parent_id" validate:"omitempty,gte=1"`
+	Actor    string `json:"-"`
 }
 
+// UpdateRequestCategory keeps the parent when it is not sent; a parent_id of
+// 0 moves the category to the top level.
 type UpdateRequestCategory struct {
-	Name string `json:"name" validate:"required"`
-	Id   int    `param:"id" validate:"required"`
+	Name     string          `json:"name" validate:"required"`
+	Id       int             `param:"id" validate:"required"`
+	ParentId *int            `json:"parent_id" validate:"omitempty,gte=0"`
+	Actor    string          `json:"-"`
+	Version  helper.Versions `json:"-"`
 }
 
 type GetDetailRequestCategory struct {
@@ -14,9 +23,21 @@ type GetDetailRequestCategory struct {
 }
 
 type GetAllRequestCategory struct {
-	Name string `query:"name"`
+	Name         string `query:"name"`
+	UpdatedSince string `query:"updated_since" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Format       string `query:"format" validate:"omitempty,oneof=json csv xlsx ndjson"`
 }
 
 type DeleteRequestCategory struct {
-	Id int `param:"id" validate:"required"`
+	Id      int             `param:"id" validate:"required"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+// ReorderCategoryRequest orders the categories under ParentId, or the top
+// level categories when it is not sent.
+type ReorderCategoryRequest struct {
+	ParentId    *int   `json:"parent_id" validate:"omitempty,gte=1"`
+	CategoryIds []int  `json:"category_ids" validate:"required,min=1,dive,gte=1"`
+	Actor       string `json:"-"`
 }
@@ -0,0 +1,21 @@
+package request
+
+import (
+	"github.com/erp_app/helper"
+	"io"
+)
+
+// UploadImageRequest replaces the image of a menu or category with Image, the
+// "image" field of a multipart form.
+type UploadImageRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	Image   io.Reader       `json:"-"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+type DeleteImageRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
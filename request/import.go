@@ -0,0 +1,11 @@
+package request
+
+import "io"
+
+type ImportRequest struct {
+	Entity string    `query:"entity" validate:"omitempty,oneof=category ingredient menu recipe"`
+	Format string    `query:"format" validate:"omitempty,oneof=csv xlsx"`
+	DryRun bool      `query:"dry_run"`
+	Actor  string    `json:"-"`
+	File   io.Reader `json:"-"`
+}
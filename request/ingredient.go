@@ -1,12 +1,44 @@
 package request
 
+import "github.com/erp_app/helper"
+
 type CreateRequestIngredient struct {
-	Name string `json:"name" validate:"required"`
+	Name              string   `json:"name" validate:"required"`
+	IngredientGroupId *int     `json:"ingredient_group_id" validate:"omitempty,gte=1"`
+	YieldPercent      float64  `json:"yield_percent" validate:"omitempty,gt=0,lte=100"`
+	Allergens         []string `json:"allergens" validate:"omitempty,dive,allergen"`
+	Vegan             bool     `json:"vegan"`
+	Halal             bool     `json:"halal"`
+	GlutenFree        bool     `json:"gluten_free"`
+	Calories          *float64 `json:"calories" validate:"omitempty,gte=0"`
+	Protein           *float64 `json:"protein" validate:"omitempty,gte=0"`
+	Fat               *float64 `json:"fat" validate:"omitempty,gte=0"`
+	Carbs             *float64 `json:"carbs" validate:"omitempty,gte=0"`
+	Sodium            *float64 `json:"sodium" validate:"omitempty,gte=0"`
+	Actor             string   `json:"-"`
 }
 
+// UpdateRequestIngredient keeps the group, yield percent, allergens, dietary
+// flags and nutrients that are not sent. An ingredient_group_id of 0 takes
+// the ingredient out of its group, and the nutrients listed in
+// clear_nutrients are emptied before the sent ones are stored.
 type UpdateRequestIngredient struct {
-	Name string `json:"name" validate:"required"`
-	Id   int    `param:"id" validate:"required"`
+	Name              string          `json:"name" validate:"required"`
+	Id                int             `param:"id" validate:"required"`
+	IngredientGroupId *int            `json:"ingredient_group_id" validate:"omitempty,gte=0"`
+	YieldPercent      *float64        `json:"yield_percent" validate:"omitempty,gt=0,lte=100"`
+	Allergens         *[]string       `json:"allergens" validate:"omitempty,dive,allergen"`
+	Vegan             *bool           `json:"vegan"`
+	Halal             *bool           `json:"halal"`
+	GlutenFree        *bool           `json:"gluten_free"`
+	Calories          *float64        `json:"calories" validate:"omitempty,gte=0"`
+	Protein           *float64        `json:"protein" validate:"omitempty,gte=0"`
+	Fat               *float64        `json:"fat" validate:"omitempty,gte=0"`
+	Carbs             *float64        `json:"carbs" validate:"omitempty,gte=0"`
+	Sodium            *float64        `json:"sodium" validate:"omitempty,gte=0"`
+	ClearNutrients    []string        `json:"clear_nutrients" validate:"omitempty,dive,oneof=calories protein fat carbs sodium"`
+	Actor             string          `json:"-"`
+	Version           helper.Versions `json:"-"`
 }
 
 type GetDetailRequestIngredient struct {
@@ -14,9 +46,14 @@ type GetDetailRequestIngredient struct {
 }
 
 type GetAllRequestIngredient struct {
-	Name string `query:"name"`
+	Name              string `query:"name"`
+	IngredientGroupId int    `query:"ingredient_group_id" validate:"omitempty,gte=1"`
+	UpdatedSince      string `query:"updated_since" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Format            string `query:"format" validate:"omitempty,oneof=json csv xlsx ndjson"`
 }
 
 type DeleteRequestIngredient struct {
-	Id int `param:"id" validate:"required"`
+	Id      int             `param:"id" validate:"required"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
 }
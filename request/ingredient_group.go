@@ -0,0 +1,37 @@
+package request
+
+import "github.com/erp_app/helper"
+
+type CreateIngredientGroupRequest struct {
+	Name    string   `json:"name" validate:"required"`
+	Storage string   `json:"storage" validate:"omitempty,oneof=ambient chilled frozen"`
+	MinTemp *float64 `json:"min_temp"`
+	MaxTemp *float64 `json:"max_temp"`
+	Actor   string   `json:"-"`
+}
+
+// UpdateIngredientGroupRequest replaces the storage conditions; temperatures
+// that are not sent are cleared.
+type UpdateIngredientGroupRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	Name    string          `json:"name" validate:"required"`
+	Storage string          `json:"storage" validate:"required,oneof=ambient chilled frozen"`
+	MinTemp *float64        `json:"min_temp"`
+	MaxTemp *float64        `json:"max_temp"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+type GetIngredientGroupRequest struct {
+	Id int `param:"id" validate:"required"`
+}
+
+type GetAllIngredientGroupRequest struct {
+	Name string `query:"name"`
+}
+
+type DeleteIngredientGroupRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
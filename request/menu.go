@@ -1,14 +1,53 @@
 package request
 
+import "github.com/erp_app/helper"
+
 type CreateMenuRequest struct {
 	Name       string `json:"name" validate:"required"`
 	CategoryId int    `json:"category_id" validate:"required,gte=1"`
+	YieldQty   string `json:"yield_qty" validate:"max=255"`
+	Portions   int    `json:"portions" validate:"omitempty,gte=1"`
+	Actor      string `json:"-"`
 }
 
+// UpdateMenuRequest leaves the yield and portions unchanged when they are
+// not sent.
 type UpdateMenuRequest struct {
-	Id         int    `param:"id" validate:"required"`
-	Name       string `json:"name" validate:"required"`
+	Id         int             `param:"id" validate:"required"`
+	Name       string          `json:"name" validate:"required"`
+	CategoryId int             `json:"category_id" validate:"required,gte=1"`
+	YieldQty   *string         `json:"yield_qty" validate:"omitempty,max=255"`
+	Portions   *int            `json:"portions" validate:"omitempty,gte=1"`
+	Actor      string          `json:"-"`
+	Version    helper.Versions `json:"-"`
+}
+
+type ScaleMenuRequest struct {
+	Id       int `param:"id" validate:"required"`
+	Portions int `query:"portions" validate:"required,gte=1,lte=100000"`
+}
+
+// CloneMenuRequest copies a menu under a new name. The copy stays in the
+// category of the original unless one is given, is scaled to Portions when
+// set, and uses the To ingredient wherever the original uses From.
+type CloneMenuRequest struct {
+	Id         int             `param:"id" validate:"required"`
+	Name       string          `json:"name" validate:"required"`
+	CategoryId int             `json:"category_id" validate:"omitempty,gte=1"`
+	Portions   int             `json:"portions" validate:"omitempty,gte=1,lte=100000"`
+	Swaps      []CloneMenuSwap `json:"swaps" validate:"dive"`
+	Actor      string          `json:"-"`
+}
+
+type CloneMenuSwap struct {
+	From int `json:"from" validate:"required,gte=1"`
+	To   int `json:"to" validate:"required,gte=1"`
+}
+
+type ReorderMenuRequest struct {
 	CategoryId int    `json:"category_id" validate:"required,gte=1"`
+	MenuIds    []int  `json:"menu_ids" validate:"required,min=1,dive,gte=1"`
+	Actor      string `json:"-"`
 }
 
 type GetMenuRequest struct {
@@ -16,9 +55,20 @@ type GetMenuRequest struct {
 }
 
 type GetAllMenuRequest struct {
-	Name string `query:"name"`
+	Name         string `query:"name"`
+	UpdatedSince string `query:"updated_since" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Format       string `query:"format" validate:"omitempty,oneof=json csv xlsx ndjson"`
+	// ExcludeAllergens is a comma separated list of allergens the menus must
+	// not contain
+	ExcludeAllergens string `query:"exclude_allergens"`
+	CategoryId       int    `query:"category_id" validate:"omitempty,gte=1"`
+	// IncludeDescendants also lists the menus of the categories below
+	// CategoryId
+	IncludeDescendants bool `query:"include_descendants"`
 }
 
 type DeleteMenuRequest struct {
-	Id int `param:"id" validate:"required"`
+	Id      int             `param:"id" validate:"required"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
 }
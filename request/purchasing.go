@@ -0,0 +1,10 @@
+package request
+
+type PurchasingItemRequest struct {
+	MenuId   int `json:"menu_id" validate:"required,gte=1"`
+	Portions int `json:"portions" validate:"required,gte=1,lte=100000"`
+}
+
+type PurchasingRequest struct {
+	Items []PurchasingItemRequest `json:"items" validate:"required,min=1,max=500,dive"`
+}
@@ -1,19 +1,46 @@
 package request
 
+import "github.com/erp_app/helper"
+
 type CreateRecipeRequest struct {
-	IngredientId int    `json:"ingredient_id" validate:"required,gte=1"`
-	MenuId       int    `param:"menu_id" validate:"required,gte=1"`
-	Qty          string `json:"qty" validate:"required"`
+	IngredientId int      `json:"ingredient_id" validate:"required,gte=1"`
+	MenuId       int      `param:"menu_id" validate:"required,gte=1"`
+	Qty          string   `json:"qty" validate:"required"`
+	YieldPercent *float64 `json:"yield_percent" validate:"omitempty,gt=0,lte=100"`
+	Actor        string   `json:"-"`
 }
 
+// UpdateRecipeRequest keeps the yield override when yield_percent is not
+// sent; 0 removes the override so the ingredient yield applies again.
 type UpdateRecipeRequest struct {
-	Id           int    `param:"id" validate:"required"`
-	IngredientId int    `json:"ingredient_id" validate:"required,gte=1"`
-	MenuId       int    `param:"menu_id" validate:"required,gte=1"`
-	Qty          string `json:"qty" validate:"required"`
+	Id           int             `param:"id" validate:"required"`
+	IngredientId int             `json:"ingredient_id" validate:"required,gte=1"`
+	MenuId       int             `param:"menu_id" validate:"required,gte=1"`
+	Qty          string          `json:"qty" validate:"required"`
+	YieldPercent *float64        `json:"yield_percent" validate:"omitempty,gte=0,lte=100"`
+	Actor        string          `json:"-"`
+	Version      helper.Versions `json:"-"`
 }
 
 type DeleteRecipeRequest struct {
-	Id     int `param:"id" validate:"required"`
-	MenuId int `param:"id" validate:"required"`
+	Id      int             `param:"id" validate:"required"`
+	MenuId  int             `param:"menu_id" validate:"required,gte=1"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+type RecipeLineRequest struct {
+	IngredientId int      `json:"ingredient_id" validate:"required,gte=1"`
+	Qty          string   `json:"qty" validate:"required"`
+	YieldPercent *float64 `json:"yield_percent" validate:"omitempty,gt=0,lte=100"`
+}
+
+// ReplaceRecipeRequest is the complete recipe a menu should end up with. An
+// empty list removes every line; a missing list is rejected. Version is the
+// version of the menu the recipe was read from.
+type ReplaceRecipeRequest struct {
+	MenuId  int                 `param:"menu_id" validate:"required,gte=1"`
+	Lines   []RecipeLineRequest `json:"lines" validate:"required,dive"`
+	Actor   string              `json:"-"`
+	Version helper.Versions     `json:"-"`
 }
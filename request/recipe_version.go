@@ -0,0 +1,70 @@
+package request
+
+import "github.com/erp_app/helper"
+
+type RecipeVersionLineRequest struct {
+	IngredientId int    `json:"ingredient_id" validate:"required,gte=1"`
+	Qty          string `json:"qty" validate:"required,max=255"`
+}
+
+type GetAllRecipeVersionRequest struct {
+	MenuId int `param:"menu_id" validate:"required,gte=1"`
+}
+
+type GetRecipeVersionRequest struct {
+	Id     int `param:"id" validate:"required"`
+	MenuId int `param:"menu_id" validate:"required,gte=1"`
+}
+
+type GetEffectiveRecipeVersionRequest struct {
+	MenuId int    `param:"menu_id" validate:"required,gte=1"`
+	At     string `query:"at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// CreateRecipeVersionRequest stages a draft. Without lines the draft starts
+// as a copy of the current recipe of the menu.
+type CreateRecipeVersionRequest struct {
+	MenuId int                        `param:"menu_id" validate:"required,gte=1"`
+	Note   string                     `json:"note" validate:"max=255"`
+	Lines  []RecipeVersionLineRequest `json:"lines" validate:"omitempty,dive"`
+	Actor  string                     `json:"-"`
+}
+
+type UpdateRecipeVersionRequest struct {
+	Id      int                        `param:"id" validate:"required"`
+	MenuId  int                        `param:"menu_id" validate:"required,gte=1"`
+	Note    string                     `json:"note" validate:"max=255"`
+	Lines   []RecipeVersionLineRequest `json:"lines" validate:"required,dive"`
+	Actor   string                     `json:"-"`
+	Version helper.Versions            `json:"-"`
+}
+
+type DeleteRecipeVersionRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	MenuId  int             `param:"menu_id" validate:"required,gte=1"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+// ActivateRecipeVersionRequest releases a draft. Without an effective date the
+// version takes effect immediately.
+type ActivateRecipeVersionRequest struct {
+	Id            int             `param:"id" validate:"required"`
+	MenuId        int             `param:"menu_id" validate:"required,gte=1"`
+	EffectiveFrom string          `json:"effective_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Actor         string          `json:"-"`
+	Version       helper.Versions `json:"-"`
+}
+
+type ArchiveRecipeVersionRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	MenuId  int             `param:"menu_id" validate:"required,gte=1"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+type DiffRecipeVersionRequest struct {
+	Id      int `param:"id" validate:"required"`
+	MenuId  int `param:"menu_id" validate:"required,gte=1"`
+	Against int `query:"against" validate:"omitempty,gte=1"`
+}
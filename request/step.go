@@ -0,0 +1,40 @@
+package request
+
+import "github.com/erp_app/helper"
+
+type GetAllStepRequest struct {
+	MenuId int `param:"menu_id" validate:"required,gte=1"`
+}
+
+type CreateStepRequest struct {
+	MenuId          int    `param:"menu_id" validate:"required,gte=1"`
+	Text            string `json:"text" validate:"required"`
+	DurationMinutes int    `json:"duration_minutes" validate:"gte=0"`
+	Station         string `json:"station" validate:"max=100"`
+	ImageUrl        string `json:"image_url" validate:"omitempty,url,max=2048"`
+	Actor           string `json:"-"`
+}
+
+type UpdateStepRequest struct {
+	Id              int             `param:"id" validate:"required"`
+	MenuId          int             `param:"menu_id" validate:"required,gte=1"`
+	Text            string          `json:"text" validate:"required"`
+	DurationMinutes int             `json:"duration_minutes" validate:"gte=0"`
+	Station         string          `json:"station" validate:"max=100"`
+	ImageUrl        string          `json:"image_url" validate:"omitempty,url,max=2048"`
+	Actor           string          `json:"-"`
+	Version         helper.Versions `json:"-"`
+}
+
+type DeleteStepRequest struct {
+	Id      int             `param:"id" validate:"required"`
+	MenuId  int             `param:"menu_id" validate:"required,gte=1"`
+	Actor   string          `json:"-"`
+	Version helper.Versions `json:"-"`
+}
+
+type ReorderStepRequest struct {
+	MenuId  int    `param:"menu_id" validate:"required,gte=1"`
+	StepIds []int  `json:"step_ids" validate:"required,min=1,dive,gte=1"`
+	Actor   string `json:"-"`
+}
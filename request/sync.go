@@ -0,0 +1,6 @@
+package request
+
+type SyncRequest struct {
+	Since string `query:"since"`
+	Limit int    `query:"limit" validate:"omitempty,gte=1,lte=5000"`
+}
@@ -13,3 +13,8 @@ func NewApiResponse(status, message string, data interface{}) apiResponse {
 		Data:    data,
 	}
 }
+
+type DuplicateResponse struct {
+	Error      string `json:"error"`
+	ExistingId int    `json:"existing_id"`
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditResponse struct {
+	Id        int             `json:"id"`
+	Actor     string          `json:"actor"`
+	Entity    string          `json:"entity"`
+	EntityId  int             `json:"entity_id"`
+	Action    string          `json:"action"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package response
+
+const (
+	BulkStatusOk         = "ok"
+	BulkStatusError      = "error"
+	BulkStatusSkipped    = "skipped"
+	BulkStatusRolledBack = "rolled_back"
+)
+
+type BulkResponse struct {
+	BestEffort bool               `json:"best_effort"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	Items      []BulkItemResponse `json:"items"`
+}
+
+type BulkItemResponse struct {
+	Index  int         `json:"index"`
+	Status string      `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Errors []string    `json:"errors,omitempty"`
+}
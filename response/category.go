@@ -1,6 +1,32 @@
 package response
 
+import "time"
+
 type CategoryResponse struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentId *int   `json:"parent_id"`
+	Position int    `json:"position"`
+	// ImageUrl and ThumbnailUrl are empty while the category has no image
+	ImageUrl     string `json:"image_url"`
+	ThumbnailUrl string `json:"thumbnail_url"`
+	// Breadcrumb is the path from the top level category down to this one,
+	// filled on the category detail only
+	Breadcrumb []CategoryCrumbResponse `json:"breadcrumb,omitempty"`
+	Version    int                     `json:"version"`
+	CreatedAt  time.Time               `json:"created_at"`
+	UpdatedAt  time.Time               `json:"updated_at"`
+}
+
+type CategoryCrumbResponse struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
 }
+
+type CategoryTreeResponse struct {
+	Id       int                    `json:"id"`
+	Name     string                 `json:"name"`
+	ParentId *int                   `json:"parent_id"`
+	Position int                    `json:"position"`
+	Children []CategoryTreeResponse `json:"children"`
+}
@@ -0,0 +1,25 @@
+package response
+
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionReject    = "reject"
+)
+
+type ImportResponse struct {
+	DryRun    bool                `json:"dry_run"`
+	Created   int                 `json:"created"`
+	Updated   int                 `json:"updated"`
+	Unchanged int                 `json:"unchanged"`
+	Rejected  int                 `json:"rejected"`
+	Rows      []ImportRowResponse `json:"rows"`
+}
+
+type ImportRowResponse struct {
+	Entity string   `json:"entity"`
+	Row    int      `json:"row"`
+	Action string   `json:"action"`
+	Id     int      `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
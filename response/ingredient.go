@@ -1,6 +1,23 @@
 package response
 
+import "time"
+
 type IngredientResponse struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
+	Id                int                      `json:"id"`
+	Name              string                   `json:"name"`
+	IngredientGroupId *int                     `json:"ingredient_group_id"`
+	IngredientGroup   *IngredientGroupResponse `json:"ingredient_group"`
+	YieldPercent      float64                  `json:"yield_percent"`
+	Allergens         []string                 `json:"allergens"`
+	Vegan             bool                     `json:"vegan"`
+	Halal             bool                     `json:"halal"`
+	GlutenFree        bool                     `json:"gluten_free"`
+	Calories          *float64                 `json:"calories"`
+	Protein           *float64                 `json:"protein"`
+	Fat               *float64                 `json:"fat"`
+	Carbs             *float64                 `json:"carbs"`
+	Sodium            *float64                 `json:"sodium"`
+	Version           int                      `json:"version"`
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         time.Time                `json:"updated_at"`
 }
@@ -0,0 +1,28 @@
+package response
+
+import "time"
+
+type IngredientGroupResponse struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Storage   string    `json:"storage"`
+	MinTemp   *float64  `json:"min_temp"`
+	MaxTemp   *float64  `json:"max_temp"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CountSheetResponse is one group of a stock count sheet. Ingredients without
+// a group are listed under a group without id.
+type CountSheetResponse struct {
+	Id          *int                     `json:"id"`
+	Name        string                   `json:"name"`
+	Storage     string                   `json:"storage"`
+	Ingredients []CountSheetLineResponse `json:"ingredients"`
+}
+
+type CountSheetLineResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
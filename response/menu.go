@@ -1,15 +1,81 @@
 package response
 
+import "time"
+
 type MenuResponse struct {
-	Id          int              `json:"id"`
-	Name        string           `json:"name"`
-	CategoryId  int              `json:"category_id"`
-	Category    CategoryResponse `json:"category"`
-	Ingredients []RecipeResponse `json:"ingredients"`
+	Id         int              `json:"id"`
+	Name       string           `json:"name"`
+	CategoryId int              `json:"category_id"`
+	Category   CategoryResponse `json:"category"`
+	Position   int              `json:"position"`
+	YieldQty   string           `json:"yield_qty"`
+	Portions   int              `json:"portions"`
+	// ImageUrl and ThumbnailUrl are empty while the menu has no image
+	ImageUrl     string `json:"image_url"`
+	ThumbnailUrl string `json:"thumbnail_url"`
+	// Allergens and the dietary flags are rolled up from the ingredients of
+	// the recipe: a menu is vegan only when every ingredient is.
+	Allergens   []string          `json:"allergens"`
+	Vegan       bool              `json:"vegan"`
+	Halal       bool              `json:"halal"`
+	GlutenFree  bool              `json:"gluten_free"`
+	Nutrition   NutritionResponse `json:"nutrition"`
+	Ingredients []RecipeResponse  `json:"ingredients"`
+	Steps       []StepResponse    `json:"steps,omitempty"`
+	Version     int               `json:"version"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// NutritionResponse is the nutrition of one portion. It is incomplete when
+// some ingredients have no nutrients or a quantity that cannot be weighed;
+// those are listed in Missing and left out of the sums.
+type NutritionResponse struct {
+	Calories float64  `json:"calories"`
+	Protein  float64  `json:"protein"`
+	Fat      float64  `json:"fat"`
+	Carbs    float64  `json:"carbs"`
+	Sodium   float64  `json:"sodium"`
+	Complete bool     `json:"complete"`
+	Missing  []string `json:"missing"`
 }
 
 type RecipeResponse struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
 	Qty  string `json:"qty"`
+	// YieldPercent is the yield used for the line, YieldOverride the one set
+	// on the line itself
+	YieldPercent  float64   `json:"yield_percent"`
+	YieldOverride *float64  `json:"yield_override"`
+	RecipeId      int       `json:"recipe_id"`
+	MenuId        int       `json:"menu_id"`
+	Version       int       `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ScaledMenuResponse is the recipe of a menu scaled to a number of portions.
+// Lines whose quantity could not be read are returned unscaled.
+type ScaledMenuResponse struct {
+	Id             int                    `json:"id"`
+	Name           string                 `json:"name"`
+	Portions       int                    `json:"portions"`
+	YieldQty       string                 `json:"yield_qty"`
+	TargetPortions int                    `json:"target_portions"`
+	Factor         float64                `json:"factor"`
+	ScaledYield    string                 `json:"scaled_yield_qty"`
+	Ingredients    []ScaledRecipeResponse `json:"ingredients"`
+}
+
+type ScaledRecipeResponse struct {
+	Id           int     `json:"id"`
+	Name         string  `json:"name"`
+	Qty          string  `json:"qty"`
+	Scaled       bool    `json:"scaled"`
+	ScaledQty    string  `json:"scaled_qty"`
+	Amount       float64 `json:"amount,omitempty"`
+	Unit         string  `json:"unit,omitempty"`
+	YieldPercent float64 `json:"yield_percent"`
+	PurchaseQty  string  `json:"purchase_qty"`
 }
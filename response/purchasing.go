@@ -0,0 +1,32 @@
+package response
+
+// PurchasingResponse lists what to buy for a production plan, ordered by
+// ingredient group so each supplier's part of the list stays together;
+// ingredients without a group come last. An ingredient used in units that do
+// not convert into each other, like grams and pieces, gets one line per unit.
+type PurchasingResponse struct {
+	Lines    []PurchasingLineResponse     `json:"lines"`
+	Unparsed []PurchasingUnparsedResponse `json:"unparsed"`
+}
+
+type PurchasingLineResponse struct {
+	IngredientId      int     `json:"ingredient_id"`
+	Name              string  `json:"name"`
+	IngredientGroupId *int    `json:"ingredient_group_id"`
+	Group             string  `json:"group"`
+	Storage           string  `json:"storage"`
+	EdibleQty         string  `json:"edible_qty"`
+	PurchaseQty       string  `json:"purchase_qty"`
+	Amount            float64 `json:"amount"`
+	Unit              string  `json:"unit"`
+}
+
+// PurchasingUnparsedResponse is a recipe line whose quantity could not be
+// read, so it is left out of the totals.
+type PurchasingUnparsedResponse struct {
+	MenuId       int    `json:"menu_id"`
+	IngredientId int    `json:"ingredient_id"`
+	Name         string `json:"name"`
+	Qty          string `json:"qty"`
+	Portions     int    `json:"portions"`
+}
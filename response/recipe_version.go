@@ -0,0 +1,41 @@
+package response
+
+import "time"
+
+type RecipeVersionResponse struct {
+	Id            int                         `json:"id"`
+	MenuId        int                         `json:"menu_id"`
+	Number        int                         `json:"number"`
+	Status        string                      `json:"status"`
+	Note          string                      `json:"note"`
+	EffectiveFrom *time.Time                  `json:"effective_from"`
+	AppliedAt     *time.Time                  `json:"applied_at"`
+	InEffect      bool                        `json:"in_effect"`
+	Lines         []RecipeVersionLineResponse `json:"lines"`
+	Version       int                         `json:"version"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+type RecipeVersionLineResponse struct {
+	IngredientId int    `json:"ingredient_id"`
+	Name         string `json:"name"`
+	Qty          string `json:"qty"`
+}
+
+// RecipeVersionDiffResponse lists what changes when going from version From
+// to version To. From is 0 when To is the first version of the menu.
+type RecipeVersionDiffResponse struct {
+	From    int                         `json:"from"`
+	To      int                         `json:"to"`
+	Added   []RecipeVersionLineResponse `json:"added"`
+	Removed []RecipeVersionLineResponse `json:"removed"`
+	Changed []RecipeVersionQtyChange    `json:"changed"`
+}
+
+type RecipeVersionQtyChange struct {
+	IngredientId int    `json:"ingredient_id"`
+	Name         string `json:"name"`
+	FromQty      string `json:"from_qty"`
+	ToQty        string `json:"to_qty"`
+}
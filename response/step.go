@@ -0,0 +1,16 @@
+package response
+
+import "time"
+
+type StepResponse struct {
+	Id              int       `json:"id"`
+	MenuId          int       `json:"menu_id"`
+	Position        int       `json:"position"`
+	Text            string    `json:"text"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Station         string    `json:"station"`
+	ImageUrl        string    `json:"image_url"`
+	Version         int       `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
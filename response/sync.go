@@ -0,0 +1,17 @@
+package response
+
+type SyncResponse struct {
+	Token            string                    `json:"token"`
+	HasMore          bool                      `json:"has_more"`
+	Categories       []CategoryResponse        `json:"categories"`
+	IngredientGroups []IngredientGroupResponse `json:"ingredient_groups"`
+	Ingredients      []IngredientResponse      `json:"ingredients"`
+	Menus            []MenuResponse            `json:"menus"`
+	Recipes          []RecipeResponse          `json:"recipes"`
+	Deleted          []TombstoneResponse       `json:"deleted"`
+}
+
+type TombstoneResponse struct {
+	Entity string `json:"entity"`
+	Id     int    `json:"id"`
+}
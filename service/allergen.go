@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erp_app/models"
+	"github.com/erp_app/response"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrGlutenFreeWithGluten = errors.New("an ingredient containing gluten cannot be gluten free")
+	ErrUnknownAllergen      = errors.New("unknown allergen")
+)
+
+// newIngredientAllergens turns allergen names into rows, dropping duplicates.
+func newIngredientAllergens(allergens []string) []models.IngredientAllergen {
+	listAllergen := []models.IngredientAllergen{}
+	seen := map[string]bool{}
+
+	for _, allergen := range allergens {
+		if seen[allergen] {
+			continue
+		}
+		seen[allergen] = true
+
+		listAllergen = append(listAllergen, models.IngredientAllergen{Allergen: allergen})
+	}
+
+	return listAllergen
+}
+
+func ingredientAllergens(ingredient models.Ingredient) []string {
+	allergens := []string{}
+	for _, allergen := range ingredient.Allergens {
+		allergens = append(allergens, allergen.Allergen)
+	}
+
+	sort.Strings(allergens)
+	return allergens
+}
+
+func checkDietary(ingredient models.Ingredient) error {
+	if !ingredient.GlutenFree {
+		return nil
+	}
+
+	for _, allergen := range ingredient.Allergens {
+		if allergen.Allergen == "gluten" {
+			return ErrGlutenFreeWithGluten
+		}
+	}
+
+	return nil
+}
+
+// parseAllergens reads a comma separated allergen filter.
+func parseAllergens(value string) ([]string, error) {
+	var allergens []string
+
+	for _, allergen := range strings.Split(value, ",") {
+		allergen = strings.ToLower(strings.TrimSpace(allergen))
+		if allergen == "" {
+			continue
+		}
+
+		if !isAllergen(allergen) {
+			return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownAllergen, allergen, strings.Join(models.Allergens, ", "))
+		}
+
+		allergens = append(allergens, allergen)
+	}
+
+	return allergens, nil
+}
+
+func isAllergen(value string) bool {
+	for _, allergen := range models.Allergens {
+		if allergen == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rollUpDietary fills the allergens and dietary flags of a menu response from
+// the ingredients of the menu. A menu without ingredients carries no dietary
+// flags, since nothing vouches for it.
+func rollUpDietary(res *response.MenuResponse, menu models.Menu) {
+	res.Allergens = []string{}
+	res.Vegan = len(menu.Ingredients) > 0
+	res.Halal = len(menu.Ingredients) > 0
+	res.GlutenFree = len(menu.Ingredients) > 0
+
+	seen := map[string]bool{}
+	for _, recipe := range menu.Ingredients {
+		ingredient := recipe.Ingredient
+		res.Vegan = res.Vegan && ingredient.Vegan
+		res.Halal = res.Halal && ingredient.Halal
+		res.GlutenFree = res.GlutenFree && ingredient.GlutenFree
+
+		for _, allergen := range ingredient.Allergens {
+			if !seen[allergen.Allergen] {
+				seen[allergen.Allergen] = true
+				res.Allergens = append(res.Allergens, allergen.Allergen)
+			}
+		}
+	}
+
+	sort.Strings(res.Allergens)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+	"time"
+)
+
+type AuditService interface {
+	Record(actor string, entityType string, entityId int, action string, before interface{}, after interface{}) error
+	GetAll(getAllAuditRequest request.GetAllAuditRequest) ([]response.AuditResponse, error)
+	Stream(getAllAuditRequest request.GetAllAuditRequest, fn func(res response.AuditResponse) error) error
+	WithTx(tx *gorm.DB) AuditService
+}
+
+type auditService struct {
+	auditRepository repository.AuditRepository
+}
+
+func NewAuditService(auditRepository repository.AuditRepository) AuditService {
+	return &auditService{auditRepository: auditRepository}
+}
+
+func (auditService *auditService) WithTx(tx *gorm.DB) AuditService {
+	return NewAuditService(auditService.auditRepository.WithTx(tx))
+}
+
+func (auditService *auditService) Record(actor string, entityType string, entityId int, action string, before interface{}, after interface{}) error {
+	changes, err := helper.ChangeSet(before, after)
+	if err != nil {
+		return err
+	}
+
+	auditLog := models.AuditLog{
+		Actor:      actor,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Action:     action,
+		Changes:    changes,
+	}
+
+	_, err = auditService.auditRepository.Create(auditLog)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (auditService *auditService) GetAll(getAllAuditRequest request.GetAllAuditRequest) ([]response.AuditResponse, error) {
+	var listRes []response.AuditResponse
+
+	from, to, err := auditPeriod(getAllAuditRequest)
+	if err != nil {
+		return listRes, err
+	}
+
+	listAuditLog, err := auditService.auditRepository.All(getAllAuditRequest.Entity, getAllAuditRequest.Id, from, to)
+	if err != nil {
+		return listRes, err
+	}
+
+	for _, auditLog := range listAuditLog {
+		listRes = append(listRes, newAuditResponse(auditLog))
+	}
+
+	return listRes, nil
+}
+
+func (auditService *auditService) Stream(getAllAuditRequest request.GetAllAuditRequest, fn func(res response.AuditResponse) error) error {
+	from, to, err := auditPeriod(getAllAuditRequest)
+	if err != nil {
+		return err
+	}
+
+	return auditService.auditRepository.Each(getAllAuditRequest.Entity, getAllAuditRequest.Id, from, to, func(auditLog models.AuditLog) error {
+		return fn(newAuditResponse(auditLog))
+	})
+}
+
+func auditPeriod(getAllAuditRequest request.GetAllAuditRequest) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if getAllAuditRequest.From != "" {
+		from, err = time.ParseInLocation(dateLayout, getAllAuditRequest.From, time.Local)
+		if err != nil {
+			return from, to, err
+		}
+	}
+
+	// the to date is inclusive, so filter up to the start of the next day
+	if getAllAuditRequest.To != "" {
+		to, err = time.ParseInLocation(dateLayout, getAllAuditRequest.To, time.Local)
+		if err != nil {
+			return from, to, err
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	return from, to, nil
+}
+
+func newAuditResponse(auditLog models.AuditLog) response.AuditResponse {
+	return response.AuditResponse{
+		Id:        auditLog.Id,
+		Actor:     auditLog.Actor,
+		Entity:    auditLog.EntityType,
+		EntityId:  auditLog.EntityId,
+		Action:    auditLog.Action,
+		Changes:   json.RawMessage(auditLog.Changes),
+		CreatedAt: auditLog.CreatedAt,
+	}
+}
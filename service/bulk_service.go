@@ -0,0 +1,278 @@
+package service
+
+import (
+	"errors"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+)
+
+var ErrBulkFailed = errors.New("bulk request failed, no item was applied")
+
+type BulkService interface {
+	CreateCategories(bulkRequest request.BulkRequest[request.CreateRequestCategory]) (response.BulkResponse, error)
+	UpdateCategories(bulkRequest request.BulkRequest[request.BulkUpdateCategoryItem]) (response.BulkResponse, error)
+	DeleteCategories(bulkRequest request.BulkRequest[request.BulkDeleteItem]) (response.BulkResponse, error)
+	CreateIngredients(bulkRequest request.BulkRequest[request.CreateRequestIngredient]) (response.BulkResponse, error)
+	UpdateIngredients(bulkRequest request.BulkRequest[request.BulkUpdateIngredientItem]) (response.BulkResponse, error)
+	DeleteIngredients(bulkRequest request.BulkRequest[request.BulkDeleteItem]) (response.BulkResponse, error)
+	CreateMenus(bulkRequest request.BulkRequest[request.CreateMenuRequest]) (response.BulkResponse, error)
+	UpdateMenus(bulkRequest request.BulkRequest[request.BulkUpdateMenuItem]) (response.BulkResponse, error)
+	DeleteMenus(bulkRequest request.BulkRequest[request.BulkDeleteItem]) (response.BulkResponse, error)
+	CreateRecipes(bulkRequest request.BulkRequest[request.BulkCreateRecipeItem]) (response.BulkResponse, error)
+	UpdateRecipes(bulkRequest request.BulkRequest[request.BulkUpdateRecipeItem]) (response.BulkResponse, error)
+	DeleteRecipes(bulkRequest request.BulkRequest[request.BulkDeleteRecipeItem]) (response.BulkResponse, error)
+}
+
+type bulkService struct {
+	transactionManager repository.TransactionManager
+	categoryService    CategoryService
+	ingredientService  IngredientService
+	menuService        MenuService
+	recipeService      RecipeService
+}
+
+func NewBulkService(transactionManager repository.TransactionManager, categoryService CategoryService, ingredientService IngredientService, menuService MenuService, recipeService RecipeService) BulkService {
+	return &bulkService{
+		transactionManager: transactionManager,
+		categoryService:    categoryService,
+		ingredientService:  ingredientService,
+		menuService:        menuService,
+		recipeService:      recipeService,
+	}
+}
+
+func (bulkService *bulkService) CreateCategories(bulkRequest request.BulkRequest[request.CreateRequestCategory]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.CreateRequestCategory) (interface{}, error) {
+		item.Actor = bulkRequest.Actor
+		return bulkService.categoryService.WithTx(tx).Create(item)
+	})
+}
+
+func (bulkService *bulkService) UpdateCategories(bulkRequest request.BulkRequest[request.BulkUpdateCategoryItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkUpdateCategoryItem) (interface{}, error) {
+		return bulkService.categoryService.WithTx(tx).Update(request.UpdateRequestCategory{
+			Id:      item.Id,
+			Name:    item.Name,
+			Actor:   bulkRequest.Actor,
+			Version: helper.Versions{item.Version},
+		})
+	})
+}
+
+func (bulkService *bulkService) DeleteCategories(bulkRequest request.BulkRequest[request.BulkDeleteItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkDeleteItem) (interface{}, error) {
+		return nil, bulkService.categoryService.WithTx(tx).Delete(request.DeleteRequestCategory{
+			Id:      item.Id,
+			Actor:   bulkRequest.Actor,
+			Version: helper.Versions{item.Version},
+		})
+	})
+}
+
+func (bulkService *bulkService) CreateIngredients(bulkRequest request.BulkRequest[request.CreateRequestIngredient]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.CreateRequestIngredient) (interface{}, error) {
+		item.Actor = bulkRequest.Actor
+		return bulkService.ingredientService.WithTx(tx).Create(item)
+	})
+}
+
+func (bulkService *bulkService) UpdateIngredients(bulkRequest request.BulkRequest[request.BulkUpdateIngredientItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkUpdateIngredientItem) (interface{}, error) {
+		return bulkService.ingredientService.WithTx(tx).Update(request.UpdateRequestIngredient{
+			Id:           item.Id,
+			Name:         item.Name,
+			YieldPercent: item.YieldPercent,
+			Actor:        bulkRequest.Actor,
+			Version:      helper.Versions{item.Version},
+		})
+	})
+}
+
+func (bulkService *bulkService) DeleteIngredients(bulkRequest request.BulkRequest[request.BulkDeleteItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkDeleteItem) (interface{}, error) {
+		return nil, bulkService.ingredientService.WithTx(tx).Delete(request.DeleteRequestIngredient{
+			Id:      item.Id,
+			Actor:   bulkRequest.Actor,
+			Version: helper.Versions{item.Version},
+		})
+	})
+}
+
+func (bulkService *bulkService) CreateMenus(bulkRequest request.BulkRequest[request.CreateMenuRequest]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.CreateMenuRequest) (interface{}, error) {
+		item.Actor = bulkRequest.Actor
+		return bulkService.menuService.WithTx(tx).Create(item)
+	})
+}
+
+func (bulkService *bulkService) UpdateMenus(bulkRequest request.BulkRequest[request.BulkUpdateMenuItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkUpdateMenuItem) (interface{}, error) {
+		return bulkService.menuService.WithTx(tx).Update(request.UpdateMenuRequest{
+			Id:         item.Id,
+			Name:       item.Name,
+			CategoryId: item.CategoryId,
+			YieldQty:   item.YieldQty,
+			Portions:   item.Portions,
+			Actor:      bulkRequest.Actor,
+			Version:    helper.Versions{item.Version},
+		})
+	})
+}
+
+func (bulkService *bulkService) DeleteMenus(bulkRequest request.BulkRequest[request.BulkDeleteItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkDeleteItem) (interface{}, error) {
+		return nil, bulkService.menuService.WithTx(tx).Delete(request.DeleteMenuRequest{
+			Id:      item.Id,
+			Actor:   bulkRequest.Actor,
+			Version: helper.Versions{item.Version},
+		})
+	})
+}
+
+func (bulkService *bulkService) CreateRecipes(bulkRequest request.BulkRequest[request.BulkCreateRecipeItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkCreateRecipeItem) (interface{}, error) {
+		recipe, err := bulkService.recipeService.WithTx(tx).Create(request.CreateRecipeRequest{
+			MenuId:       item.MenuId,
+			IngredientId: item.IngredientId,
+			Qty:          item.Qty,
+			YieldPercent: item.YieldPercent,
+			Actor:        bulkRequest.Actor,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return newRecipeResponse(recipe), nil
+	})
+}
+
+func (bulkService *bulkService) UpdateRecipes(bulkRequest request.BulkRequest[request.BulkUpdateRecipeItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkUpdateRecipeItem) (interface{}, error) {
+		recipe, err := bulkService.recipeService.WithTx(tx).Update(request.UpdateRecipeRequest{
+			Id:           item.Id,
+			MenuId:       item.MenuId,
+			IngredientId: item.IngredientId,
+			Qty:          item.Qty,
+			YieldPercent: item.YieldPercent,
+			Actor:        bulkRequest.Actor,
+			Version:      helper.Versions{item.Version},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return newRecipeResponse(recipe), nil
+	})
+}
+
+func (bulkService *bulkService) DeleteRecipes(bulkRequest request.BulkRequest[request.BulkDeleteRecipeItem]) (response.BulkResponse, error) {
+	return runBulk(bulkService.transactionManager, bulkRequest, func(tx *gorm.DB, item request.BulkDeleteRecipeItem) (interface{}, error) {
+		return nil, bulkService.recipeService.WithTx(tx).Delete(request.DeleteRecipeRequest{
+			Id:      item.Id,
+			MenuId:  item.MenuId,
+			Actor:   bulkRequest.Actor,
+			Version: helper.Versions{item.Version},
+		})
+	})
+}
+
+// runBulk applies every valid item. By default all items share one
+// transaction and a single failure rolls everything back; in best-effort mode
+// each item commits on its own. Every item runs in a savepoint so a failing
+// statement does not abort the surrounding transaction on postgres.
+func runBulk[T any](transactionManager repository.TransactionManager, bulkRequest request.BulkRequest[T], apply func(tx *gorm.DB, item T) (interface{}, error)) (response.BulkResponse, error) {
+	res := response.BulkResponse{
+		BestEffort: bulkRequest.BestEffort,
+		Items:      make([]response.BulkItemResponse, len(bulkRequest.Items)),
+	}
+
+	for i := range bulkRequest.Items {
+		res.Items[i] = response.BulkItemResponse{Index: i, Status: response.BulkStatusSkipped}
+		if len(bulkRequest.Errors[i]) > 0 {
+			res.Items[i].Status = response.BulkStatusError
+			res.Items[i].Errors = bulkRequest.Errors[i]
+		}
+	}
+
+	applyItem := func(tx *gorm.DB, i int) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			data, err := apply(tx, bulkRequest.Items[i])
+			if err != nil {
+				res.Items[i].Status = response.BulkStatusError
+				res.Items[i].Errors = []string{err.Error()}
+				return err
+			}
+
+			res.Items[i].Status = response.BulkStatusOk
+			res.Items[i].Data = data
+			return nil
+		})
+	}
+
+	if bulkRequest.BestEffort {
+		for i := range bulkRequest.Items {
+			if res.Items[i].Status == response.BulkStatusError {
+				continue
+			}
+
+			// the error is already reported on the item
+			_ = transactionManager.Transaction(func(tx *gorm.DB) error {
+				return applyItem(tx, i)
+			})
+		}
+
+		countBulk(&res)
+		return res, nil
+	}
+
+	if len(bulkRequest.Errors) > 0 {
+		countBulk(&res)
+		return res, ErrBulkFailed
+	}
+
+	var firstErr error
+	err := transactionManager.Transaction(func(tx *gorm.DB) error {
+		for i := range bulkRequest.Items {
+			err := applyItem(tx, i)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		return firstErr
+	})
+
+	if err != nil {
+		for i := range res.Items {
+			if res.Items[i].Status == response.BulkStatusOk {
+				res.Items[i].Status = response.BulkStatusRolledBack
+				res.Items[i].Data = nil
+			}
+		}
+
+		countBulk(&res)
+		if firstErr != nil {
+			return res, ErrBulkFailed
+		}
+		return res, err
+	}
+
+	countBulk(&res)
+	return res, nil
+}
+
+func countBulk(res *response.BulkResponse) {
+	res.Succeeded = 0
+	res.Failed = 0
+	for _, item := range res.Items {
+		switch item.Status {
+		case response.BulkStatusOk:
+			res.Succeeded++
+		case response.BulkStatusError:
+			res.Failed++
+		}
+	}
+}
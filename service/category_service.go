@@ -1,41 +1,107 @@
 package service
 
 import (
+	"errors"
+	"github.com/erp_app/libraries"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
 	"github.com/erp_app/request"
 	"github.com/erp_app/response"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrCategoryCycle       = errors.New("a category cannot be placed below itself or one of its subcategories")
+	ErrCategoryHasChildren = errors.New("category still has subcategories")
 )
 
 type CategoryService interface {
 	Create(createRequestCategory request.CreateRequestCategory) (response.CategoryResponse, error)
 	Get(getDetailCategoryRequest request.GetDetailRequestCategory) (response.CategoryResponse, error)
 	GetAll(getAllCategoryRequest request.GetAllRequestCategory) ([]response.CategoryResponse, error)
+	Tree() ([]response.CategoryTreeResponse, error)
+	Reorder(reorderCategoryRequest request.ReorderCategoryRequest) ([]response.CategoryResponse, error)
+	Stream(getAllCategoryRequest request.GetAllRequestCategory, fn func(res response.CategoryResponse) error) error
 	Update(updateRequestCategory request.UpdateRequestCategory) (response.CategoryResponse, error)
 	Delete(deleteRequestCategory request.DeleteRequestCategory) error
+	WithTx(tx *gorm.DB) CategoryService
 }
 
 type categoryService struct {
+	transactionManager repository.TransactionManager
 	categoryRepository repository.CategoryRepository
+	auditService       AuditService
+	storage            libraries.Storage
+}
+
+func NewCategoryService(transactionManager repository.TransactionManager, categoryRepository repository.CategoryRepository, auditService AuditService, storage libraries.Storage) CategoryService {
+	return newCategoryService(transactionManager, categoryRepository, auditService, storage)
+}
+
+func newCategoryService(transactionManager repository.TransactionManager, categoryRepository repository.CategoryRepository, auditService AuditService, storage libraries.Storage) *categoryService {
+	return &categoryService{
+		transactionManager: transactionManager,
+		categoryRepository: categoryRepository,
+		auditService:       auditService,
+		storage:            storage,
+	}
 }
 
-func NewCategoryService(categoryRepository repository.CategoryRepository) CategoryService {
-	return &categoryService{categoryRepository: categoryRepository}
+func (categoryService *categoryService) WithTx(tx *gorm.DB) CategoryService {
+	return categoryService.withTx(tx)
+}
+
+func (categoryService *categoryService) withTx(tx *gorm.DB) *categoryService {
+	return newCategoryService(repository.NewTransactionManager(tx), categoryService.categoryRepository.WithTx(tx), categoryService.auditService.WithTx(tx), categoryService.storage)
 }
 
 func (categoryService *categoryService) Create(createRequestCategory request.CreateRequestCategory) (response.CategoryResponse, error) {
 	res := response.CategoryResponse{}
 
+	err := categoryService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = categoryService.withTx(tx).create(createRequestCategory)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (categoryService *categoryService) create(createRequestCategory request.CreateRequestCategory) (response.CategoryResponse, error) {
+	res := response.CategoryResponse{}
+
+	findName := categoryService.findName(createRequestCategory.Name)
+	err := uniqueName("category", createRequestCategory.Name, 0, findName)
+	if err != nil {
+		return res, err
+	}
+
 	category := models.Category{}
 	category.Name = createRequestCategory.Name
 
-	category, err := categoryService.categoryRepository.Create(category)
+	if createRequestCategory.ParentId != nil {
+		parent, err := categoryService.categoryRepository.Find(*createRequestCategory.ParentId)
+		if err != nil {
+			return res, err
+		}
+		category.ParentId = &parent.Id
+	}
+
+	category, err = categoryService.categoryRepository.Create(category)
+	if err != nil {
+		return res, nameConflict(err, "category", createRequestCategory.Name, 0, findName)
+	}
+
+	err = categoryService.auditService.Record(createRequestCategory.Actor, "category", category.Id, models.AuditActionCreate, nil, category)
 	if err != nil {
 		return res, err
 	}
 
-	res.Id = category.Id
-	res.Name = category.Name
+	res = newCategoryResponse(category, categoryService.storage)
 
 	return res, nil
 }
@@ -47,24 +113,36 @@ func (categoryService *categoryService) Get(getDetailCategoryRequest request.Get
 		return res, err
 	}
 
-	res.Id = category.Id
-	res.Name = category.Name
+	res = newCategoryResponse(category, categoryService.storage)
+
+	ancestors, err := categoryService.ancestors(category, categoryService.categoryRepository.Find)
+	if err != nil {
+		return res, err
+	}
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		res.Breadcrumb = append(res.Breadcrumb, response.CategoryCrumbResponse{Id: ancestors[i].Id, Name: ancestors[i].Name})
+	}
+	res.Breadcrumb = append(res.Breadcrumb, response.CategoryCrumbResponse{Id: category.Id, Name: category.Name})
 
 	return res, nil
 }
 
 func (categoryService *categoryService) GetAll(getAllCategoryRequest request.GetAllRequestCategory) ([]response.CategoryResponse, error) {
 	var listRes []response.CategoryResponse
-	listCategory, err := categoryService.categoryRepository.All(getAllCategoryRequest.Name)
+	updatedSince, err := parseTimestamp(getAllCategoryRequest.UpdatedSince)
+	if err != nil {
+		return listRes, err
+	}
+
+	listCategory, err := categoryService.categoryRepository.All(getAllCategoryRequest.Name, updatedSince)
 	if err != nil {
 		return listRes, err
 	}
 
 	if len(listCategory) > 0 {
 		for _, category := range listCategory {
-			res := response.CategoryResponse{}
-			res.Id = category.Id
-			res.Name = category.Name
+			res := newCategoryResponse(category, categoryService.storage)
 
 			listRes = append(listRes, res)
 		}
@@ -74,37 +152,301 @@ func (categoryService *categoryService) GetAll(getAllCategoryRequest request.Get
 
 }
 
+func (categoryService *categoryService) Stream(getAllCategoryRequest request.GetAllRequestCategory, fn func(res response.CategoryResponse) error) error {
+	updatedSince, err := parseTimestamp(getAllCategoryRequest.UpdatedSince)
+	if err != nil {
+		return err
+	}
+
+	return categoryService.categoryRepository.Each(getAllCategoryRequest.Name, updatedSince, func(category models.Category) error {
+		return fn(newCategoryResponse(category, categoryService.storage))
+	})
+}
+
 func (categoryService *categoryService) Update(updateRequestCategory request.UpdateRequestCategory) (response.CategoryResponse, error) {
 	res := response.CategoryResponse{}
 
-	category, err := categoryService.categoryRepository.Find(updateRequestCategory.Id)
+	err := categoryService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = categoryService.withTx(tx).update(updateRequestCategory)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (categoryService *categoryService) update(updateRequestCategory request.UpdateRequestCategory) (response.CategoryResponse, error) {
+	res := response.CategoryResponse{}
+
+	// the category and every ancestor of its new parent stay locked until
+	// the move commits, so two moves cannot each pass the cycle check and
+	// together form a loop
+	category, err := categoryService.categoryRepository.FindForUpdate(updateRequestCategory.Id)
 	if err != nil {
 		return res, err
 	}
 
+	if !versionMatches(updateRequestCategory.Version, category.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	findName := categoryService.findName(updateRequestCategory.Name)
+	if !models.SameNameKey(category.NameKey, category.Id, updateRequestCategory.Name) {
+		err = uniqueName("category", updateRequestCategory.Name, category.Id, findName)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	before := category
 	category.Name = updateRequestCategory.Name
 
+	if updateRequestCategory.ParentId != nil {
+		category.ParentId = nil
+		if *updateRequestCategory.ParentId != 0 {
+			parent, err := categoryService.categoryRepository.FindForUpdate(*updateRequestCategory.ParentId)
+			if err != nil {
+				return res, err
+			}
+
+			if parent.Id == category.Id {
+				return res, ErrCategoryCycle
+			}
+
+			ancestors, err := categoryService.ancestors(parent, categoryService.categoryRepository.FindForUpdate)
+			if err != nil {
+				return res, err
+			}
+			for _, ancestor := range ancestors {
+				if ancestor.Id == category.Id {
+					return res, ErrCategoryCycle
+				}
+			}
+
+			category.ParentId = &parent.Id
+		}
+	}
+
+	// a category moved to another parent goes to the end of its new siblings
+	if !sameParent(before.ParentId, category.ParentId) {
+		category.Position, err = categoryService.categoryRepository.NextPosition(category.ParentId)
+		if err != nil {
+			return res, err
+		}
+	}
+
 	category, err = categoryService.categoryRepository.Update(category)
+	if err != nil {
+		return res, nameConflict(err, "category", updateRequestCategory.Name, category.Id, findName)
+	}
+
+	err = categoryService.auditService.Record(updateRequestCategory.Actor, "category", category.Id, models.AuditActionUpdate, before, category)
 	if err != nil {
 		return res, err
 	}
 
-	res.Id = category.Id
-	res.Name = category.Name
+	res = newCategoryResponse(category, categoryService.storage)
 
 	return res, nil
 }
 
 func (categoryService *categoryService) Delete(deleteRequestCategory request.DeleteRequestCategory) error {
+	return categoryService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		return categoryService.withTx(tx).delete(deleteRequestCategory)
+	})
+}
+
+func (categoryService *categoryService) delete(deleteRequestCategory request.DeleteRequestCategory) error {
 	category, err := categoryService.categoryRepository.Find(deleteRequestCategory.Id)
 	if err != nil {
 		return err
 	}
 
+	if !versionMatches(deleteRequestCategory.Version, category.Version) {
+		return repository.ErrVersionConflict
+	}
+
+	children, err := categoryService.categoryRepository.CountChildren(category.Id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrCategoryHasChildren
+	}
+
 	err = categoryService.categoryRepository.Delete(category)
 	if err != nil {
 		return err
 	}
 
+	err = categoryService.auditService.Record(deleteRequestCategory.Actor, "category", category.Id, models.AuditActionDelete, category, nil)
+	if err != nil {
+		return err
+	}
+
+	// The files go only once the delete is committed; a rollback keeps the
+	// record and it still needs them.
+	categoryService.transactionManager.AfterCommit(func() {
+		removeImage(categoryService.storage, category.ImageKey)
+	})
+
 	return nil
 }
+
+// Tree returns every category nested below its parent, in display order.
+func (categoryService *categoryService) Tree() ([]response.CategoryTreeResponse, error) {
+	listCategory, err := categoryService.categoryRepository.All("", time.Time{})
+	if err != nil {
+		return []response.CategoryTreeResponse{}, err
+	}
+
+	ids := map[int]bool{}
+	children := map[int][]models.Category{}
+	for _, category := range listCategory {
+		ids[category.Id] = true
+	}
+	for _, category := range listCategory {
+		// a category whose parent is gone is shown at the top level
+		parentId := 0
+		if category.ParentId != nil && ids[*category.ParentId] {
+			parentId = *category.ParentId
+		}
+		children[parentId] = append(children[parentId], category)
+	}
+
+	seen := map[int]bool{}
+	var branch func(parentId int) []response.CategoryTreeResponse
+	branch = func(parentId int) []response.CategoryTreeResponse {
+		listNode := []response.CategoryTreeResponse{}
+		for _, category := range children[parentId] {
+			if seen[category.Id] {
+				continue
+			}
+			seen[category.Id] = true
+
+			listNode = append(listNode, response.CategoryTreeResponse{
+				Id:       category.Id,
+				Name:     category.Name,
+				ParentId: category.ParentId,
+				Position: category.Position,
+				Children: branch(category.Id),
+			})
+		}
+
+		return listNode
+	}
+
+	return branch(0), nil
+}
+
+// Reorder sets the display order of the categories under one parent.
+func (categoryService *categoryService) Reorder(reorderCategoryRequest request.ReorderCategoryRequest) ([]response.CategoryResponse, error) {
+	listRes := []response.CategoryResponse{}
+
+	err := categoryService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		listRes, err = categoryService.withTx(tx).reorder(reorderCategoryRequest)
+		return err
+	})
+	if err != nil {
+		return listRes, err
+	}
+
+	return listRes, nil
+}
+
+func (categoryService *categoryService) reorder(reorderCategoryRequest request.ReorderCategoryRequest) ([]response.CategoryResponse, error) {
+	listCategoryResponse := []response.CategoryResponse{}
+
+	if reorderCategoryRequest.ParentId != nil {
+		_, err := categoryService.categoryRepository.Find(*reorderCategoryRequest.ParentId)
+		if err != nil {
+			return listCategoryResponse, err
+		}
+	}
+
+	before, err := categoryService.categoryRepository.AllByIds(reorderCategoryRequest.CategoryIds)
+	if err != nil {
+		return listCategoryResponse, err
+	}
+
+	listCategory, err := categoryService.categoryRepository.Reorder(reorderCategoryRequest.ParentId, reorderCategoryRequest.CategoryIds)
+	if err != nil {
+		return listCategoryResponse, err
+	}
+
+	previous := map[int]models.Category{}
+	for _, category := range before {
+		previous[category.Id] = category
+	}
+
+	for _, category := range listCategory {
+		if previous[category.Id].Position != category.Position {
+			err = categoryService.auditService.Record(reorderCategoryRequest.Actor, "category", category.Id, models.AuditActionUpdate, previous[category.Id], category)
+			if err != nil {
+				return listCategoryResponse, err
+			}
+		}
+
+		listCategoryResponse = append(listCategoryResponse, newCategoryResponse(category, categoryService.storage))
+	}
+
+	return listCategoryResponse, nil
+}
+
+func (categoryService *categoryService) findName(name string) func() (int, error) {
+	return func() (int, error) {
+		category, err := categoryService.categoryRepository.FindByName(name)
+		return category.Id, err
+	}
+}
+
+func sameParent(left *int, right *int) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+
+	return *left == *right
+}
+
+// ancestors walks up from the parent of a category to the top level, loading
+// each one with find.
+func (categoryService *categoryService) ancestors(category models.Category, find func(id int) (models.Category, error)) ([]models.Category, error) {
+	var ancestors []models.Category
+
+	seen := map[int]bool{category.Id: true}
+	for category.ParentId != nil && !seen[*category.ParentId] {
+		parent, err := find(*category.ParentId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return ancestors, err
+		}
+
+		seen[parent.Id] = true
+		ancestors = append(ancestors, parent)
+		category = parent
+	}
+
+	return ancestors, nil
+}
+
+func newCategoryResponse(category models.Category, storage libraries.Storage) response.CategoryResponse {
+	imageUrl, thumbnailUrl := imageUrls(storage, category.ImageKey)
+
+	return response.CategoryResponse{
+		Id:           category.Id,
+		Name:         category.Name,
+		ParentId:     category.ParentId,
+		Position:     category.Position,
+		ImageUrl:     imageUrl,
+		ThumbnailUrl: thumbnailUrl,
+		Version:      category.Version,
+		CreatedAt:    category.CreatedAt,
+		UpdatedAt:    category.UpdatedAt,
+	}
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+	"io"
+	"path"
+	"strings"
+)
+
+var ErrNoImage = errors.New("there is no image to delete")
+
+type ImageService interface {
+	PutMenuImage(uploadImageRequest request.UploadImageRequest) (response.MenuResponse, error)
+	DeleteMenuImage(deleteImageRequest request.DeleteImageRequest) (response.MenuResponse, error)
+	PutCategoryImage(uploadImageRequest request.UploadImageRequest) (response.CategoryResponse, error)
+	DeleteCategoryImage(deleteImageRequest request.DeleteImageRequest) (response.CategoryResponse, error)
+}
+
+type imageService struct {
+	transactionManager repository.TransactionManager
+	storage            libraries.Storage
+	menuRepository     repository.MenuRepository
+	categoryRepository repository.CategoryRepository
+	auditService       AuditService
+	maxUploadSize      int64
+	thumbnailWidth     int
+}
+
+func NewImageService(transactionManager repository.TransactionManager, storage libraries.Storage, menuRepository repository.MenuRepository, categoryRepository repository.CategoryRepository, auditService AuditService, maxUploadSize int64, thumbnailWidth int) ImageService {
+	return &imageService{
+		transactionManager: transactionManager,
+		storage:            storage,
+		menuRepository:     menuRepository,
+		categoryRepository: categoryRepository,
+		auditService:       auditService,
+		maxUploadSize:      maxUploadSize,
+		thumbnailWidth:     thumbnailWidth,
+	}
+}
+
+func (imageService *imageService) PutMenuImage(uploadImageRequest request.UploadImageRequest) (response.MenuResponse, error) {
+	res := response.MenuResponse{}
+
+	menu, err := imageService.menuRepository.Find(uploadImageRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(uploadImageRequest.Version, menu.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	key, err := imageService.store("menus", menu.Id, uploadImageRequest)
+	if err != nil {
+		return res, err
+	}
+
+	before := menu
+	menu.ImageKey = key
+
+	return imageService.updateMenu(menu, before, key, uploadImageRequest.Actor)
+}
+
+func (imageService *imageService) DeleteMenuImage(deleteImageRequest request.DeleteImageRequest) (response.MenuResponse, error) {
+	res := response.MenuResponse{}
+
+	menu, err := imageService.menuRepository.Find(deleteImageRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(deleteImageRequest.Version, menu.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	if menu.ImageKey == "" {
+		return res, ErrNoImage
+	}
+
+	before := menu
+	menu.ImageKey = ""
+
+	return imageService.updateMenu(menu, before, "", deleteImageRequest.Actor)
+}
+
+// updateMenu saves the image fields of a menu with its audit entry. The files
+// of the new image are removed when the menu cannot be saved, the files of the
+// old one once it is.
+func (imageService *imageService) updateMenu(menu models.Menu, before models.Menu, newKey string, actor string) (response.MenuResponse, error) {
+	res := response.MenuResponse{}
+
+	err := imageService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		menu, err = imageService.menuRepository.WithTx(tx).Update(menu)
+		if err != nil {
+			return err
+		}
+
+		return imageService.auditService.WithTx(tx).Record(actor, "menu", menu.Id, models.AuditActionUpdate, before, menu)
+	})
+	if err != nil {
+		imageService.remove(newKey)
+		return res, err
+	}
+
+	imageService.remove(before.ImageKey)
+
+	res = newMenuResponse(menu, menu.Category, imageService.storage)
+	rollUpDietary(&res, menu)
+	rollUpNutrition(&res, menu)
+
+	return res, nil
+}
+
+func (imageService *imageService) PutCategoryImage(uploadImageRequest request.UploadImageRequest) (response.CategoryResponse, error) {
+	res := response.CategoryResponse{}
+
+	category, err := imageService.categoryRepository.Find(uploadImageRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(uploadImageRequest.Version, category.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	key, err := imageService.store("categories", category.Id, uploadImageRequest)
+	if err != nil {
+		return res, err
+	}
+
+	before := category
+	category.ImageKey = key
+
+	return imageService.updateCategory(category, before, key, uploadImageRequest.Actor)
+}
+
+func (imageService *imageService) DeleteCategoryImage(deleteImageRequest request.DeleteImageRequest) (response.CategoryResponse, error) {
+	res := response.CategoryResponse{}
+
+	category, err := imageService.categoryRepository.Find(deleteImageRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(deleteImageRequest.Version, category.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	if category.ImageKey == "" {
+		return res, ErrNoImage
+	}
+
+	before := category
+	category.ImageKey = ""
+
+	return imageService.updateCategory(category, before, "", deleteImageRequest.Actor)
+}
+
+func (imageService *imageService) updateCategory(category models.Category, before models.Category, newKey string, actor string) (response.CategoryResponse, error) {
+	res := response.CategoryResponse{}
+
+	err := imageService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		category, err = imageService.categoryRepository.WithTx(tx).Update(category)
+		if err != nil {
+			return err
+		}
+
+		return imageService.auditService.WithTx(tx).Record(actor, "category", category.Id, models.AuditActionUpdate, before, category)
+	})
+	if err != nil {
+		imageService.remove(newKey)
+		return res, err
+	}
+
+	imageService.remove(before.ImageKey)
+
+	res = newCategoryResponse(category, imageService.storage)
+
+	return res, nil
+}
+
+// store checks an upload and saves it with its thumbnail, returning the key
+// of the image. Every upload gets a new random name, so a replaced image is
+// never served from a stale cache.
+func (imageService *imageService) store(prefix string, id int, uploadImageRequest request.UploadImageRequest) (string, error) {
+	if uploadImageRequest.Image == nil {
+		return "", helper.ErrImageRequired
+	}
+
+	image, err := helper.ReadImage(uploadImageRequest.Image, imageService.maxUploadSize, imageService.thumbnailWidth)
+	if err != nil {
+		return "", err
+	}
+
+	token := make([]byte, 8)
+	_, err = rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s/%d/%s.%s", prefix, id, hex.EncodeToString(token), image.Extension)
+	err = imageService.storage.Put(key, bytes.NewReader(image.Content), image.ContentType)
+	if err != nil {
+		return "", err
+	}
+
+	err = imageService.storage.Put(thumbnailKey(key), bytes.NewReader(image.Thumbnail), "image/jpeg")
+	if err != nil {
+		imageService.remove(key)
+		return "", err
+	}
+
+	return key, nil
+}
+
+// remove deletes an image and its thumbnail. Failures are ignored: no record
+// points at the files anymore, so they only take up space.
+func (imageService *imageService) remove(key string) {
+	removeImage(imageService.storage, key)
+}
+
+func removeImage(storage libraries.Storage, key string) {
+	if key == "" {
+		return
+	}
+
+	storage.Delete(key)
+	storage.Delete(thumbnailKey(key))
+}
+
+func thumbnailKey(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "_thumb.jpg"
+}
+
+// imageUrls returns where clients fetch an image and its thumbnail. Only the
+// key is stored, so moving the files to another storage or host needs no
+// change to the records.
+func imageUrls(storage libraries.Storage, key string) (string, string) {
+	if key == "" {
+		return "", ""
+	}
+
+	return storage.URL(key), storage.URL(thumbnailKey(key))
+}
+
+// recipeCardPhoto loads the image of a menu for its recipe card. Types fpdf
+// cannot draw fall back to the jpeg thumbnail; an image that cannot be read
+// leaves the card without a photo.
+func recipeCardPhoto(storage libraries.Storage, key string) (io.Reader, string) {
+	if key == "" {
+		return nil, ""
+	}
+
+	photoType := strings.TrimPrefix(path.Ext(key), ".")
+	if photoType != "jpg" && photoType != "png" && photoType != "gif" {
+		key = thumbnailKey(key)
+		photoType = "jpg"
+	}
+
+	file, err := storage.Get(key)
+	if err != nil {
+		return nil, ""
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, ""
+	}
+
+	return bytes.NewReader(content), photoType
+}
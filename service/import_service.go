@@ -0,0 +1,466 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrImportEntityRequired = errors.New("entity is required to import a csv file")
+	ErrImportNoSheet        = errors.New("the file has no categories, ingredients, menus or recipes sheet")
+	errImportDryRun         = errors.New("dry run")
+)
+
+// importEntities lists the entities in the order their sheets are imported,
+// so menus can refer to categories created by the same file.
+var importEntities = []string{"category", "ingredient", "menu", "recipe"}
+
+var importSheetNames = map[string]string{
+	"category":    "category",
+	"categories":  "category",
+	"ingredient":  "ingredient",
+	"ingredients": "ingredient",
+	"menu":        "menu",
+	"menus":       "menu",
+	"recipe":      "recipe",
+	"recipes":     "recipe",
+}
+
+type ImportService interface {
+	Import(importRequest request.ImportRequest) (response.ImportResponse, error)
+}
+
+type importService struct {
+	transactionManager   repository.TransactionManager
+	categoryRepository   repository.CategoryRepository
+	ingredientRepository repository.IngredientRepository
+	menuRepository       repository.MenuRepository
+	recipeRepository     repository.RecipeRepository
+	categoryService      CategoryService
+	ingredientService    IngredientService
+	menuService          MenuService
+	recipeService        RecipeService
+	validate             *validator.Validate
+}
+
+func NewImportService(transactionManager repository.TransactionManager, categoryRepository repository.CategoryRepository, ingredientRepository repository.IngredientRepository, menuRepository repository.MenuRepository, recipeRepository repository.RecipeRepository, categoryService CategoryService, ingredientService IngredientService, menuService MenuService, recipeService RecipeService) ImportService {
+	return &importService{
+		transactionManager:   transactionManager,
+		categoryRepository:   categoryRepository,
+		ingredientRepository: ingredientRepository,
+		menuRepository:       menuRepository,
+		recipeRepository:     recipeRepository,
+		categoryService:      categoryService,
+		ingredientService:    ingredientService,
+		menuService:          menuService,
+		recipeService:        recipeService,
+		validate:             libraries.NewValidator(),
+	}
+}
+
+// importTx bundles the repositories and services bound to the import
+// transaction.
+type importTx struct {
+	actor                string
+	validate             *validator.Validate
+	categoryRepository   repository.CategoryRepository
+	ingredientRepository repository.IngredientRepository
+	menuRepository       repository.MenuRepository
+	recipeRepository     repository.RecipeRepository
+	categoryService      CategoryService
+	ingredientService    IngredientService
+	menuService          MenuService
+	recipeService        RecipeService
+}
+
+// Import creates or updates the rows of a csv or xlsx file through the
+// regular services. Rows are matched by id when the id column is filled and
+// by name otherwise; a rejected row does not stop the others. A dry run does
+// the same work and rolls it back, so the report shows exactly what a real
+// import would do.
+func (importService *importService) Import(importRequest request.ImportRequest) (response.ImportResponse, error) {
+	res := response.ImportResponse{DryRun: importRequest.DryRun, Rows: []response.ImportRowResponse{}}
+
+	sheets, err := helper.ReadSpreadsheet(importRequest.File, importRequest.Format)
+	if err != nil {
+		return res, err
+	}
+
+	sheetsByEntity, err := importSheets(sheets, importRequest.Entity)
+	if err != nil {
+		return res, err
+	}
+
+	err = importService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		itx := importTx{
+			actor:                importRequest.Actor,
+			validate:             importService.validate,
+			categoryRepository:   importService.categoryRepository.WithTx(tx),
+			ingredientRepository: importService.ingredientRepository.WithTx(tx),
+			menuRepository:       importService.menuRepository.WithTx(tx),
+			recipeRepository:     importService.recipeRepository.WithTx(tx),
+			categoryService:      importService.categoryService.WithTx(tx),
+			ingredientService:    importService.ingredientService.WithTx(tx),
+			menuService:          importService.menuService.WithTx(tx),
+			recipeService:        importService.recipeService.WithTx(tx),
+		}
+
+		for _, entity := range importEntities {
+			for _, row := range sheetsByEntity[entity] {
+				rowResponse := response.ImportRowResponse{Entity: entity, Row: row.Line}
+
+				// every row runs in a savepoint so a rejected row leaves no
+				// trace; the savepoint lives on the connection itx already uses
+				err := tx.Transaction(func(*gorm.DB) error {
+					var err error
+					rowResponse.Action, rowResponse.Id, err = itx.importRow(entity, row.Values)
+					return err
+				})
+				if err != nil {
+					rowResponse.Action = response.ImportActionReject
+					rowResponse.Errors = importErrors(err)
+				}
+
+				res.Rows = append(res.Rows, rowResponse)
+			}
+		}
+
+		if importRequest.DryRun {
+			return errImportDryRun
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return res, err
+	}
+
+	for _, row := range res.Rows {
+		switch row.Action {
+		case response.ImportActionCreate:
+			res.Created++
+		case response.ImportActionUpdate:
+			res.Updated++
+		case response.ImportActionUnchanged:
+			res.Unchanged++
+		case response.ImportActionReject:
+			res.Rejected++
+		}
+	}
+
+	return res, nil
+}
+
+func importSheets(sheets []helper.Sheet, entity string) (map[string][]helper.SheetRow, error) {
+	sheetsByEntity := map[string][]helper.SheetRow{}
+
+	if entity != "" {
+		for _, sheet := range sheets {
+			if importSheetNames[strings.ToLower(sheet.Name)] == entity {
+				sheetsByEntity[entity] = sheet.Rows
+				return sheetsByEntity, nil
+			}
+		}
+
+		if len(sheets) > 0 {
+			sheetsByEntity[entity] = sheets[0].Rows
+		}
+		return sheetsByEntity, nil
+	}
+
+	for _, sheet := range sheets {
+		if sheet.Name == "" {
+			return nil, ErrImportEntityRequired
+		}
+
+		sheetEntity, ok := importSheetNames[strings.ToLower(sheet.Name)]
+		if ok {
+			sheetsByEntity[sheetEntity] = append(sheetsByEntity[sheetEntity], sheet.Rows...)
+		}
+	}
+
+	if len(sheetsByEntity) == 0 {
+		return nil, ErrImportNoSheet
+	}
+
+	return sheetsByEntity, nil
+}
+
+func importErrors(err error) []string {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return helper.FormatErrorValidation(validationErrors)
+	}
+
+	return []string{err.Error()}
+}
+
+func (itx importTx) importRow(entity string, values map[string]string) (string, int, error) {
+	switch entity {
+	case "category":
+		return itx.importCategory(values)
+	case "ingredient":
+		return itx.importIngredient(values)
+	case "menu":
+		return itx.importMenu(values)
+	default:
+		return itx.importRecipe(values)
+	}
+}
+
+func (itx importTx) importCategory(values map[string]string) (string, int, error) {
+	createRequest := request.CreateRequestCategory{Name: values["name"], Actor: itx.actor}
+	err := itx.validate.Struct(createRequest)
+	if err != nil {
+		return "", 0, err
+	}
+
+	id, err := importLookup(values["id"], "category", func() (int, error) {
+		category, err := itx.categoryRepository.FindByName(createRequest.Name)
+		return category.Id, err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if id == 0 {
+		category, err := itx.categoryService.Create(createRequest)
+		return response.ImportActionCreate, category.Id, err
+	}
+
+	category, err := itx.categoryRepository.Find(id)
+	if err != nil {
+		return "", 0, fmt.Errorf("category %d not found", id)
+	}
+
+	if category.Name == models.NormalizeName(createRequest.Name) {
+		return response.ImportActionUnchanged, category.Id, nil
+	}
+
+	_, err = itx.categoryService.Update(request.UpdateRequestCategory{
+		Id:      category.Id,
+		Name:    createRequest.Name,
+		Actor:   itx.actor,
+		Version: helper.Versions{category.Version},
+	})
+	return response.ImportActionUpdate, category.Id, err
+}
+
+func (itx importTx) importIngredient(values map[string]string) (string, int, error) {
+	createRequest := request.CreateRequestIngredient{Name: values["name"], Actor: itx.actor}
+	err := itx.validate.Struct(createRequest)
+	if err != nil {
+		return "", 0, err
+	}
+
+	id, err := importLookup(values["id"], "ingredient", func() (int, error) {
+		ingredient, err := itx.ingredientRepository.FindByName(createRequest.Name)
+		return ingredient.Id, err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if id == 0 {
+		ingredient, err := itx.ingredientService.Create(createRequest)
+		return response.ImportActionCreate, ingredient.Id, err
+	}
+
+	ingredient, err := itx.ingredientRepository.Find(id)
+	if err != nil {
+		return "", 0, fmt.Errorf("ingredient %d not found", id)
+	}
+
+	if ingredient.Name == models.NormalizeName(createRequest.Name) {
+		return response.ImportActionUnchanged, ingredient.Id, nil
+	}
+
+	_, err = itx.ingredientService.Update(request.UpdateRequestIngredient{
+		Id:      ingredient.Id,
+		Name:    createRequest.Name,
+		Actor:   itx.actor,
+		Version: helper.Versions{ingredient.Version},
+	})
+	return response.ImportActionUpdate, ingredient.Id, err
+}
+
+func (itx importTx) importMenu(values map[string]string) (string, int, error) {
+	categoryId, err := importReference(values, "category", itx.findCategory)
+	if err != nil {
+		return "", 0, err
+	}
+
+	createRequest := request.CreateMenuRequest{Name: values["name"], CategoryId: categoryId, Actor: itx.actor}
+	err = itx.validate.Struct(createRequest)
+	if err != nil {
+		return "", 0, err
+	}
+
+	id, err := importLookup(values["id"], "menu", func() (int, error) {
+		menu, err := itx.menuRepository.FindInCategory(categoryId, createRequest.Name)
+		return menu.Id, err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if id == 0 {
+		menu, err := itx.menuService.Create(createRequest)
+		return response.ImportActionCreate, menu.Id, err
+	}
+
+	menu, err := itx.menuRepository.Find(id)
+	if err != nil {
+		return "", 0, fmt.Errorf("menu %d not found", id)
+	}
+
+	if menu.Name == models.NormalizeName(createRequest.Name) && menu.CategoryId == createRequest.CategoryId {
+		return response.ImportActionUnchanged, menu.Id, nil
+	}
+
+	_, err = itx.menuService.Update(request.UpdateMenuRequest{
+		Id:         menu.Id,
+		Name:       createRequest.Name,
+		CategoryId: createRequest.CategoryId,
+		Actor:      itx.actor,
+		Version:    helper.Versions{menu.Version},
+	})
+	return response.ImportActionUpdate, menu.Id, err
+}
+
+// importRecipe matches a recipe line by its menu and ingredient, so a sheet
+// row only ever changes the quantity of an existing line. A menu given by
+// name is looked up in the category of the row; without one the name must
+// belong to a single menu.
+func (itx importTx) importRecipe(values map[string]string) (string, int, error) {
+	menuId, err := importReference(values, "menu", func(name string) (int, error) {
+		return itx.findMenu(values, name)
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	ingredientId, err := importReference(values, "ingredient", func(name string) (int, error) {
+		ingredient, err := itx.ingredientRepository.FindByName(name)
+		return ingredient.Id, err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	createRequest := request.CreateRecipeRequest{MenuId: menuId, IngredientId: ingredientId, Qty: values["qty"], Actor: itx.actor}
+	err = itx.validate.Struct(createRequest)
+	if err != nil {
+		return "", 0, err
+	}
+
+	recipe, err := itx.recipeRepository.FindByMenuAndIngredient(menuId, ingredientId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		recipe, err = itx.recipeService.Create(createRequest)
+		return response.ImportActionCreate, recipe.Id, err
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if recipe.Qty == createRequest.Qty {
+		return response.ImportActionUnchanged, recipe.Id, nil
+	}
+
+	_, err = itx.recipeService.Update(request.UpdateRecipeRequest{
+		Id:           recipe.Id,
+		MenuId:       menuId,
+		IngredientId: ingredientId,
+		Qty:          createRequest.Qty,
+		Actor:        itx.actor,
+		Version:      helper.Versions{recipe.Version},
+	})
+	return response.ImportActionUpdate, recipe.Id, err
+}
+
+func (itx importTx) findCategory(name string) (int, error) {
+	category, err := itx.categoryRepository.FindByName(name)
+	return category.Id, err
+}
+
+func (itx importTx) findMenu(values map[string]string, name string) (int, error) {
+	if values["category_id"] != "" || values["category"] != "" {
+		categoryId, err := importReference(values, "category", itx.findCategory)
+		if err != nil {
+			return 0, err
+		}
+
+		menu, err := itx.menuRepository.FindInCategory(categoryId, name)
+		return menu.Id, err
+	}
+
+	listMenu, err := itx.menuRepository.AllByName(name)
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(listMenu) {
+	case 0:
+		return 0, gorm.ErrRecordNotFound
+	case 1:
+		return listMenu[0].Id, nil
+	default:
+		return 0, fmt.Errorf("menu %q is in %d categories, add a category or menu_id column", name, len(listMenu))
+	}
+}
+
+// importLookup returns the id of the row to update: the id column when it is
+// filled, otherwise the record found by name. Zero means a new record.
+func importLookup(idValue string, entity string, findByName func() (int, error)) (int, error) {
+	if idValue != "" {
+		id, err := strconv.Atoi(idValue)
+		if err != nil || id < 1 {
+			return 0, fmt.Errorf("%s id %q is not a valid id", entity, idValue)
+		}
+
+		return id, nil
+	}
+
+	id, err := findByName()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+
+	return id, err
+}
+
+// importReference resolves a column pointing at another record, either as
+// <entity>_id or as <entity> holding its name.
+func importReference(values map[string]string, entity string, findByName func(name string) (int, error)) (int, error) {
+	idValue := values[entity+"_id"]
+	if idValue != "" {
+		id, err := strconv.Atoi(idValue)
+		if err != nil || id < 1 {
+			return 0, fmt.Errorf("%s_id %q is not a valid id", entity, idValue)
+		}
+
+		return id, nil
+	}
+
+	name := values[entity]
+	if name == "" {
+		return 0, fmt.Errorf("%s or %s_id is required", entity, entity)
+	}
+
+	id, err := findByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("%s %q not found", entity, name)
+	}
+
+	return id, err
+}
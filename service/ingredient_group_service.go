@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrStorageTempRange     = errors.New("min_temp cannot be above max_temp")
+	ErrIngredientGroupInUse = errors.New("ingredient group still has ingredients")
+)
+
+type IngredientGroupService interface {
+	Create(createIngredientGroupRequest request.CreateIngredientGroupRequest) (response.IngredientGroupResponse, error)
+	Get(getIngredientGroupRequest request.GetIngredientGroupRequest) (response.IngredientGroupResponse, error)
+	GetAll(getAllIngredientGroupRequest request.GetAllIngredientGroupRequest) ([]response.IngredientGroupResponse, error)
+	Update(updateIngredientGroupRequest request.UpdateIngredientGroupRequest) (response.IngredientGroupResponse, error)
+	Delete(deleteIngredientGroupRequest request.DeleteIngredientGroupRequest) error
+	CountSheet() ([]response.CountSheetResponse, error)
+	WithTx(tx *gorm.DB) IngredientGroupService
+}
+
+type ingredientGroupService struct {
+	transactionManager        repository.TransactionManager
+	ingredientGroupRepository repository.IngredientGroupRepository
+	ingredientRepository      repository.IngredientRepository
+	auditService              AuditService
+}
+
+func NewIngredientGroupService(transactionManager repository.TransactionManager, ingredientGroupRepository repository.IngredientGroupRepository, ingredientRepository repository.IngredientRepository, auditService AuditService) IngredientGroupService {
+	return newIngredientGroupService(transactionManager, ingredientGroupRepository, ingredientRepository, auditService)
+}
+
+func newIngredientGroupService(transactionManager repository.TransactionManager, ingredientGroupRepository repository.IngredientGroupRepository, ingredientRepository repository.IngredientRepository, auditService AuditService) *ingredientGroupService {
+	return &ingredientGroupService{
+		transactionManager:        transactionManager,
+		ingredientGroupRepository: ingredientGroupRepository,
+		ingredientRepository:      ingredientRepository,
+		auditService:              auditService,
+	}
+}
+
+func (ingredientGroupService *ingredientGroupService) WithTx(tx *gorm.DB) IngredientGroupService {
+	return ingredientGroupService.withTx(tx)
+}
+
+func (ingredientGroupService *ingredientGroupService) withTx(tx *gorm.DB) *ingredientGroupService {
+	return newIngredientGroupService(repository.NewTransactionManager(tx), ingredientGroupService.ingredientGroupRepository.WithTx(tx), ingredientGroupService.ingredientRepository.WithTx(tx), ingredientGroupService.auditService.WithTx(tx))
+}
+
+func (ingredientGroupService *ingredientGroupService) Create(createIngredientGroupRequest request.CreateIngredientGroupRequest) (response.IngredientGroupResponse, error) {
+	res := response.IngredientGroupResponse{}
+
+	err := ingredientGroupService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = ingredientGroupService.withTx(tx).create(createIngredientGroupRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (ingredientGroupService *ingredientGroupService) create(createIngredientGroupRequest request.CreateIngredientGroupRequest) (response.IngredientGroupResponse, error) {
+	res := response.IngredientGroupResponse{}
+
+	group := models.IngredientGroup{}
+	group.Name = createIngredientGroupRequest.Name
+	group.Storage = createIngredientGroupRequest.Storage
+	group.MinTemp = createIngredientGroupRequest.MinTemp
+	group.MaxTemp = createIngredientGroupRequest.MaxTemp
+
+	err := checkStorageTemp(group)
+	if err != nil {
+		return res, err
+	}
+
+	group, err = ingredientGroupService.ingredientGroupRepository.Create(group)
+	if err != nil {
+		return res, err
+	}
+
+	err = ingredientGroupService.auditService.Record(createIngredientGroupRequest.Actor, "ingredient_group", group.Id, models.AuditActionCreate, nil, group)
+	if err != nil {
+		return res, err
+	}
+
+	return newIngredientGroupResponse(group), nil
+}
+
+func (ingredientGroupService *ingredientGroupService) Get(getIngredientGroupRequest request.GetIngredientGroupRequest) (response.IngredientGroupResponse, error) {
+	group, err := ingredientGroupService.ingredientGroupRepository.Find(getIngredientGroupRequest.Id)
+	if err != nil {
+		return response.IngredientGroupResponse{}, err
+	}
+
+	return newIngredientGroupResponse(group), nil
+}
+
+func (ingredientGroupService *ingredientGroupService) GetAll(getAllIngredientGroupRequest request.GetAllIngredientGroupRequest) ([]response.IngredientGroupResponse, error) {
+	listGroupResponse := []response.IngredientGroupResponse{}
+
+	listGroup, err := ingredientGroupService.ingredientGroupRepository.All(getAllIngredientGroupRequest.Name)
+	if err != nil {
+		return listGroupResponse, err
+	}
+
+	for _, group := range listGroup {
+		listGroupResponse = append(listGroupResponse, newIngredientGroupResponse(group))
+	}
+
+	return listGroupResponse, nil
+}
+
+func (ingredientGroupService *ingredientGroupService) Update(updateIngredientGroupRequest request.UpdateIngredientGroupRequest) (response.IngredientGroupResponse, error) {
+	res := response.IngredientGroupResponse{}
+
+	err := ingredientGroupService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = ingredientGroupService.withTx(tx).update(updateIngredientGroupRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (ingredientGroupService *ingredientGroupService) update(updateIngredientGroupRequest request.UpdateIngredientGroupRequest) (response.IngredientGroupResponse, error) {
+	res := response.IngredientGroupResponse{}
+
+	group, err := ingredientGroupService.ingredientGroupRepository.Find(updateIngredientGroupRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(updateIngredientGroupRequest.Version, group.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	before := group
+	group.Name = updateIngredientGroupRequest.Name
+	group.Storage = updateIngredientGroupRequest.Storage
+	group.MinTemp = updateIngredientGroupRequest.MinTemp
+	group.MaxTemp = updateIngredientGroupRequest.MaxTemp
+
+	err = checkStorageTemp(group)
+	if err != nil {
+		return res, err
+	}
+
+	group, err = ingredientGroupService.ingredientGroupRepository.Update(group)
+	if err != nil {
+		return res, err
+	}
+
+	err = ingredientGroupService.auditService.Record(updateIngredientGroupRequest.Actor, "ingredient_group", group.Id, models.AuditActionUpdate, before, group)
+	if err != nil {
+		return res, err
+	}
+
+	return newIngredientGroupResponse(group), nil
+}
+
+func (ingredientGroupService *ingredientGroupService) Delete(deleteIngredientGroupRequest request.DeleteIngredientGroupRequest) error {
+	return ingredientGroupService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		return ingredientGroupService.withTx(tx).delete(deleteIngredientGroupRequest)
+	})
+}
+
+func (ingredientGroupService *ingredientGroupService) delete(deleteIngredientGroupRequest request.DeleteIngredientGroupRequest) error {
+	group, err := ingredientGroupService.ingredientGroupRepository.Find(deleteIngredientGroupRequest.Id)
+	if err != nil {
+		return err
+	}
+
+	if !versionMatches(deleteIngredientGroupRequest.Version, group.Version) {
+		return repository.ErrVersionConflict
+	}
+
+	count, err := ingredientGroupService.ingredientGroupRepository.CountIngredients(group.Id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrIngredientGroupInUse
+	}
+
+	err = ingredientGroupService.ingredientGroupRepository.Delete(group)
+	if err != nil {
+		return err
+	}
+
+	return ingredientGroupService.auditService.Record(deleteIngredientGroupRequest.Actor, "ingredient_group", group.Id, models.AuditActionDelete, group, nil)
+}
+
+// storageOrder is the order a stock count walks the store: freezers first,
+// then the cold room, then the dry store.
+var storageOrder = map[string]int{models.StorageFrozen: 0, models.StorageChilled: 1, models.StorageAmbient: 2}
+
+// CountSheet lists every ingredient by group, in the order the store is
+// counted, so a stock count can be taken one storage area at a time.
+func (ingredientGroupService *ingredientGroupService) CountSheet() ([]response.CountSheetResponse, error) {
+	listSheet := []response.CountSheetResponse{}
+
+	listGroup, err := ingredientGroupService.ingredientGroupRepository.All("")
+	if err != nil {
+		return listSheet, err
+	}
+
+	listIngredient, err := ingredientGroupService.ingredientRepository.All("", time.Time{}, 0)
+	if err != nil {
+		return listSheet, err
+	}
+
+	sort.SliceStable(listGroup, func(i, j int) bool {
+		return storageOrder[listGroup[i].Storage] < storageOrder[listGroup[j].Storage]
+	})
+
+	sheets := map[int]*response.CountSheetResponse{}
+	for _, group := range listGroup {
+		id := group.Id
+		listSheet = append(listSheet, response.CountSheetResponse{Id: &id, Name: group.Name, Storage: group.Storage, Ingredients: []response.CountSheetLineResponse{}})
+	}
+	listSheet = append(listSheet, response.CountSheetResponse{Name: "ungrouped", Ingredients: []response.CountSheetLineResponse{}})
+	for i := range listSheet {
+		if listSheet[i].Id != nil {
+			sheets[*listSheet[i].Id] = &listSheet[i]
+		}
+	}
+
+	sort.SliceStable(listIngredient, func(i, j int) bool {
+		return strings.ToLower(listIngredient[i].Name) < strings.ToLower(listIngredient[j].Name)
+	})
+	for _, ingredient := range listIngredient {
+		sheet := &listSheet[len(listSheet)-1]
+		if ingredient.IngredientGroupId != nil && sheets[*ingredient.IngredientGroupId] != nil {
+			sheet = sheets[*ingredient.IngredientGroupId]
+		}
+		sheet.Ingredients = append(sheet.Ingredients, response.CountSheetLineResponse{Id: ingredient.Id, Name: ingredient.Name})
+	}
+
+	if len(listSheet[len(listSheet)-1].Ingredients) == 0 {
+		listSheet = listSheet[:len(listSheet)-1]
+	}
+
+	return listSheet, nil
+}
+
+func checkStorageTemp(group models.IngredientGroup) error {
+	if group.MinTemp != nil && group.MaxTemp != nil && *group.MinTemp > *group.MaxTemp {
+		return ErrStorageTempRange
+	}
+
+	return nil
+}
+
+func newIngredientGroupResponse(group models.IngredientGroup) response.IngredientGroupResponse {
+	return response.IngredientGroupResponse{
+		Id:        group.Id,
+		Name:      group.Name,
+		Storage:   group.Storage,
+		MinTemp:   group.MinTemp,
+		MaxTemp:   group.MaxTemp,
+		Version:   group.Version,
+		CreatedAt: group.CreatedAt,
+		UpdatedAt: group.UpdatedAt,
+	}
+}
@@ -5,37 +5,109 @@ import (
 	"github.com/erp_app/repository"
 	"github.com/erp_app/request"
 	"github.com/erp_app/response"
+	"gorm.io/gorm"
 )
 
 type IngredientService interface {
 	Create(createRequestIngredient request.CreateRequestIngredient) (response.IngredientResponse, error)
 	Get(getDetailRequestIngredient request.GetDetailRequestIngredient) (response.IngredientResponse, error)
 	GetAll(getAllRequestIngredient request.GetAllRequestIngredient) ([]response.IngredientResponse, error)
+	Stream(getAllRequestIngredient request.GetAllRequestIngredient, fn func(res response.IngredientResponse) error) error
 	Update(updateRequestIngredient request.UpdateRequestIngredient) (response.IngredientResponse, error)
 	Delete(deleteRequestIngredient request.DeleteRequestIngredient) error
+	WithTx(tx *gorm.DB) IngredientService
 }
 
 type ingredientService struct {
-	ingredientRepository repository.IngredientRepository
+	transactionManager        repository.TransactionManager
+	ingredientRepository      repository.IngredientRepository
+	ingredientGroupRepository repository.IngredientGroupRepository
+	auditService              AuditService
 }
 
-func NewIngredientService(ingredientRepository repository.IngredientRepository) IngredientService {
-	return &ingredientService{ingredientRepository: ingredientRepository}
+func NewIngredientService(transactionManager repository.TransactionManager, ingredientRepository repository.IngredientRepository, ingredientGroupRepository repository.IngredientGroupRepository, auditService AuditService) IngredientService {
+	return newIngredientService(transactionManager, ingredientRepository, ingredientGroupRepository, auditService)
+}
+
+func newIngredientService(transactionManager repository.TransactionManager, ingredientRepository repository.IngredientRepository, ingredientGroupRepository repository.IngredientGroupRepository, auditService AuditService) *ingredientService {
+	return &ingredientService{
+		transactionManager:        transactionManager,
+		ingredientRepository:      ingredientRepository,
+		ingredientGroupRepository: ingredientGroupRepository,
+		auditService:              auditService,
+	}
+}
+
+func (ingredientService *ingredientService) WithTx(tx *gorm.DB) IngredientService {
+	return ingredientService.withTx(tx)
+}
+
+func (ingredientService *ingredientService) withTx(tx *gorm.DB) *ingredientService {
+	return newIngredientService(repository.NewTransactionManager(tx), ingredientService.ingredientRepository.WithTx(tx), ingredientService.ingredientGroupRepository.WithTx(tx), ingredientService.auditService.WithTx(tx))
 }
 
 func (ingredientService *ingredientService) Create(createRequestIngredient request.CreateRequestIngredient) (response.IngredientResponse, error) {
 	res := response.IngredientResponse{}
 
+	err := ingredientService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = ingredientService.withTx(tx).create(createRequestIngredient)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (ingredientService *ingredientService) create(createRequestIngredient request.CreateRequestIngredient) (response.IngredientResponse, error) {
+	res := response.IngredientResponse{}
+
+	findName := ingredientService.findName(createRequestIngredient.Name)
+	err := uniqueName("ingredient", createRequestIngredient.Name, 0, findName)
+	if err != nil {
+		return res, err
+	}
+
 	ingredient := models.Ingredient{}
 	ingredient.Name = createRequestIngredient.Name
+	ingredient.YieldPercent = createRequestIngredient.YieldPercent
+	ingredient.Allergens = newIngredientAllergens(createRequestIngredient.Allergens)
+	ingredient.Vegan = createRequestIngredient.Vegan
+	ingredient.Halal = createRequestIngredient.Halal
+	ingredient.GlutenFree = createRequestIngredient.GlutenFree
+	ingredient.Calories = createRequestIngredient.Calories
+	ingredient.Protein = createRequestIngredient.Protein
+	ingredient.Fat = createRequestIngredient.Fat
+	ingredient.Carbs = createRequestIngredient.Carbs
+	ingredient.Sodium = createRequestIngredient.Sodium
+
+	if createRequestIngredient.IngredientGroupId != nil {
+		group, err := ingredientService.ingredientGroupRepository.Find(*createRequestIngredient.IngredientGroupId)
+		if err != nil {
+			return res, err
+		}
+		ingredient.IngredientGroupId = &group.Id
+		ingredient.IngredientGroup = &group
+	}
 
-	ingredient, err := ingredientService.ingredientRepository.Create(ingredient)
+	err = checkDietary(ingredient)
 	if err != nil {
 		return res, err
 	}
 
-	res.Id = ingredient.Id
-	res.Name = ingredient.Name
+	ingredient, err = ingredientService.ingredientRepository.Create(ingredient)
+	if err != nil {
+		return res, nameConflict(err, "ingredient", createRequestIngredient.Name, 0, findName)
+	}
+
+	err = ingredientService.auditService.Record(createRequestIngredient.Actor, "ingredient", ingredient.Id, models.AuditActionCreate, nil, ingredient)
+	if err != nil {
+		return res, err
+	}
+
+	res = newIngredientResponse(ingredient)
 
 	return res, nil
 }
@@ -47,24 +119,26 @@ func (ingredientService *ingredientService) Get(getDetailRequestIngredient reque
 		return res, err
 	}
 
-	res.Id = ingredient.Id
-	res.Name = ingredient.Name
+	res = newIngredientResponse(ingredient)
 
 	return res, nil
 }
 
 func (ingredientService *ingredientService) GetAll(getAllRequestIngredient request.GetAllRequestIngredient) ([]response.IngredientResponse, error) {
 	var listRes []response.IngredientResponse
-	listIngredient, err := ingredientService.ingredientRepository.All(getAllRequestIngredient.Name)
+	updatedSince, err := parseTimestamp(getAllRequestIngredient.UpdatedSince)
+	if err != nil {
+		return listRes, err
+	}
+
+	listIngredient, err := ingredientService.ingredientRepository.All(getAllRequestIngredient.Name, updatedSince, getAllRequestIngredient.IngredientGroupId)
 	if err != nil {
 		return listRes, err
 	}
 
 	if len(listIngredient) > 0 {
 		for _, ingredient := range listIngredient {
-			res := response.IngredientResponse{}
-			res.Id = ingredient.Id
-			res.Name = ingredient.Name
+			res := newIngredientResponse(ingredient)
 
 			listRes = append(listRes, res)
 		}
@@ -74,37 +148,191 @@ func (ingredientService *ingredientService) GetAll(getAllRequestIngredient reque
 
 }
 
+func (ingredientService *ingredientService) Stream(getAllRequestIngredient request.GetAllRequestIngredient, fn func(res response.IngredientResponse) error) error {
+	updatedSince, err := parseTimestamp(getAllRequestIngredient.UpdatedSince)
+	if err != nil {
+		return err
+	}
+
+	return ingredientService.ingredientRepository.Each(getAllRequestIngredient.Name, updatedSince, getAllRequestIngredient.IngredientGroupId, func(ingredient models.Ingredient) error {
+		return fn(newIngredientResponse(ingredient))
+	})
+}
+
 func (ingredientService *ingredientService) Update(updateRequestIngredient request.UpdateRequestIngredient) (response.IngredientResponse, error) {
 	res := response.IngredientResponse{}
 
+	err := ingredientService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = ingredientService.withTx(tx).update(updateRequestIngredient)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (ingredientService *ingredientService) update(updateRequestIngredient request.UpdateRequestIngredient) (response.IngredientResponse, error) {
+	res := response.IngredientResponse{}
+
 	ingredient, err := ingredientService.ingredientRepository.Find(updateRequestIngredient.Id)
 	if err != nil {
 		return res, err
 	}
 
+	if !versionMatches(updateRequestIngredient.Version, ingredient.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	findName := ingredientService.findName(updateRequestIngredient.Name)
+	if !models.SameNameKey(ingredient.NameKey, ingredient.Id, updateRequestIngredient.Name) {
+		err = uniqueName("ingredient", updateRequestIngredient.Name, ingredient.Id, findName)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	before := ingredient
 	ingredient.Name = updateRequestIngredient.Name
+	if updateRequestIngredient.YieldPercent != nil {
+		ingredient.YieldPercent = *updateRequestIngredient.YieldPercent
+	}
+	if updateRequestIngredient.Allergens != nil {
+		ingredient.Allergens = newIngredientAllergens(*updateRequestIngredient.Allergens)
+	}
+	if updateRequestIngredient.Vegan != nil {
+		ingredient.Vegan = *updateRequestIngredient.Vegan
+	}
+	if updateRequestIngredient.Halal != nil {
+		ingredient.Halal = *updateRequestIngredient.Halal
+	}
+	if updateRequestIngredient.GlutenFree != nil {
+		ingredient.GlutenFree = *updateRequestIngredient.GlutenFree
+	}
+	for _, nutrient := range updateRequestIngredient.ClearNutrients {
+		switch nutrient {
+		case "calories":
+			ingredient.Calories = nil
+		case "protein":
+			ingredient.Protein = nil
+		case "fat":
+			ingredient.Fat = nil
+		case "carbs":
+			ingredient.Carbs = nil
+		case "sodium":
+			ingredient.Sodium = nil
+		}
+	}
+	if updateRequestIngredient.Calories != nil {
+		ingredient.Calories = updateRequestIngredient.Calories
+	}
+	if updateRequestIngredient.Protein != nil {
+		ingredient.Protein = updateRequestIngredient.Protein
+	}
+	if updateRequestIngredient.Fat != nil {
+		ingredient.Fat = updateRequestIngredient.Fat
+	}
+	if updateRequestIngredient.Carbs != nil {
+		ingredient.Carbs = updateRequestIngredient.Carbs
+	}
+	if updateRequestIngredient.Sodium != nil {
+		ingredient.Sodium = updateRequestIngredient.Sodium
+	}
+	if updateRequestIngredient.IngredientGroupId != nil {
+		ingredient.IngredientGroupId = nil
+		ingredient.IngredientGroup = nil
+		if *updateRequestIngredient.IngredientGroupId != 0 {
+			group, err := ingredientService.ingredientGroupRepository.Find(*updateRequestIngredient.IngredientGroupId)
+			if err != nil {
+				return res, err
+			}
+			ingredient.IngredientGroupId = &group.Id
+			ingredient.IngredientGroup = &group
+		}
+	}
+
+	err = checkDietary(ingredient)
+	if err != nil {
+		return res, err
+	}
 
 	ingredient, err = ingredientService.ingredientRepository.Update(ingredient)
+	if err != nil {
+		return res, nameConflict(err, "ingredient", updateRequestIngredient.Name, ingredient.Id, findName)
+	}
+
+	err = ingredientService.auditService.Record(updateRequestIngredient.Actor, "ingredient", ingredient.Id, models.AuditActionUpdate, before, ingredient)
 	if err != nil {
 		return res, err
 	}
 
-	res.Id = ingredient.Id
-	res.Name = ingredient.Name
+	res = newIngredientResponse(ingredient)
 
 	return res, nil
 }
 
 func (ingredientService *ingredientService) Delete(deleteRequestIngredient request.DeleteRequestIngredient) error {
+	return ingredientService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		return ingredientService.withTx(tx).delete(deleteRequestIngredient)
+	})
+}
+
+func (ingredientService *ingredientService) delete(deleteRequestIngredient request.DeleteRequestIngredient) error {
 	ingredient, err := ingredientService.ingredientRepository.Find(deleteRequestIngredient.Id)
 	if err != nil {
 		return err
 	}
 
+	if !versionMatches(deleteRequestIngredient.Version, ingredient.Version) {
+		return repository.ErrVersionConflict
+	}
+
 	err = ingredientService.ingredientRepository.Delete(ingredient)
 	if err != nil {
 		return err
 	}
 
+	err = ingredientService.auditService.Record(deleteRequestIngredient.Actor, "ingredient", ingredient.Id, models.AuditActionDelete, ingredient, nil)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+func (ingredientService *ingredientService) findName(name string) func() (int, error) {
+	return func() (int, error) {
+		ingredient, err := ingredientService.ingredientRepository.FindByName(name)
+		return ingredient.Id, err
+	}
+}
+
+func newIngredientResponse(ingredient models.Ingredient) response.IngredientResponse {
+	var group *response.IngredientGroupResponse
+	if ingredient.IngredientGroup != nil {
+		groupResponse := newIngredientGroupResponse(*ingredient.IngredientGroup)
+		group = &groupResponse
+	}
+
+	return response.IngredientResponse{
+		Id:                ingredient.Id,
+		Name:              ingredient.Name,
+		IngredientGroupId: ingredient.IngredientGroupId,
+		IngredientGroup:   group,
+		YieldPercent:      ingredient.YieldPercent,
+		Allergens:         ingredientAllergens(ingredient),
+		Vegan:             ingredient.Vegan,
+		Halal:             ingredient.Halal,
+		GlutenFree:        ingredient.GlutenFree,
+		Calories:          ingredient.Calories,
+		Protein:           ingredient.Protein,
+		Fat:               ingredient.Fat,
+		Carbs:             ingredient.Carbs,
+		Sodium:            ingredient.Sodium,
+		Version:           ingredient.Version,
+		CreatedAt:         ingredient.CreatedAt,
+		UpdatedAt:         ingredient.UpdatedAt,
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSwapNotInMenu     = errors.New("swapped ingredient is not used by the menu")
+	ErrSwapDuplicate     = errors.New("an ingredient can be swapped only once")
+	ErrSwapDuplicateLine = errors.New("swap would put the same ingredient twice in the recipe")
+)
+
+type MenuCloneService interface {
+	Clone(cloneMenuRequest request.CloneMenuRequest) (response.MenuResponse, error)
+	WithTx(tx *gorm.DB) MenuCloneService
+}
+
+type menuCloneService struct {
+	transactionManager   repository.TransactionManager
+	menuRepository       repository.MenuRepository
+	categoryRepository   repository.CategoryRepository
+	ingredientRepository repository.IngredientRepository
+	recipeRepository     repository.RecipeRepository
+	stepRepository       repository.StepRepository
+	auditService         AuditService
+	storage              libraries.Storage
+}
+
+func NewMenuCloneService(transactionManager repository.TransactionManager, menuRepository repository.MenuRepository, categoryRepository repository.CategoryRepository, ingredientRepository repository.IngredientRepository, recipeRepository repository.RecipeRepository, stepRepository repository.StepRepository, auditService AuditService, storage libraries.Storage) MenuCloneService {
+	return newMenuCloneService(transactionManager, menuRepository, categoryRepository, ingredientRepository, recipeRepository, stepRepository, auditService, storage)
+}
+
+func newMenuCloneService(transactionManager repository.TransactionManager, menuRepository repository.MenuRepository, categoryRepository repository.CategoryRepository, ingredientRepository repository.IngredientRepository, recipeRepository repository.RecipeRepository, stepRepository repository.StepRepository, auditService AuditService, storage libraries.Storage) *menuCloneService {
+	return &menuCloneService{
+		transactionManager:   transactionManager,
+		menuRepository:       menuRepository,
+		categoryRepository:   categoryRepository,
+		ingredientRepository: ingredientRepository,
+		recipeRepository:     recipeRepository,
+		stepRepository:       stepRepository,
+		auditService:         auditService,
+		storage:              storage,
+	}
+}
+
+func (menuCloneService *menuCloneService) WithTx(tx *gorm.DB) MenuCloneService {
+	return menuCloneService.withTx(tx)
+}
+
+func (menuCloneService *menuCloneService) withTx(tx *gorm.DB) *menuCloneService {
+	return newMenuCloneService(repository.NewTransactionManager(tx), menuCloneService.menuRepository.WithTx(tx), menuCloneService.categoryRepository.WithTx(tx), menuCloneService.ingredientRepository.WithTx(tx), menuCloneService.recipeRepository.WithTx(tx), menuCloneService.stepRepository.WithTx(tx), menuCloneService.auditService.WithTx(tx), menuCloneService.storage)
+}
+
+// Clone copies a menu with its recipe lines and steps in one transaction.
+func (menuCloneService *menuCloneService) Clone(cloneMenuRequest request.CloneMenuRequest) (response.MenuResponse, error) {
+	res := response.MenuResponse{}
+
+	err := menuCloneService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		menu, err := menuCloneService.withTx(tx).clone(cloneMenuRequest)
+		if err != nil {
+			return err
+		}
+
+		res = newMenuDetailResponse(menu, menuCloneService.storage)
+		for _, step := range menu.Steps {
+			res.Steps = append(res.Steps, newStepResponse(step))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.MenuResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (menuCloneService *menuCloneService) clone(cloneMenuRequest request.CloneMenuRequest) (models.Menu, error) {
+	original, err := menuCloneService.menuRepository.Find(cloneMenuRequest.Id)
+	if err != nil {
+		return original, err
+	}
+
+	swaps, err := menuCloneService.swaps(original, cloneMenuRequest.Swaps)
+	if err != nil {
+		return original, err
+	}
+
+	menu := models.Menu{}
+	menu.Name = cloneMenuRequest.Name
+	menu.CategoryId = original.CategoryId
+	menu.YieldQty = original.YieldQty
+	menu.Portions = original.Portions
+
+	if cloneMenuRequest.CategoryId != 0 {
+		category, err := menuCloneService.categoryRepository.Find(cloneMenuRequest.CategoryId)
+		if err != nil {
+			return menu, err
+		}
+		menu.CategoryId = category.Id
+	}
+
+	factor := 1.0
+	if cloneMenuRequest.Portions != 0 {
+		portions := original.Portions
+		if portions < 1 {
+			portions = 1
+		}
+		factor = float64(cloneMenuRequest.Portions) / float64(portions)
+		menu.YieldQty = scaleQty(original.YieldQty, factor)
+		menu.Portions = cloneMenuRequest.Portions
+	}
+
+	findName := findMenuName(menuCloneService.menuRepository, menu.CategoryId, menu.Name)
+	err = uniqueName("menu", menu.Name, 0, findName)
+	if err != nil {
+		return menu, err
+	}
+
+	menu, err = menuCloneService.menuRepository.Create(menu)
+	if err != nil {
+		return menu, nameConflict(err, "menu", cloneMenuRequest.Name, 0, findName)
+	}
+
+	err = menuCloneService.auditService.Record(cloneMenuRequest.Actor, "menu", menu.Id, models.AuditActionCreate, nil, menu)
+	if err != nil {
+		return menu, err
+	}
+
+	for _, line := range original.Ingredients {
+		recipe := models.MenuIngredient{}
+		recipe.MenuId = menu.Id
+		recipe.IngredientId = line.IngredientId
+		recipe.Qty = line.Qty
+		recipe.YieldPercent = line.YieldPercent
+		// the yield override was about the old ingredient, the new one
+		// starts from its own yield
+		if ingredientId, ok := swaps[line.IngredientId]; ok {
+			recipe.IngredientId = ingredientId
+			recipe.YieldPercent = nil
+		}
+		if factor != 1 {
+			recipe.Qty = scaleQty(line.Qty, factor)
+		}
+
+		recipe, err = menuCloneService.recipeRepository.Create(recipe)
+		if err != nil {
+			return menu, err
+		}
+
+		err = menuCloneService.auditService.Record(cloneMenuRequest.Actor, "recipe", recipe.Id, models.AuditActionCreate, nil, recipe)
+		if err != nil {
+			return menu, err
+		}
+	}
+
+	for _, original := range original.Steps {
+		step := models.MenuStep{}
+		step.MenuId = menu.Id
+		step.Text = original.Text
+		step.DurationMinutes = original.DurationMinutes
+		step.Station = original.Station
+		step.ImageUrl = original.ImageUrl
+
+		step, err = menuCloneService.stepRepository.Create(step)
+		if err != nil {
+			return menu, err
+		}
+
+		err = menuCloneService.auditService.Record(cloneMenuRequest.Actor, "menu_step", step.Id, models.AuditActionCreate, nil, step)
+		if err != nil {
+			return menu, err
+		}
+	}
+
+	return menuCloneService.menuRepository.Find(menu.Id)
+}
+
+// swaps maps the ingredients to replace to their replacement, making sure the
+// copied recipe still holds every ingredient once.
+func (menuCloneService *menuCloneService) swaps(menu models.Menu, listSwap []request.CloneMenuSwap) (map[int]int, error) {
+	swaps := map[int]int{}
+	for _, swap := range listSwap {
+		if _, ok := swaps[swap.From]; ok {
+			return swaps, ErrSwapDuplicate
+		}
+
+		_, err := menuCloneService.ingredientRepository.Find(swap.To)
+		if err != nil {
+			return swaps, err
+		}
+
+		swaps[swap.From] = swap.To
+	}
+
+	used := map[int]bool{}
+	for _, recipe := range menu.Ingredients {
+		used[recipe.IngredientId] = true
+	}
+
+	lines := map[int]bool{}
+	for from := range swaps {
+		if !used[from] {
+			return swaps, ErrSwapNotInMenu
+		}
+	}
+	for _, recipe := range menu.Ingredients {
+		ingredientId := recipe.IngredientId
+		if to, ok := swaps[ingredientId]; ok {
+			ingredientId = to
+		}
+		if lines[ingredientId] {
+			return swaps, ErrSwapDuplicateLine
+		}
+		lines[ingredientId] = true
+	}
+
+	return swaps, nil
+}
@@ -2,10 +2,15 @@ package service
 
 import (
 	"fmt"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
 	"github.com/erp_app/request"
 	"github.com/erp_app/response"
+	"gorm.io/gorm"
+	"math"
+	"time"
 )
 
 type MenuService interface {
@@ -13,36 +18,96 @@ type MenuService interface {
 	Update(updateMenuRequest request.UpdateMenuRequest) (response.MenuResponse, error)
 	Get(getMenuRequest request.GetMenuRequest) (response.MenuResponse, error)
 	GetAll(getAllMenuRequest request.GetAllMenuRequest) ([]response.MenuResponse, error)
+	Stream(getAllMenuRequest request.GetAllMenuRequest, fn func(res response.MenuResponse) error) error
 	Delete(deleteRequestIngredient request.DeleteMenuRequest) error
+	RecipeCard(getMenuRequest request.GetMenuRequest) (helper.RecipeCard, error)
+	MenuBook() ([]helper.RecipeCard, error)
+	Scale(scaleMenuRequest request.ScaleMenuRequest) (response.ScaledMenuResponse, error)
+	Reorder(reorderMenuRequest request.ReorderMenuRequest) ([]response.MenuResponse, error)
+	WithTx(tx *gorm.DB) MenuService
 }
 
 type menuService struct {
+	transactionManager repository.TransactionManager
 	menuRepository     repository.MenuRepository
 	categoryRepository repository.CategoryRepository
+	auditService       AuditService
+	storage            libraries.Storage
 }
 
-func NewMenuService(menuRepository repository.MenuRepository, categoryRepository repository.CategoryRepository) MenuService {
+func NewMenuService(transactionManager repository.TransactionManager, menuRepository repository.MenuRepository, categoryRepository repository.CategoryRepository, auditService AuditService, storage libraries.Storage) MenuService {
+	return newMenuService(transactionManager, menuRepository, categoryRepository, auditService, storage)
+}
+
+func newMenuService(transactionManager repository.TransactionManager, menuRepository repository.MenuRepository, categoryRepository repository.CategoryRepository, auditService AuditService, storage libraries.Storage) *menuService {
 	return &menuService{
+		transactionManager: transactionManager,
 		menuRepository:     menuRepository,
 		categoryRepository: categoryRepository,
+		auditService:       auditService,
+		storage:            storage,
 	}
 }
 
+func (menuService *menuService) WithTx(tx *gorm.DB) MenuService {
+	return menuService.withTx(tx)
+}
+
+func (menuService *menuService) withTx(tx *gorm.DB) *menuService {
+	return newMenuService(repository.NewTransactionManager(tx), menuService.menuRepository.WithTx(tx), menuService.categoryRepository.WithTx(tx), menuService.auditService.WithTx(tx), menuService.storage)
+}
+
 func (menuService *menuService) Delete(deleteMenuRequest request.DeleteMenuRequest) error {
+	return menuService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		return menuService.withTx(tx).delete(deleteMenuRequest)
+	})
+}
+
+func (menuService *menuService) delete(deleteMenuRequest request.DeleteMenuRequest) error {
 	menu, err := menuService.menuRepository.Find(deleteMenuRequest.Id)
 	if err != nil {
 		return err
 	}
 
+	if !versionMatches(deleteMenuRequest.Version, menu.Version) {
+		return repository.ErrVersionConflict
+	}
+
 	err = menuService.menuRepository.Delete(menu)
 	if err != nil {
 		return err
 	}
 
+	err = menuService.auditService.Record(deleteMenuRequest.Actor, "menu", menu.Id, models.AuditActionDelete, menu, nil)
+	if err != nil {
+		return err
+	}
+
+	// The files go only once the delete is committed; a rollback keeps the
+	// record and it still needs them.
+	menuService.transactionManager.AfterCommit(func() {
+		removeImage(menuService.storage, menu.ImageKey)
+	})
+
 	return nil
 }
 
 func (menuService *menuService) Create(createMenuRequest request.CreateMenuRequest) (response.MenuResponse, error) {
+	res := response.MenuResponse{}
+
+	err := menuService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = menuService.withTx(tx).create(createMenuRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (menuService *menuService) create(createMenuRequest request.CreateMenuRequest) (response.MenuResponse, error) {
 
 	res := response.MenuResponse{}
 
@@ -51,23 +116,28 @@ func (menuService *menuService) Create(createMenuRequest request.CreateMenuReque
 		return res, err
 	}
 
+	findName := findMenuName(menuService.menuRepository, category.Id, createMenuRequest.Name)
+	err = uniqueName("menu", createMenuRequest.Name, 0, findName)
+	if err != nil {
+		return res, err
+	}
+
 	menu := models.Menu{}
 	menu.Name = createMenuRequest.Name
 	menu.CategoryId = createMenuRequest.CategoryId
+	menu.YieldQty = createMenuRequest.YieldQty
+	menu.Portions = createMenuRequest.Portions
 	menu, err = menuService.menuRepository.Create(menu)
 	if err != nil {
-		return res, err
+		return res, nameConflict(err, "menu", createMenuRequest.Name, 0, findName)
 	}
 
-	categoryResponse := response.CategoryResponse{
-		Id:   category.Id,
-		Name: category.Name,
+	err = menuService.auditService.Record(createMenuRequest.Actor, "menu", menu.Id, models.AuditActionCreate, nil, menu)
+	if err != nil {
+		return res, err
 	}
 
-	res.Id = menu.Id
-	res.Name = menu.Name
-	res.CategoryId = menu.CategoryId
-	res.Category = categoryResponse
+	res = newMenuResponse(menu, category, menuService.storage)
 
 	return res, nil
 }
@@ -75,33 +145,74 @@ func (menuService *menuService) Create(createMenuRequest request.CreateMenuReque
 func (menuService *menuService) Update(updateMenuRequest request.UpdateMenuRequest) (response.MenuResponse, error) {
 	res := response.MenuResponse{}
 
+	err := menuService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = menuService.withTx(tx).update(updateMenuRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (menuService *menuService) update(updateMenuRequest request.UpdateMenuRequest) (response.MenuResponse, error) {
+	res := response.MenuResponse{}
+
 	menu, err := menuService.menuRepository.Find(updateMenuRequest.Id)
 	if err != nil {
 		return res, err
 	}
 
+	if !versionMatches(updateMenuRequest.Version, menu.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
 	category, err := menuService.categoryRepository.Find(updateMenuRequest.CategoryId)
 	if err != nil {
 		return res, err
 	}
 
+	findName := findMenuName(menuService.menuRepository, category.Id, updateMenuRequest.Name)
+	if !models.SameNameKey(menu.NameKey, menu.Id, updateMenuRequest.Name) || category.Id != menu.CategoryId {
+		err = uniqueName("menu", updateMenuRequest.Name, menu.Id, findName)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	before := menu
 	menu.Name = updateMenuRequest.Name
 	menu.CategoryId = updateMenuRequest.CategoryId
+	if updateMenuRequest.YieldQty != nil {
+		menu.YieldQty = *updateMenuRequest.YieldQty
+	}
+	if updateMenuRequest.Portions != nil {
+		menu.Portions = *updateMenuRequest.Portions
+	}
+
+	// a menu moved to another category goes to the end of it
+	if menu.CategoryId != before.CategoryId {
+		menu.Position, err = menuService.menuRepository.NextPosition(menu.CategoryId)
+		if err != nil {
+			return res, err
+		}
+	}
 
 	menu, err = menuService.menuRepository.Update(menu)
 	if err != nil {
-		return res, err
+		return res, nameConflict(err, "menu", updateMenuRequest.Name, menu.Id, findName)
 	}
 
-	categoryResponse := response.CategoryResponse{
-		Id:   category.Id,
-		Name: category.Name,
+	err = menuService.auditService.Record(updateMenuRequest.Actor, "menu", menu.Id, models.AuditActionUpdate, before, menu)
+	if err != nil {
+		return res, err
 	}
 
-	res.Id = menu.Id
-	res.Name = menu.Name
-	res.CategoryId = menu.CategoryId
-	res.Category = categoryResponse
+	res = newMenuResponse(menu, category, menuService.storage)
+	rollUpDietary(&res, menu)
+	rollUpNutrition(&res, menu)
 
 	return res, nil
 }
@@ -113,62 +224,289 @@ func (menuService *menuService) Get(getMenuRequest request.GetMenuRequest) (resp
 		return res, err
 	}
 
-	categoryRes := response.CategoryResponse{
-		Id:   menu.Category.Id,
-		Name: menu.Category.Name,
+	res = newMenuResponse(menu, menu.Category, menuService.storage)
+	rollUpDietary(&res, menu)
+	rollUpNutrition(&res, menu)
+	for _, step := range menu.Steps {
+		res.Steps = append(res.Steps, newStepResponse(step))
 	}
 
-	res.Id = menu.Id
-	res.Name = menu.Name
-	res.CategoryId = menu.CategoryId
-	res.Category = categoryRes
-
 	return res, nil
 }
 
 func (menuService *menuService) GetAll(getAllMenuRequest request.GetAllMenuRequest) ([]response.MenuResponse, error) {
 	var listMenuResponse []response.MenuResponse
 
-	listMenu, err := menuService.menuRepository.All(getAllMenuRequest.Name)
+	updatedSince, err := parseTimestamp(getAllMenuRequest.UpdatedSince)
+	if err != nil {
+		return listMenuResponse, err
+	}
+
+	excludeAllergens, err := parseAllergens(getAllMenuRequest.ExcludeAllergens)
+	if err != nil {
+		return listMenuResponse, err
+	}
+
+	categoryIds, err := menuService.categoryIds(getAllMenuRequest)
+	if err != nil {
+		return listMenuResponse, err
+	}
+
+	listMenu, err := menuService.menuRepository.All(getAllMenuRequest.Name, updatedSince, excludeAllergens, categoryIds)
 	if err != nil {
 		return listMenuResponse, err
 	}
 
 	fmt.Println(listMenu)
 
-	if len(listMenu) > 0 {
-		for _, menu := range listMenu {
+	for _, menu := range listMenu {
+		listMenuResponse = append(listMenuResponse, newMenuDetailResponse(menu, menuService.storage))
+	}
 
-			var listRecipeResponse []response.RecipeResponse
-			if len(menu.Ingredients) > 0 {
-				for _, ingredient := range menu.Ingredients {
-					recipeResponse := response.RecipeResponse{
-						Id:   ingredient.IngredientId,
-						Name: ingredient.Ingredient.Name,
-						Qty:  ingredient.Qty,
-					}
+	//fmt.Println(listMenuResponse)
+	return listMenuResponse, nil
+}
 
-					listRecipeResponse = append(listRecipeResponse, recipeResponse)
+func (menuService *menuService) Stream(getAllMenuRequest request.GetAllMenuRequest, fn func(res response.MenuResponse) error) error {
+	updatedSince, err := parseTimestamp(getAllMenuRequest.UpdatedSince)
+	if err != nil {
+		return err
+	}
 
-				}
-			}
+	excludeAllergens, err := parseAllergens(getAllMenuRequest.ExcludeAllergens)
+	if err != nil {
+		return err
+	}
 
-			categoryRes := response.CategoryResponse{
-				Id:   menu.Category.Id,
-				Name: menu.Category.Name,
-			}
+	categoryIds, err := menuService.categoryIds(getAllMenuRequest)
+	if err != nil {
+		return err
+	}
+
+	return menuService.menuRepository.Each(getAllMenuRequest.Name, updatedSince, excludeAllergens, categoryIds, func(menu models.Menu) error {
+		return fn(newMenuDetailResponse(menu, menuService.storage))
+	})
+}
 
-			res := response.MenuResponse{}
-			res.Id = menu.Id
-			res.Name = menu.Name
-			res.CategoryId = menu.CategoryId
-			res.Category = categoryRes
-			res.Ingredients = listRecipeResponse
+// Reorder sets the display order of the menus of one category.
+func (menuService *menuService) Reorder(reorderMenuRequest request.ReorderMenuRequest) ([]response.MenuResponse, error) {
+	listRes := []response.MenuResponse{}
 
-			listMenuResponse = append(listMenuResponse, res)
+	err := menuService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		listRes, err = menuService.withTx(tx).reorder(reorderMenuRequest)
+		return err
+	})
+	if err != nil {
+		return listRes, err
+	}
+
+	return listRes, nil
+}
+
+func (menuService *menuService) reorder(reorderMenuRequest request.ReorderMenuRequest) ([]response.MenuResponse, error) {
+	listMenuResponse := []response.MenuResponse{}
+
+	_, err := menuService.categoryRepository.Find(reorderMenuRequest.CategoryId)
+	if err != nil {
+		return listMenuResponse, err
+	}
+
+	before, err := menuService.menuRepository.AllByIds(reorderMenuRequest.MenuIds)
+	if err != nil {
+		return listMenuResponse, err
+	}
+
+	listMenu, err := menuService.menuRepository.Reorder(reorderMenuRequest.CategoryId, reorderMenuRequest.MenuIds)
+	if err != nil {
+		return listMenuResponse, err
+	}
+
+	previous := map[int]models.Menu{}
+	for _, menu := range before {
+		previous[menu.Id] = menu
+	}
+
+	for _, menu := range listMenu {
+		if previous[menu.Id].Position != menu.Position {
+			err = menuService.auditService.Record(reorderMenuRequest.Actor, "menu", menu.Id, models.AuditActionUpdate, previous[menu.Id], menu)
+			if err != nil {
+				return listMenuResponse, err
+			}
 		}
+
+		listMenuResponse = append(listMenuResponse, newMenuResponse(menu, menu.Category, menuService.storage))
 	}
 
-	//fmt.Println(listMenuResponse)
 	return listMenuResponse, nil
 }
+
+// categoryIds is the category filter of a menu listing, nil when the listing
+// is not filtered by category.
+func (menuService *menuService) categoryIds(getAllMenuRequest request.GetAllMenuRequest) ([]int, error) {
+	if getAllMenuRequest.CategoryId == 0 {
+		return nil, nil
+	}
+
+	category, err := menuService.categoryRepository.Find(getAllMenuRequest.CategoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !getAllMenuRequest.IncludeDescendants {
+		return []int{category.Id}, nil
+	}
+
+	return menuService.categoryRepository.DescendantIds(category.Id)
+}
+
+func (menuService *menuService) RecipeCard(getMenuRequest request.GetMenuRequest) (helper.RecipeCard, error) {
+	menu, err := menuService.menuRepository.Find(getMenuRequest.Id)
+	if err != nil {
+		return helper.RecipeCard{}, err
+	}
+
+	return newRecipeCard(menu, menuService.storage), nil
+}
+
+func (menuService *menuService) MenuBook() ([]helper.RecipeCard, error) {
+	var listRecipeCard []helper.RecipeCard
+
+	listMenu, err := menuService.menuRepository.All("", time.Time{}, nil, nil)
+	if err != nil {
+		return listRecipeCard, err
+	}
+
+	for _, menu := range listMenu {
+		listRecipeCard = append(listRecipeCard, newRecipeCard(menu, menuService.storage))
+	}
+
+	return listRecipeCard, nil
+}
+
+// Scale multiplies the recipe of a menu by the requested portions over the
+// portions the recipe yields.
+func (menuService *menuService) Scale(scaleMenuRequest request.ScaleMenuRequest) (response.ScaledMenuResponse, error) {
+	res := response.ScaledMenuResponse{}
+
+	menu, err := menuService.menuRepository.Find(scaleMenuRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	portions := menu.Portions
+	if portions < 1 {
+		portions = 1
+	}
+	factor := float64(scaleMenuRequest.Portions) / float64(portions)
+
+	res = response.ScaledMenuResponse{
+		Id:             menu.Id,
+		Name:           menu.Name,
+		Portions:       portions,
+		YieldQty:       menu.YieldQty,
+		TargetPortions: scaleMenuRequest.Portions,
+		Factor:         math.Round(factor*10000) / 10000,
+		ScaledYield:    scaleQty(menu.YieldQty, factor),
+		Ingredients:    []response.ScaledRecipeResponse{},
+	}
+
+	for _, recipe := range menu.Ingredients {
+		line := response.ScaledRecipeResponse{
+			Id:           recipe.IngredientId,
+			Name:         recipe.Ingredient.Name,
+			Qty:          recipe.Qty,
+			ScaledQty:    recipe.Qty,
+			YieldPercent: recipeYield(recipe),
+			PurchaseQty:  recipe.Qty,
+		}
+
+		quantity, ok := helper.ParseQuantity(recipe.Qty)
+		if ok {
+			scaled := quantity.Scale(factor)
+			line.Scaled = true
+			line.ScaledQty = scaled.String()
+			line.Amount = scaled.Amount
+			line.Unit = scaled.Unit
+			line.PurchaseQty = asPurchased(quantity, factor, recipe).ScaleUp(1).String()
+		}
+
+		res.Ingredients = append(res.Ingredients, line)
+	}
+
+	return res, nil
+}
+
+// findMenuName looks for a menu by name within a category, where menu names
+// are unique.
+func findMenuName(menuRepository repository.MenuRepository, categoryId int, name string) func() (int, error) {
+	return func() (int, error) {
+		menu, err := menuRepository.FindInCategory(categoryId, name)
+		return menu.Id, err
+	}
+}
+
+func scaleQty(qty string, factor float64) string {
+	quantity, ok := helper.ParseQuantity(qty)
+	if !ok {
+		return qty
+	}
+
+	return quantity.Scale(factor).String()
+}
+
+func newMenuResponse(menu models.Menu, category models.Category, storage libraries.Storage) response.MenuResponse {
+	imageUrl, thumbnailUrl := imageUrls(storage, menu.ImageKey)
+
+	return response.MenuResponse{
+		Id:           menu.Id,
+		Name:         menu.Name,
+		CategoryId:   menu.CategoryId,
+		Category:     newCategoryResponse(category, storage),
+		Position:     menu.Position,
+		YieldQty:     menu.YieldQty,
+		Portions:     menu.Portions,
+		ImageUrl:     imageUrl,
+		ThumbnailUrl: thumbnailUrl,
+		Version:      menu.Version,
+		CreatedAt:    menu.CreatedAt,
+		UpdatedAt:    menu.UpdatedAt,
+	}
+}
+
+// newMenuDetailResponse maps a menu loaded with its category and recipe lines.
+func newMenuDetailResponse(menu models.Menu, storage libraries.Storage) response.MenuResponse {
+	res := newMenuResponse(menu, menu.Category, storage)
+	rollUpDietary(&res, menu)
+	rollUpNutrition(&res, menu)
+	for _, recipe := range menu.Ingredients {
+		res.Ingredients = append(res.Ingredients, newRecipeResponse(recipe))
+	}
+
+	return res
+}
+
+func newRecipeCard(menu models.Menu, storage libraries.Storage) helper.RecipeCard {
+	card := helper.RecipeCard{Menu: newMenuDetailResponse(menu, storage)}
+	for _, step := range menu.Steps {
+		card.Steps = append(card.Steps, step.Text)
+	}
+	card.Photo, card.PhotoType = recipeCardPhoto(storage, menu.ImageKey)
+
+	return card
+}
+
+func newRecipeResponse(recipe models.MenuIngredient) response.RecipeResponse {
+	return response.RecipeResponse{
+		Id:            recipe.IngredientId,
+		Name:          recipe.Ingredient.Name,
+		Qty:           recipe.Qty,
+		YieldPercent:  recipeYield(recipe),
+		YieldOverride: recipe.YieldPercent,
+		RecipeId:      recipe.Id,
+		MenuId:        recipe.MenuId,
+		Version:       recipe.Version,
+		CreatedAt:     recipe.CreatedAt,
+		UpdatedAt:     recipe.UpdatedAt,
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erp_app/models"
+	"gorm.io/gorm"
+)
+
+// DuplicateNameError is returned when a name is already taken. ExistingId is
+// the record that has it.
+type DuplicateNameError struct {
+	Entity     string
+	Name       string
+	ExistingId int
+}
+
+func (err *DuplicateNameError) Error() string {
+	return fmt.Sprintf("%s %q already exists with id %d", err.Entity, err.Name, err.ExistingId)
+}
+
+// uniqueName fails when find returns a record other than id, which is 0 for a
+// record that is being created. Names are compared by models.NameKey.
+func uniqueName(entity string, name string, id int, find func() (int, error)) error {
+	existingId, err := find()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if existingId == id {
+		return nil
+	}
+
+	return &DuplicateNameError{Entity: entity, Name: models.NormalizeName(name), ExistingId: existingId}
+}
+
+// nameConflict explains a failed write: when the name was taken after the
+// check before the write, the unique index rejected it and the duplicate is
+// reported instead of the database error.
+func nameConflict(err error, entity string, name string, id int, find func() (int, error)) error {
+	duplicate := &DuplicateNameError{}
+	if errors.As(uniqueName(entity, name, id, find), &duplicate) {
+		return duplicate
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/models"
+	"github.com/erp_app/response"
+	"math"
+)
+
+// rollUpNutrition fills the nutrition of one portion of a menu from its
+// recipe lines. Recipe quantities are edible portions, which is what the
+// nutrients per 100 g are given for, so no yield is applied.
+func rollUpNutrition(res *response.MenuResponse, menu models.Menu) {
+	nutrition := response.NutritionResponse{Missing: []string{}}
+
+	portions := menu.Portions
+	if portions < 1 {
+		portions = 1
+	}
+
+	for _, recipe := range menu.Ingredients {
+		ingredient := recipe.Ingredient
+
+		quantity, ok := helper.ParseQuantity(recipe.Qty)
+		grams := 0.0
+		if ok {
+			grams, ok = quantity.Grams()
+		}
+		if !ok {
+			nutrition.Missing = append(nutrition.Missing, ingredient.Name)
+			continue
+		}
+
+		factor := grams / 100 / float64(portions)
+		complete := true
+		for _, nutrient := range []struct {
+			total *float64
+			value *float64
+		}{
+			{&nutrition.Calories, ingredient.Calories},
+			{&nutrition.Protein, ingredient.Protein},
+			{&nutrition.Fat, ingredient.Fat},
+			{&nutrition.Carbs, ingredient.Carbs},
+			{&nutrition.Sodium, ingredient.Sodium},
+		} {
+			if nutrient.value == nil {
+				complete = false
+				continue
+			}
+			*nutrient.total += *nutrient.value * factor
+		}
+
+		if !complete {
+			nutrition.Missing = append(nutrition.Missing, ingredient.Name)
+		}
+	}
+
+	nutrition.Calories = math.Round(nutrition.Calories)
+	nutrition.Protein = math.Round(nutrition.Protein*10) / 10
+	nutrition.Fat = math.Round(nutrition.Fat*10) / 10
+	nutrition.Carbs = math.Round(nutrition.Carbs*10) / 10
+	nutrition.Sodium = math.Round(nutrition.Sodium)
+	nutrition.Complete = len(menu.Ingredients) > 0 && len(nutrition.Missing) == 0
+
+	res.Nutrition = nutrition
+}
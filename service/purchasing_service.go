@@ -0,0 +1,121 @@
+package service
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"sort"
+	"strings"
+)
+
+type PurchasingService interface {
+	Requirements(purchasingRequest request.PurchasingRequest) (response.PurchasingResponse, error)
+}
+
+type purchasingService struct {
+	menuRepository repository.MenuRepository
+}
+
+func NewPurchasingService(menuRepository repository.MenuRepository) PurchasingService {
+	return &purchasingService{
+		menuRepository: menuRepository,
+	}
+}
+
+type purchasingTotal struct {
+	ingredient models.Ingredient
+	edible     helper.QuantityTotal
+	purchase   helper.QuantityTotal
+}
+
+// Requirements adds up the ingredients of every menu scaled to its planned
+// portions, in edible portion and in as-purchased quantities.
+func (purchasingService *purchasingService) Requirements(purchasingRequest request.PurchasingRequest) (response.PurchasingResponse, error) {
+	res := response.PurchasingResponse{
+		Lines:    []response.PurchasingLineResponse{},
+		Unparsed: []response.PurchasingUnparsedResponse{},
+	}
+
+	menus := map[int]models.Menu{}
+	totals := map[int]*purchasingTotal{}
+	var ingredientIds []int
+
+	for _, item := range purchasingRequest.Items {
+		menu, ok := menus[item.MenuId]
+		if !ok {
+			var err error
+			menu, err = purchasingService.menuRepository.Find(item.MenuId)
+			if err != nil {
+				return res, err
+			}
+			menus[item.MenuId] = menu
+		}
+
+		portions := menu.Portions
+		if portions < 1 {
+			portions = 1
+		}
+		factor := float64(item.Portions) / float64(portions)
+
+		for _, recipe := range menu.Ingredients {
+			quantity, ok := helper.ParseQuantity(recipe.Qty)
+			if !ok {
+				res.Unparsed = append(res.Unparsed, response.PurchasingUnparsedResponse{
+					MenuId:       menu.Id,
+					IngredientId: recipe.IngredientId,
+					Name:         recipe.Ingredient.Name,
+					Qty:          recipe.Qty,
+					Portions:     item.Portions,
+				})
+				continue
+			}
+
+			total, ok := totals[recipe.IngredientId]
+			if !ok {
+				total = &purchasingTotal{ingredient: recipe.Ingredient}
+				totals[recipe.IngredientId] = total
+				ingredientIds = append(ingredientIds, recipe.IngredientId)
+			}
+
+			total.edible.Add(helper.Quantity{Amount: quantity.Amount * factor, Unit: quantity.Unit})
+			total.purchase.Add(asPurchased(quantity, factor, recipe))
+		}
+	}
+
+	sort.SliceStable(ingredientIds, func(i, j int) bool {
+		left, right := totals[ingredientIds[i]].ingredient, totals[ingredientIds[j]].ingredient
+		if (left.IngredientGroup == nil) != (right.IngredientGroup == nil) {
+			return right.IngredientGroup == nil
+		}
+		if left.IngredientGroup != nil && left.IngredientGroup.Id != right.IngredientGroup.Id {
+			return strings.ToLower(left.IngredientGroup.Name) < strings.ToLower(right.IngredientGroup.Name)
+		}
+		return strings.ToLower(left.Name) < strings.ToLower(right.Name)
+	})
+
+	for _, ingredientId := range ingredientIds {
+		total := totals[ingredientId]
+		edible := total.edible.Quantities()
+		for i, purchase := range total.purchase.Quantities() {
+			line := response.PurchasingLineResponse{
+				IngredientId: ingredientId,
+				Name:         total.ingredient.Name,
+				EdibleQty:    edible[i].String(),
+				PurchaseQty:  purchase.String(),
+				Amount:       purchase.Amount,
+				Unit:         purchase.Unit,
+			}
+			if group := total.ingredient.IngredientGroup; group != nil {
+				line.IngredientGroupId = &group.Id
+				line.Group = group.Name
+				line.Storage = group.Storage
+			}
+
+			res.Lines = append(res.Lines, line)
+		}
+	}
+
+	return res, nil
+}
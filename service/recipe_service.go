@@ -1,33 +1,89 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"github.com/erp_app/helper"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
 	"github.com/erp_app/request"
+	"gorm.io/gorm"
+	"time"
+)
+
+// What adding an ingredient that is already in the recipe of a menu does.
+const (
+	DuplicateLinesReject = "reject"
+	DuplicateLinesMerge  = "merge"
+)
+
+var (
+	ErrRecipeNotInMenu        = errors.New("recipe line does not belong to this menu")
+	ErrRecipeLinesUnmergeable = errors.New("the ingredient is already in the recipe and the lines cannot be merged: quantities must be in units that add up and the yield must be the same")
+	ErrDuplicateRecipeLine    = errors.New("an ingredient can appear only once in a recipe")
 )
 
 type RecipeService interface {
 	Create(createRecipeRequest request.CreateRecipeRequest) (models.MenuIngredient, error)
 	Update(recipeRequest request.UpdateRecipeRequest) (models.MenuIngredient, error)
 	Delete(recipeRequest request.DeleteRecipeRequest) error
+	Replace(replaceRecipeRequest request.ReplaceRecipeRequest) (models.Menu, error)
+	WithTx(tx *gorm.DB) RecipeService
 }
 
 type recipeService struct {
-	recipeRepository     repository.RecipeRepository
-	menuRepository       repository.MenuRepository
-	ingredientRepository repository.IngredientRepository
+	transactionManager      repository.TransactionManager
+	recipeRepository        repository.RecipeRepository
+	recipeVersionRepository repository.RecipeVersionRepository
+	menuRepository          repository.MenuRepository
+	ingredientRepository    repository.IngredientRepository
+	auditService            AuditService
+	duplicateLines          string
 }
 
-func NewRecipeService(recipeRepository repository.RecipeRepository, menuRepository repository.MenuRepository, ingredientRepository repository.IngredientRepository) RecipeService {
+func NewRecipeService(transactionManager repository.TransactionManager, recipeRepository repository.RecipeRepository, recipeVersionRepository repository.RecipeVersionRepository, menuRepository repository.MenuRepository, ingredientRepository repository.IngredientRepository, auditService AuditService, duplicateLines string) RecipeService {
+	return newRecipeService(transactionManager, recipeRepository, recipeVersionRepository, menuRepository, ingredientRepository, auditService, duplicateLines)
+}
+
+func newRecipeService(transactionManager repository.TransactionManager, recipeRepository repository.RecipeRepository, recipeVersionRepository repository.RecipeVersionRepository, menuRepository repository.MenuRepository, ingredientRepository repository.IngredientRepository, auditService AuditService, duplicateLines string) *recipeService {
 	return &recipeService{
-		recipeRepository:     recipeRepository,
-		menuRepository:       menuRepository,
-		ingredientRepository: ingredientRepository,
+		transactionManager:      transactionManager,
+		recipeRepository:        recipeRepository,
+		recipeVersionRepository: recipeVersionRepository,
+		menuRepository:          menuRepository,
+		ingredientRepository:    ingredientRepository,
+		auditService:            auditService,
+		duplicateLines:          duplicateLines,
 	}
 }
 
+func (recipeService *recipeService) WithTx(tx *gorm.DB) RecipeService {
+	return recipeService.withTx(tx)
+}
+
+func (recipeService *recipeService) withTx(tx *gorm.DB) *recipeService {
+	return newRecipeService(repository.NewTransactionManager(tx), recipeService.recipeRepository.WithTx(tx), recipeService.recipeVersionRepository.WithTx(tx), recipeService.menuRepository.WithTx(tx), recipeService.ingredientRepository.WithTx(tx), recipeService.auditService.WithTx(tx), recipeService.duplicateLines)
+}
+
 func (recipeService *recipeService) Create(createRecipeRequest request.CreateRecipeRequest) (models.MenuIngredient, error) {
+	res := models.MenuIngredient{}
+
+	err := recipeService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		txService := recipeService.withTx(tx)
+		return txService.versioned(createRecipeRequest.MenuId, createRecipeRequest.Actor, func() error {
+			var err error
+			res, err = txService.create(createRecipeRequest)
+			return err
+		})
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (recipeService *recipeService) create(createRecipeRequest request.CreateRecipeRequest) (models.MenuIngredient, error) {
 	recipe := models.MenuIngredient{}
 	menu, err := recipeService.menuRepository.Find(createRecipeRequest.MenuId)
 	if err != nil {
@@ -41,12 +97,29 @@ func (recipeService *recipeService) Create(createRecipeRequest request.CreateRec
 		return recipe, err
 	}
 
+	existing, err := recipeService.recipeRepository.FindByMenuAndIngredient(menu.Id, ingredient.Id)
+	if err == nil {
+		if recipeService.duplicateLines == DuplicateLinesMerge {
+			return recipeService.merge(existing, createRecipeRequest)
+		}
+		return recipe, &DuplicateNameError{Entity: "recipe line", Name: ingredient.Name, ExistingId: existing.Id}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return recipe, err
+	}
+
 	recipe.MenuId = menu.Id
 	recipe.IngredientId = ingredient.Id
 	recipe.Qty = createRecipeRequest.Qty
+	recipe.YieldPercent = createRecipeRequest.YieldPercent
 	recipe.Ingredient = ingredient
 
 	recipe, err = recipeService.recipeRepository.Create(recipe)
+	if err != nil {
+		return recipe, recipeService.lineConflict(err, recipe, ingredient)
+	}
+
+	err = recipeService.auditService.Record(createRecipeRequest.Actor, "recipe", recipe.Id, models.AuditActionCreate, nil, recipe)
 	if err != nil {
 		return recipe, err
 	}
@@ -55,6 +128,24 @@ func (recipeService *recipeService) Create(createRecipeRequest request.CreateRec
 }
 
 func (recipeService *recipeService) Update(recipeRequest request.UpdateRecipeRequest) (models.MenuIngredient, error) {
+	res := models.MenuIngredient{}
+
+	err := recipeService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		txService := recipeService.withTx(tx)
+		return txService.versioned(recipeRequest.MenuId, recipeRequest.Actor, func() error {
+			var err error
+			res, err = txService.update(recipeRequest)
+			return err
+		})
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (recipeService *recipeService) update(recipeRequest request.UpdateRecipeRequest) (models.MenuIngredient, error) {
 	fmt.Println(recipeRequest)
 	recipe, err := recipeService.recipeRepository.Find(recipeRequest.Id)
 	if err != nil {
@@ -62,22 +153,47 @@ func (recipeService *recipeService) Update(recipeRequest request.UpdateRecipeReq
 		return recipe, err
 	}
 
+	if recipe.MenuId != recipeRequest.MenuId {
+		return recipe, ErrRecipeNotInMenu
+	}
+
+	if !versionMatches(recipeRequest.Version, recipe.Version) {
+		return recipe, repository.ErrVersionConflict
+	}
+
 	ingredient, err := recipeService.ingredientRepository.Find(recipeRequest.IngredientId)
 	if err != nil {
 		return recipe, err
 	}
 
-	menu, err := recipeService.menuRepository.Find(recipeRequest.MenuId)
-	if err != nil {
-		return recipe, err
+	// another line may already hold the ingredient the line is switched to
+	if ingredient.Id != recipe.IngredientId {
+		existing, err := recipeService.recipeRepository.FindByMenuAndIngredient(recipe.MenuId, ingredient.Id)
+		if err == nil {
+			return recipe, &DuplicateNameError{Entity: "recipe line", Name: ingredient.Name, ExistingId: existing.Id}
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return recipe, err
+		}
 	}
 
-	recipe.MenuId = menu.Id
+	before := recipe
 	recipe.IngredientId = ingredient.Id
 	recipe.Qty = recipeRequest.Qty
+	if recipeRequest.YieldPercent != nil {
+		recipe.YieldPercent = recipeRequest.YieldPercent
+		if *recipeRequest.YieldPercent == 0 {
+			recipe.YieldPercent = nil
+		}
+	}
 	recipe.Ingredient = ingredient
 
 	recipe, err = recipeService.recipeRepository.Update(recipe)
+	if err != nil {
+		return recipe, recipeService.lineConflict(err, recipe, ingredient)
+	}
+
+	err = recipeService.auditService.Record(recipeRequest.Actor, "recipe", recipe.Id, models.AuditActionUpdate, before, recipe)
 	if err != nil {
 		return recipe, err
 	}
@@ -86,16 +202,245 @@ func (recipeService *recipeService) Update(recipeRequest request.UpdateRecipeReq
 }
 
 func (recipeService *recipeService) Delete(recipeRequest request.DeleteRecipeRequest) error {
+	return recipeService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		txService := recipeService.withTx(tx)
+		return txService.versioned(recipeRequest.MenuId, recipeRequest.Actor, func() error {
+			return txService.delete(recipeRequest)
+		})
+	})
+}
+
+func (recipeService *recipeService) delete(recipeRequest request.DeleteRecipeRequest) error {
 	recipe, err := recipeService.recipeRepository.Find(recipeRequest.Id)
 	if err != nil {
 		fmt.Println("error recipe find")
 		return err
 	}
 
+	if recipe.MenuId != recipeRequest.MenuId {
+		return ErrRecipeNotInMenu
+	}
+
+	if !versionMatches(recipeRequest.Version, recipe.Version) {
+		return repository.ErrVersionConflict
+	}
+
 	err = recipeService.recipeRepository.Delete(recipe)
 	if err != nil {
 		return err
 	}
 
+	err = recipeService.auditService.Record(recipeRequest.Actor, "recipe", recipe.Id, models.AuditActionDelete, recipe, nil)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// Replace makes the lines of the request the whole recipe of the menu. Lines
+// are matched to the current recipe by ingredient, so a line that stays keeps
+// its id and is only written when its quantity or yield changes. Everything
+// runs in one transaction: a bad line leaves the recipe as it was. The menu
+// version is bumped once, so a concurrent replace of the same recipe fails
+// with a version conflict. The menu is returned with its new recipe.
+func (recipeService *recipeService) Replace(replaceRecipeRequest request.ReplaceRecipeRequest) (models.Menu, error) {
+	menu := models.Menu{}
+
+	err := recipeService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		txService := recipeService.withTx(tx)
+		return txService.versioned(replaceRecipeRequest.MenuId, replaceRecipeRequest.Actor, func() error {
+			var err error
+			menu, err = txService.replace(replaceRecipeRequest)
+			return err
+		})
+	})
+	if err != nil {
+		return menu, err
+	}
+
+	return menu, nil
+}
+
+func (recipeService *recipeService) replace(replaceRecipeRequest request.ReplaceRecipeRequest) (models.Menu, error) {
+	menu, err := recipeService.menuRepository.Find(replaceRecipeRequest.MenuId)
+	if err != nil {
+		return menu, err
+	}
+
+	if !versionMatches(replaceRecipeRequest.Version, menu.Version) {
+		return menu, repository.ErrVersionConflict
+	}
+
+	menu, err = recipeService.menuRepository.Update(menu)
+	if err != nil {
+		return menu, err
+	}
+
+	current, err := recipeService.recipeRepository.AllByMenu(menu.Id)
+	if err != nil {
+		return menu, err
+	}
+
+	live := map[int]models.MenuIngredient{}
+	for _, recipe := range current {
+		live[recipe.IngredientId] = recipe
+	}
+
+	kept := map[int]bool{}
+	seen := map[int]bool{}
+	for _, line := range replaceRecipeRequest.Lines {
+		if seen[line.IngredientId] {
+			return menu, ErrDuplicateRecipeLine
+		}
+		seen[line.IngredientId] = true
+
+		ingredient, err := recipeService.ingredientRepository.Find(line.IngredientId)
+		if err != nil {
+			return menu, fmt.Errorf("ingredient %d: %w", line.IngredientId, err)
+		}
+
+		recipe, ok := live[ingredient.Id]
+		if !ok {
+			recipe = models.MenuIngredient{MenuId: menu.Id, IngredientId: ingredient.Id, Qty: line.Qty, YieldPercent: line.YieldPercent, Ingredient: ingredient}
+			recipe, err = recipeService.recipeRepository.Create(recipe)
+			if err != nil {
+				return menu, recipeService.lineConflict(err, recipe, ingredient)
+			}
+
+			err = recipeService.auditService.Record(replaceRecipeRequest.Actor, "recipe", recipe.Id, models.AuditActionCreate, nil, recipe)
+			if err != nil {
+				return menu, err
+			}
+			continue
+		}
+
+		kept[recipe.Id] = true
+		if recipe.Qty == line.Qty && sameYield(recipe.YieldPercent, line.YieldPercent) {
+			continue
+		}
+
+		before := recipe
+		recipe.Qty = line.Qty
+		recipe.YieldPercent = line.YieldPercent
+		recipe, err = recipeService.recipeRepository.Update(recipe)
+		if err != nil {
+			return menu, err
+		}
+
+		err = recipeService.auditService.Record(replaceRecipeRequest.Actor, "recipe", recipe.Id, models.AuditActionUpdate, before, recipe)
+		if err != nil {
+			return menu, err
+		}
+	}
+
+	for _, recipe := range current {
+		if kept[recipe.Id] {
+			continue
+		}
+
+		err = recipeService.recipeRepository.Delete(recipe)
+		if err != nil {
+			return menu, err
+		}
+
+		err = recipeService.auditService.Record(replaceRecipeRequest.Actor, "recipe", recipe.Id, models.AuditActionDelete, recipe, nil)
+		if err != nil {
+			return menu, err
+		}
+	}
+
+	menu.Ingredients, err = recipeService.recipeRepository.AllByMenu(menu.Id)
+	if err != nil {
+		return menu, err
+	}
+
+	return menu, nil
+}
+
+// versioned runs a direct edit of the recipe of a menu and stores the result
+// as a new recipe version, so the version history never drifts from the live
+// recipe. A menu without versions first keeps the recipe it had before.
+func (recipeService *recipeService) versioned(menuId int, actor string, edit func() error) error {
+	now := time.Now()
+	err := keepBaseline(recipeService.recipeVersionRepository, recipeService.menuRepository, recipeService.auditService, menuId, actor, now)
+	if err != nil {
+		return err
+	}
+
+	err = edit()
+	if err != nil {
+		return err
+	}
+
+	recipeVersion, err := recipeService.recipeVersionRepository.Snapshot(menuId, "recipe edited directly", now)
+	if err != nil {
+		return err
+	}
+
+	return recipeService.auditService.Record(actor, "recipe_version", recipeVersion.Id, models.AuditActionCreate, nil, recipeVersion)
+}
+
+// lineConflict explains a failed write of a recipe line: when another line
+// took the ingredient after the check before the write, the unique index
+// rejected it and the duplicate is reported instead of the database error.
+func (recipeService *recipeService) lineConflict(err error, recipe models.MenuIngredient, ingredient models.Ingredient) error {
+	existing, findErr := recipeService.recipeRepository.FindByMenuAndIngredient(recipe.MenuId, ingredient.Id)
+	if findErr == nil && existing.Id != recipe.Id {
+		return &DuplicateNameError{Entity: "recipe line", Name: ingredient.Name, ExistingId: existing.Id}
+	}
+
+	return err
+}
+
+func sameYield(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// merge adds the quantity of a new line to the line already holding its
+// ingredient. Both quantities must add up to a single quantity, and the new
+// line must not change the yield the existing line is bought at.
+func (recipeService *recipeService) merge(recipe models.MenuIngredient, createRecipeRequest request.CreateRecipeRequest) (models.MenuIngredient, error) {
+	added := models.MenuIngredient{YieldPercent: createRecipeRequest.YieldPercent, Ingredient: recipe.Ingredient}
+	if recipeYield(added) != recipeYield(recipe) {
+		return recipe, ErrRecipeLinesUnmergeable
+	}
+
+	current, ok := helper.ParseQuantity(recipe.Qty)
+	if !ok {
+		return recipe, ErrRecipeLinesUnmergeable
+	}
+
+	addedQuantity, ok := helper.ParseQuantity(createRecipeRequest.Qty)
+	if !ok {
+		return recipe, ErrRecipeLinesUnmergeable
+	}
+
+	total := helper.QuantityTotal{}
+	total.Add(current)
+	total.Add(addedQuantity)
+
+	quantities := total.Quantities()
+	if len(quantities) != 1 {
+		return recipe, ErrRecipeLinesUnmergeable
+	}
+
+	before := recipe
+	recipe.Qty = quantities[0].String()
+
+	recipe, err := recipeService.recipeRepository.Update(recipe)
+	if err != nil {
+		return recipe, err
+	}
+
+	err = recipeService.auditService.Record(createRecipeRequest.Actor, "recipe", recipe.Id, models.AuditActionUpdate, before, recipe)
+	if err != nil {
+		return recipe, err
+	}
+
+	return recipe, nil
+}
@@ -0,0 +1,607 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+var (
+	ErrRecipeVersionNotInMenu   = errors.New("recipe version does not belong to this menu")
+	ErrRecipeVersionNotDraft    = errors.New("only draft recipe versions can be changed or activated")
+	ErrRecipeVersionInEffect    = errors.New("the recipe version in effect cannot be archived, activate another version instead")
+	ErrRecipeVersionArchived    = errors.New("recipe version is already archived")
+	ErrDuplicateVersionLine     = errors.New("an ingredient can appear only once in a recipe version")
+	ErrRecipeVersionNotEffected = errors.New("no recipe version was in effect at that time")
+	ErrRecipeVersionPast        = errors.New("effective_from must not be in the past, the recipe history cannot be rewritten")
+)
+
+type RecipeVersionService interface {
+	GetAll(getAllRecipeVersionRequest request.GetAllRecipeVersionRequest) ([]response.RecipeVersionResponse, error)
+	Get(getRecipeVersionRequest request.GetRecipeVersionRequest) (response.RecipeVersionResponse, error)
+	Effective(getEffectiveRecipeVersionRequest request.GetEffectiveRecipeVersionRequest) (response.RecipeVersionResponse, error)
+	Create(createRecipeVersionRequest request.CreateRecipeVersionRequest) (response.RecipeVersionResponse, error)
+	Update(updateRecipeVersionRequest request.UpdateRecipeVersionRequest) (response.RecipeVersionResponse, error)
+	Delete(deleteRecipeVersionRequest request.DeleteRecipeVersionRequest) error
+	Activate(activateRecipeVersionRequest request.ActivateRecipeVersionRequest) (response.RecipeVersionResponse, error)
+	Archive(archiveRecipeVersionRequest request.ArchiveRecipeVersionRequest) (response.RecipeVersionResponse, error)
+	Diff(diffRecipeVersionRequest request.DiffRecipeVersionRequest) (response.RecipeVersionDiffResponse, error)
+	ActivateDue(now time.Time) (int, error)
+	WithTx(tx *gorm.DB) RecipeVersionService
+}
+
+type recipeVersionService struct {
+	transactionManager      repository.TransactionManager
+	recipeVersionRepository repository.RecipeVersionRepository
+	menuRepository          repository.MenuRepository
+	ingredientRepository    repository.IngredientRepository
+	auditService            AuditService
+}
+
+func NewRecipeVersionService(transactionManager repository.TransactionManager, recipeVersionRepository repository.RecipeVersionRepository, menuRepository repository.MenuRepository, ingredientRepository repository.IngredientRepository, auditService AuditService) RecipeVersionService {
+	return newRecipeVersionService(transactionManager, recipeVersionRepository, menuRepository, ingredientRepository, auditService)
+}
+
+func newRecipeVersionService(transactionManager repository.TransactionManager, recipeVersionRepository repository.RecipeVersionRepository, menuRepository repository.MenuRepository, ingredientRepository repository.IngredientRepository, auditService AuditService) *recipeVersionService {
+	return &recipeVersionService{
+		transactionManager:      transactionManager,
+		recipeVersionRepository: recipeVersionRepository,
+		menuRepository:          menuRepository,
+		ingredientRepository:    ingredientRepository,
+		auditService:            auditService,
+	}
+}
+
+func (recipeVersionService *recipeVersionService) WithTx(tx *gorm.DB) RecipeVersionService {
+	return recipeVersionService.withTx(tx)
+}
+
+func (recipeVersionService *recipeVersionService) withTx(tx *gorm.DB) *recipeVersionService {
+	return newRecipeVersionService(repository.NewTransactionManager(tx), recipeVersionService.recipeVersionRepository.WithTx(tx), recipeVersionService.menuRepository.WithTx(tx), recipeVersionService.ingredientRepository.WithTx(tx), recipeVersionService.auditService.WithTx(tx))
+}
+
+func (recipeVersionService *recipeVersionService) GetAll(getAllRecipeVersionRequest request.GetAllRecipeVersionRequest) ([]response.RecipeVersionResponse, error) {
+	listRecipeVersionResponse := []response.RecipeVersionResponse{}
+
+	_, err := recipeVersionService.menuRepository.Find(getAllRecipeVersionRequest.MenuId)
+	if err != nil {
+		return listRecipeVersionResponse, err
+	}
+
+	listRecipeVersion, err := recipeVersionService.recipeVersionRepository.All(getAllRecipeVersionRequest.MenuId)
+	if err != nil {
+		return listRecipeVersionResponse, err
+	}
+
+	for _, recipeVersion := range listRecipeVersion {
+		listRecipeVersionResponse = append(listRecipeVersionResponse, newRecipeVersionResponse(recipeVersion))
+	}
+
+	return listRecipeVersionResponse, nil
+}
+
+func (recipeVersionService *recipeVersionService) Get(getRecipeVersionRequest request.GetRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	recipeVersion, err := recipeVersionService.find(getRecipeVersionRequest.MenuId, getRecipeVersionRequest.Id)
+	if err != nil {
+		return response.RecipeVersionResponse{}, err
+	}
+
+	return newRecipeVersionResponse(recipeVersion), nil
+}
+
+func (recipeVersionService *recipeVersionService) Effective(getEffectiveRecipeVersionRequest request.GetEffectiveRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	at := time.Now()
+	if getEffectiveRecipeVersionRequest.At != "" {
+		var err error
+		at, err = parseTimestamp(getEffectiveRecipeVersionRequest.At)
+		if err != nil {
+			return response.RecipeVersionResponse{}, err
+		}
+	}
+
+	recipeVersion, err := recipeVersionService.recipeVersionRepository.FindEffective(getEffectiveRecipeVersionRequest.MenuId, at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.RecipeVersionResponse{}, ErrRecipeVersionNotEffected
+	}
+	if err != nil {
+		return response.RecipeVersionResponse{}, err
+	}
+
+	return newRecipeVersionResponse(recipeVersion), nil
+}
+
+func (recipeVersionService *recipeVersionService) Create(createRecipeVersionRequest request.CreateRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	err := recipeVersionService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = recipeVersionService.withTx(tx).create(createRecipeVersionRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (recipeVersionService *recipeVersionService) create(createRecipeVersionRequest request.CreateRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	menu, err := recipeVersionService.menuRepository.Find(createRecipeVersionRequest.MenuId)
+	if err != nil {
+		return res, err
+	}
+
+	recipeVersion := models.RecipeVersion{MenuId: menu.Id, Status: models.RecipeVersionDraft, Note: createRecipeVersionRequest.Note}
+	if createRecipeVersionRequest.Lines == nil {
+		recipeVersion.Lines = liveRecipeLines(menu)
+	} else {
+		recipeVersion.Lines, err = recipeVersionService.lines(createRecipeVersionRequest.Lines)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	recipeVersion, err = recipeVersionService.recipeVersionRepository.Create(recipeVersion)
+	if err != nil {
+		return res, err
+	}
+
+	err = recipeVersionService.auditService.Record(createRecipeVersionRequest.Actor, "recipe_version", recipeVersion.Id, models.AuditActionCreate, nil, recipeVersion)
+	if err != nil {
+		return res, err
+	}
+
+	return newRecipeVersionResponse(recipeVersion), nil
+}
+
+func (recipeVersionService *recipeVersionService) Update(updateRecipeVersionRequest request.UpdateRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	err := recipeVersionService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = recipeVersionService.withTx(tx).update(updateRecipeVersionRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (recipeVersionService *recipeVersionService) update(updateRecipeVersionRequest request.UpdateRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	recipeVersion, err := recipeVersionService.find(updateRecipeVersionRequest.MenuId, updateRecipeVersionRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(updateRecipeVersionRequest.Version, recipeVersion.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	if recipeVersion.Status != models.RecipeVersionDraft {
+		return res, ErrRecipeVersionNotDraft
+	}
+
+	before := recipeVersion
+	recipeVersion.Note = updateRecipeVersionRequest.Note
+	recipeVersion.Lines, err = recipeVersionService.lines(updateRecipeVersionRequest.Lines)
+	if err != nil {
+		return res, err
+	}
+
+	recipeVersion, err = recipeVersionService.recipeVersionRepository.Update(recipeVersion)
+	if err != nil {
+		return res, err
+	}
+
+	err = recipeVersionService.auditService.Record(updateRecipeVersionRequest.Actor, "recipe_version", recipeVersion.Id, models.AuditActionUpdate, before, recipeVersion)
+	if err != nil {
+		return res, err
+	}
+
+	return newRecipeVersionResponse(recipeVersion), nil
+}
+
+func (recipeVersionService *recipeVersionService) Delete(deleteRecipeVersionRequest request.DeleteRecipeVersionRequest) error {
+	return recipeVersionService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		return recipeVersionService.withTx(tx).delete(deleteRecipeVersionRequest)
+	})
+}
+
+func (recipeVersionService *recipeVersionService) delete(deleteRecipeVersionRequest request.DeleteRecipeVersionRequest) error {
+	recipeVersion, err := recipeVersionService.find(deleteRecipeVersionRequest.MenuId, deleteRecipeVersionRequest.Id)
+	if err != nil {
+		return err
+	}
+
+	if !versionMatches(deleteRecipeVersionRequest.Version, recipeVersion.Version) {
+		return repository.ErrVersionConflict
+	}
+
+	if recipeVersion.Status != models.RecipeVersionDraft {
+		return ErrRecipeVersionNotDraft
+	}
+
+	err = recipeVersionService.recipeVersionRepository.Delete(recipeVersion)
+	if err != nil {
+		return err
+	}
+
+	err = recipeVersionService.auditService.Record(deleteRecipeVersionRequest.Actor, "recipe_version", recipeVersion.Id, models.AuditActionDelete, recipeVersion, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Activate releases a draft. A version effective now replaces the recipe of
+// the menu right away; a future one waits for ActivateDue. The first
+// activation of a menu also keeps the formula it had before as an archived
+// version, so the history starts with what the kitchen was actually using.
+// An effective date in the past is rejected: costing for that period must
+// keep the formula the kitchen had then.
+func (recipeVersionService *recipeVersionService) Activate(activateRecipeVersionRequest request.ActivateRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	now := time.Now()
+	effectiveFrom := now
+	if activateRecipeVersionRequest.EffectiveFrom != "" {
+		var err error
+		effectiveFrom, err = parseTimestamp(activateRecipeVersionRequest.EffectiveFrom)
+		if err != nil {
+			return res, err
+		}
+
+		// timestamps carry whole seconds, so the current second counts as now
+		if effectiveFrom.Before(now.Truncate(time.Second)) {
+			return res, ErrRecipeVersionPast
+		}
+		if effectiveFrom.Before(now) {
+			effectiveFrom = now
+		}
+	}
+
+	var recipeVersion models.RecipeVersion
+	err := recipeVersionService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		txService := recipeVersionService.withTx(tx)
+
+		var err error
+		recipeVersion, err = txService.find(activateRecipeVersionRequest.MenuId, activateRecipeVersionRequest.Id)
+		if err != nil {
+			return err
+		}
+
+		if !versionMatches(activateRecipeVersionRequest.Version, recipeVersion.Version) {
+			return repository.ErrVersionConflict
+		}
+
+		if recipeVersion.Status != models.RecipeVersionDraft {
+			return ErrRecipeVersionNotDraft
+		}
+
+		err = keepBaseline(txService.recipeVersionRepository, txService.menuRepository, txService.auditService, recipeVersion.MenuId, activateRecipeVersionRequest.Actor, now)
+		if err != nil {
+			return err
+		}
+
+		before := recipeVersion
+		recipeVersion.Status = models.RecipeVersionActive
+		recipeVersion.EffectiveFrom = &effectiveFrom
+		recipeVersion, err = txService.recipeVersionRepository.Update(recipeVersion)
+		if err != nil {
+			return err
+		}
+
+		if !effectiveFrom.After(now) {
+			recipeVersion, err = txService.recipeVersionRepository.Apply(recipeVersion, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		return txService.auditService.Record(activateRecipeVersionRequest.Actor, "recipe_version", recipeVersion.Id, models.AuditActionUpdate, before, recipeVersion)
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return newRecipeVersionResponse(recipeVersion), nil
+}
+
+// Archive withdraws a draft or a version still waiting for its effective
+// date. The version in effect can only be replaced, not archived.
+func (recipeVersionService *recipeVersionService) Archive(archiveRecipeVersionRequest request.ArchiveRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	err := recipeVersionService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = recipeVersionService.withTx(tx).archive(archiveRecipeVersionRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (recipeVersionService *recipeVersionService) archive(archiveRecipeVersionRequest request.ArchiveRecipeVersionRequest) (response.RecipeVersionResponse, error) {
+	res := response.RecipeVersionResponse{}
+
+	recipeVersion, err := recipeVersionService.find(archiveRecipeVersionRequest.MenuId, archiveRecipeVersionRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(archiveRecipeVersionRequest.Version, recipeVersion.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	switch {
+	case recipeVersion.Status == models.RecipeVersionArchived:
+		return res, ErrRecipeVersionArchived
+	case recipeVersion.AppliedAt != nil:
+		return res, ErrRecipeVersionInEffect
+	}
+
+	before := recipeVersion
+	recipeVersion.Status = models.RecipeVersionArchived
+	recipeVersion, err = recipeVersionService.recipeVersionRepository.Update(recipeVersion)
+	if err != nil {
+		return res, err
+	}
+
+	err = recipeVersionService.auditService.Record(archiveRecipeVersionRequest.Actor, "recipe_version", recipeVersion.Id, models.AuditActionUpdate, before, recipeVersion)
+	if err != nil {
+		return res, err
+	}
+
+	return newRecipeVersionResponse(recipeVersion), nil
+}
+
+// Diff compares a version with the one given by against, or with the version
+// numbered just before it.
+func (recipeVersionService *recipeVersionService) Diff(diffRecipeVersionRequest request.DiffRecipeVersionRequest) (response.RecipeVersionDiffResponse, error) {
+	res := response.RecipeVersionDiffResponse{}
+
+	to, err := recipeVersionService.find(diffRecipeVersionRequest.MenuId, diffRecipeVersionRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	from := models.RecipeVersion{}
+	if diffRecipeVersionRequest.Against != 0 {
+		from, err = recipeVersionService.find(diffRecipeVersionRequest.MenuId, diffRecipeVersionRequest.Against)
+		if err != nil {
+			return res, err
+		}
+	} else {
+		from, err = recipeVersionService.recipeVersionRepository.FindPrevious(to)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, err
+		}
+	}
+
+	return diffRecipeVersions(from, to), nil
+}
+
+// ActivateDue applies every active version whose effective date has passed
+// and returns how many were applied. It is run periodically by the server. A
+// version that fails is logged and skipped, so it does not hold back the
+// versions of other menus; it is tried again on the next run.
+func (recipeVersionService *recipeVersionService) ActivateDue(now time.Time) (int, error) {
+	listRecipeVersion, err := recipeVersionService.recipeVersionRepository.Due(now)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	failed := 0
+	for _, due := range listRecipeVersion {
+		menuExists := true
+		locked := true
+		err = recipeVersionService.transactionManager.Transaction(func(tx *gorm.DB) error {
+			txService := recipeVersionService.withTx(tx)
+
+			// another server may be applying the same version
+			recipeVersion, err := txService.recipeVersionRepository.LockDue(due.Id, now)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				locked = false
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			before := recipeVersion
+			_, err = txService.menuRepository.Find(recipeVersion.MenuId)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// the menu is gone, there is nothing left to apply the version to
+				menuExists = false
+				recipeVersion.Status = models.RecipeVersionArchived
+				recipeVersion, err = txService.recipeVersionRepository.Update(recipeVersion)
+			} else if err == nil {
+				recipeVersion, err = txService.recipeVersionRepository.Apply(recipeVersion, now)
+			}
+			if err != nil {
+				return err
+			}
+
+			return txService.auditService.Record("", "recipe_version", recipeVersion.Id, models.AuditActionUpdate, before, recipeVersion)
+		})
+		if err != nil {
+			log.Printf("apply recipe version %d: %s", due.Id, err.Error())
+			failed++
+			continue
+		}
+
+		if locked && menuExists {
+			applied++
+		}
+	}
+
+	if failed > 0 {
+		return applied, fmt.Errorf("%d of %d due recipe versions could not be applied", failed, len(listRecipeVersion))
+	}
+
+	return applied, nil
+}
+
+func (recipeVersionService *recipeVersionService) find(menuId int, id int) (models.RecipeVersion, error) {
+	recipeVersion, err := recipeVersionService.recipeVersionRepository.Find(id)
+	if err != nil {
+		return recipeVersion, err
+	}
+
+	if recipeVersion.MenuId != menuId {
+		return recipeVersion, ErrRecipeVersionNotInMenu
+	}
+
+	return recipeVersion, nil
+}
+
+// keepBaseline stores the recipe a menu has before its first version is
+// released as archived version 0, so the history starts with what the kitchen
+// was actually using.
+func keepBaseline(recipeVersionRepository repository.RecipeVersionRepository, menuRepository repository.MenuRepository, auditService AuditService, menuId int, actor string, now time.Time) error {
+	released, err := recipeVersionRepository.CountReleased(menuId)
+	if err != nil || released > 0 {
+		return err
+	}
+
+	menu, err := menuRepository.Find(menuId)
+	if err != nil {
+		return err
+	}
+
+	effectiveFrom := menu.CreatedAt
+	baseline, err := recipeVersionRepository.CreateBaseline(models.RecipeVersion{
+		MenuId:        menu.Id,
+		Status:        models.RecipeVersionArchived,
+		Note:          "recipe before versioning",
+		EffectiveFrom: &effectiveFrom,
+		AppliedAt:     &now,
+		Lines:         liveRecipeLines(menu),
+	})
+	if err != nil {
+		return err
+	}
+
+	return auditService.Record(actor, "recipe_version", baseline.Id, models.AuditActionCreate, nil, baseline)
+}
+
+func (recipeVersionService *recipeVersionService) lines(lineRequests []request.RecipeVersionLineRequest) ([]models.RecipeVersionLine, error) {
+	lines := []models.RecipeVersionLine{}
+	seen := map[int]bool{}
+
+	for _, lineRequest := range lineRequests {
+		if seen[lineRequest.IngredientId] {
+			return lines, ErrDuplicateVersionLine
+		}
+		seen[lineRequest.IngredientId] = true
+
+		ingredient, err := recipeVersionService.ingredientRepository.Find(lineRequest.IngredientId)
+		if err != nil {
+			return lines, fmt.Errorf("ingredient %d: %w", lineRequest.IngredientId, err)
+		}
+
+		lines = append(lines, models.RecipeVersionLine{IngredientId: ingredient.Id, Qty: lineRequest.Qty, Ingredient: ingredient})
+	}
+
+	return lines, nil
+}
+
+// liveRecipeLines copies the current recipe of a menu loaded with its
+// ingredients.
+func liveRecipeLines(menu models.Menu) []models.RecipeVersionLine {
+	lines := []models.RecipeVersionLine{}
+
+	for _, recipe := range menu.Ingredients {
+		lines = append(lines, models.RecipeVersionLine{IngredientId: recipe.IngredientId, Qty: recipe.Qty, Ingredient: recipe.Ingredient})
+	}
+
+	return lines
+}
+
+func diffRecipeVersions(from models.RecipeVersion, to models.RecipeVersion) response.RecipeVersionDiffResponse {
+	res := response.RecipeVersionDiffResponse{
+		From:    from.Number,
+		To:      to.Number,
+		Added:   []response.RecipeVersionLineResponse{},
+		Removed: []response.RecipeVersionLineResponse{},
+		Changed: []response.RecipeVersionQtyChange{},
+	}
+
+	previous := map[int]models.RecipeVersionLine{}
+	for _, line := range from.Lines {
+		previous[line.IngredientId] = line
+	}
+
+	for _, line := range to.Lines {
+		old, ok := previous[line.IngredientId]
+		delete(previous, line.IngredientId)
+
+		switch {
+		case !ok:
+			res.Added = append(res.Added, newRecipeVersionLineResponse(line))
+		case old.Qty != line.Qty:
+			res.Changed = append(res.Changed, response.RecipeVersionQtyChange{
+				IngredientId: line.IngredientId,
+				Name:         line.Ingredient.Name,
+				FromQty:      old.Qty,
+				ToQty:        line.Qty,
+			})
+		}
+	}
+
+	for _, line := range from.Lines {
+		if _, removed := previous[line.IngredientId]; removed {
+			res.Removed = append(res.Removed, newRecipeVersionLineResponse(line))
+		}
+	}
+
+	return res
+}
+
+func newRecipeVersionResponse(recipeVersion models.RecipeVersion) response.RecipeVersionResponse {
+	res := response.RecipeVersionResponse{
+		Id:            recipeVersion.Id,
+		MenuId:        recipeVersion.MenuId,
+		Number:        recipeVersion.Number,
+		Status:        recipeVersion.Status,
+		Note:          recipeVersion.Note,
+		EffectiveFrom: recipeVersion.EffectiveFrom,
+		AppliedAt:     recipeVersion.AppliedAt,
+		InEffect:      recipeVersion.Status == models.RecipeVersionActive && recipeVersion.AppliedAt != nil,
+		Lines:         []response.RecipeVersionLineResponse{},
+		Version:       recipeVersion.Version,
+		CreatedAt:     recipeVersion.CreatedAt,
+		UpdatedAt:     recipeVersion.UpdatedAt,
+	}
+
+	for _, line := range recipeVersion.Lines {
+		res.Lines = append(res.Lines, newRecipeVersionLineResponse(line))
+	}
+
+	return res
+}
+
+func newRecipeVersionLineResponse(line models.RecipeVersionLine) response.RecipeVersionLineResponse {
+	return response.RecipeVersionLineResponse{
+		IngredientId: line.IngredientId,
+		Name:         line.Ingredient.Name,
+		Qty:          line.Qty,
+	}
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"gorm.io/gorm"
+)
+
+var ErrStepNotInMenu = errors.New("step does not belong to this menu")
+
+type StepService interface {
+	GetAll(getAllStepRequest request.GetAllStepRequest) ([]response.StepResponse, error)
+	Create(createStepRequest request.CreateStepRequest) (response.StepResponse, error)
+	Update(updateStepRequest request.UpdateStepRequest) (response.StepResponse, error)
+	Delete(deleteStepRequest request.DeleteStepRequest) error
+	Reorder(reorderStepRequest request.ReorderStepRequest) ([]response.StepResponse, error)
+	WithTx(tx *gorm.DB) StepService
+}
+
+type stepService struct {
+	transactionManager repository.TransactionManager
+	stepRepository     repository.StepRepository
+	menuRepository     repository.MenuRepository
+	auditService       AuditService
+}
+
+func NewStepService(transactionManager repository.TransactionManager, stepRepository repository.StepRepository, menuRepository repository.MenuRepository, auditService AuditService) StepService {
+	return newStepService(transactionManager, stepRepository, menuRepository, auditService)
+}
+
+func newStepService(transactionManager repository.TransactionManager, stepRepository repository.StepRepository, menuRepository repository.MenuRepository, auditService AuditService) *stepService {
+	return &stepService{
+		transactionManager: transactionManager,
+		stepRepository:     stepRepository,
+		menuRepository:     menuRepository,
+		auditService:       auditService,
+	}
+}
+
+func (stepService *stepService) WithTx(tx *gorm.DB) StepService {
+	return stepService.withTx(tx)
+}
+
+func (stepService *stepService) withTx(tx *gorm.DB) *stepService {
+	return newStepService(repository.NewTransactionManager(tx), stepService.stepRepository.WithTx(tx), stepService.menuRepository.WithTx(tx), stepService.auditService.WithTx(tx))
+}
+
+func (stepService *stepService) GetAll(getAllStepRequest request.GetAllStepRequest) ([]response.StepResponse, error) {
+	listStepResponse := []response.StepResponse{}
+
+	_, err := stepService.menuRepository.Find(getAllStepRequest.MenuId)
+	if err != nil {
+		return listStepResponse, err
+	}
+
+	listStep, err := stepService.stepRepository.All(getAllStepRequest.MenuId)
+	if err != nil {
+		return listStepResponse, err
+	}
+
+	for _, step := range listStep {
+		listStepResponse = append(listStepResponse, newStepResponse(step))
+	}
+
+	return listStepResponse, nil
+}
+
+func (stepService *stepService) Create(createStepRequest request.CreateStepRequest) (response.StepResponse, error) {
+	res := response.StepResponse{}
+
+	err := stepService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = stepService.withTx(tx).create(createStepRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (stepService *stepService) create(createStepRequest request.CreateStepRequest) (response.StepResponse, error) {
+	res := response.StepResponse{}
+
+	menu, err := stepService.menuRepository.Find(createStepRequest.MenuId)
+	if err != nil {
+		return res, err
+	}
+
+	step := models.MenuStep{}
+	step.MenuId = menu.Id
+	step.Text = createStepRequest.Text
+	step.DurationMinutes = createStepRequest.DurationMinutes
+	step.Station = createStepRequest.Station
+	step.ImageUrl = createStepRequest.ImageUrl
+
+	step, err = stepService.stepRepository.Create(step)
+	if err != nil {
+		return res, err
+	}
+
+	err = stepService.auditService.Record(createStepRequest.Actor, "menu_step", step.Id, models.AuditActionCreate, nil, step)
+	if err != nil {
+		return res, err
+	}
+
+	return newStepResponse(step), nil
+}
+
+func (stepService *stepService) Update(updateStepRequest request.UpdateStepRequest) (response.StepResponse, error) {
+	res := response.StepResponse{}
+
+	err := stepService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = stepService.withTx(tx).update(updateStepRequest)
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (stepService *stepService) update(updateStepRequest request.UpdateStepRequest) (response.StepResponse, error) {
+	res := response.StepResponse{}
+
+	step, err := stepService.find(updateStepRequest.MenuId, updateStepRequest.Id)
+	if err != nil {
+		return res, err
+	}
+
+	if !versionMatches(updateStepRequest.Version, step.Version) {
+		return res, repository.ErrVersionConflict
+	}
+
+	before := step
+	step.Text = updateStepRequest.Text
+	step.DurationMinutes = updateStepRequest.DurationMinutes
+	step.Station = updateStepRequest.Station
+	step.ImageUrl = updateStepRequest.ImageUrl
+
+	step, err = stepService.stepRepository.Update(step)
+	if err != nil {
+		return res, err
+	}
+
+	err = stepService.auditService.Record(updateStepRequest.Actor, "menu_step", step.Id, models.AuditActionUpdate, before, step)
+	if err != nil {
+		return res, err
+	}
+
+	return newStepResponse(step), nil
+}
+
+func (stepService *stepService) Delete(deleteStepRequest request.DeleteStepRequest) error {
+	return stepService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		return stepService.withTx(tx).delete(deleteStepRequest)
+	})
+}
+
+func (stepService *stepService) delete(deleteStepRequest request.DeleteStepRequest) error {
+	step, err := stepService.find(deleteStepRequest.MenuId, deleteStepRequest.Id)
+	if err != nil {
+		return err
+	}
+
+	if !versionMatches(deleteStepRequest.Version, step.Version) {
+		return repository.ErrVersionConflict
+	}
+
+	err = stepService.stepRepository.Delete(step)
+	if err != nil {
+		return err
+	}
+
+	err = stepService.auditService.Record(deleteStepRequest.Actor, "menu_step", step.Id, models.AuditActionDelete, step, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (stepService *stepService) Reorder(reorderStepRequest request.ReorderStepRequest) ([]response.StepResponse, error) {
+	listRes := []response.StepResponse{}
+
+	err := stepService.transactionManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		listRes, err = stepService.withTx(tx).reorder(reorderStepRequest)
+		return err
+	})
+	if err != nil {
+		return listRes, err
+	}
+
+	return listRes, nil
+}
+
+func (stepService *stepService) reorder(reorderStepRequest request.ReorderStepRequest) ([]response.StepResponse, error) {
+	listStepResponse := []response.StepResponse{}
+
+	_, err := stepService.menuRepository.Find(reorderStepRequest.MenuId)
+	if err != nil {
+		return listStepResponse, err
+	}
+
+	before, err := stepService.stepRepository.All(reorderStepRequest.MenuId)
+	if err != nil {
+		return listStepResponse, err
+	}
+
+	listStep, err := stepService.stepRepository.Reorder(reorderStepRequest.MenuId, reorderStepRequest.StepIds)
+	if err != nil {
+		return listStepResponse, err
+	}
+
+	positions := map[int]int{}
+	for _, step := range before {
+		positions[step.Id] = step.Position
+	}
+
+	for i, step := range listStep {
+		if positions[step.Id] != step.Position {
+			previous := step
+			previous.Position = positions[step.Id]
+			previous.Version = step.Version - 1
+			err = stepService.auditService.Record(reorderStepRequest.Actor, "menu_step", step.Id, models.AuditActionUpdate, previous, listStep[i])
+			if err != nil {
+				return listStepResponse, err
+			}
+		}
+
+		listStepResponse = append(listStepResponse, newStepResponse(step))
+	}
+
+	return listStepResponse, nil
+}
+
+func (stepService *stepService) find(menuId int, id int) (models.MenuStep, error) {
+	step, err := stepService.stepRepository.Find(id)
+	if err != nil {
+		return step, err
+	}
+
+	if step.MenuId != menuId {
+		return step, ErrStepNotInMenu
+	}
+
+	return step, nil
+}
+
+func newStepResponse(step models.MenuStep) response.StepResponse {
+	return response.StepResponse{
+		Id:              step.Id,
+		MenuId:          step.MenuId,
+		Position:        step.Position,
+		Text:            step.Text,
+		DurationMinutes: step.DurationMinutes,
+		Station:         step.Station,
+		ImageUrl:        step.ImageUrl,
+		Version:         step.Version,
+		CreatedAt:       step.CreatedAt,
+		UpdatedAt:       step.UpdatedAt,
+	}
+}
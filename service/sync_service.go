@@ -0,0 +1,206 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/response"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	syncTokenPrefix  = "v1:"
+	defaultSyncLimit = 1000
+	// syncSettleTime is how long a gap in the change sequence is waited on
+	// before it is taken for a rolled back write. It must be longer than the
+	// longest write transaction.
+	syncSettleTime = time.Minute
+)
+
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+type SyncService interface {
+	Get(syncRequest request.SyncRequest) (response.SyncResponse, error)
+}
+
+type syncService struct {
+	changeRepository          repository.ChangeRepository
+	categoryRepository        repository.CategoryRepository
+	ingredientGroupRepository repository.IngredientGroupRepository
+	ingredientRepository      repository.IngredientRepository
+	menuRepository            repository.MenuRepository
+	recipeRepository          repository.RecipeRepository
+	storage                   libraries.Storage
+}
+
+func NewSyncService(changeRepository repository.ChangeRepository, categoryRepository repository.CategoryRepository, ingredientGroupRepository repository.IngredientGroupRepository, ingredientRepository repository.IngredientRepository, menuRepository repository.MenuRepository, recipeRepository repository.RecipeRepository, storage libraries.Storage) SyncService {
+	return &syncService{
+		changeRepository:          changeRepository,
+		categoryRepository:        categoryRepository,
+		ingredientGroupRepository: ingredientGroupRepository,
+		ingredientRepository:      ingredientRepository,
+		menuRepository:            menuRepository,
+		recipeRepository:          recipeRepository,
+		storage:                   storage,
+	}
+}
+
+// Get returns the current state of every entity changed after the token, at
+// most limit changes at a time. Entities are sent whole, so clients simply
+// upsert them; recipe lines travel separately from their menu.
+func (syncService *syncService) Get(syncRequest request.SyncRequest) (response.SyncResponse, error) {
+	res := response.SyncResponse{
+		Categories:       []response.CategoryResponse{},
+		IngredientGroups: []response.IngredientGroupResponse{},
+		Ingredients:      []response.IngredientResponse{},
+		Menus:            []response.MenuResponse{},
+		Recipes:          []response.RecipeResponse{},
+		Deleted:          []response.TombstoneResponse{},
+	}
+
+	since, err := decodeSyncToken(syncRequest.Since)
+	if err != nil {
+		return res, err
+	}
+
+	limit := syncRequest.Limit
+	if limit == 0 {
+		limit = defaultSyncLimit
+	}
+
+	listChange, err := syncService.changeRepository.Since(since, limit, time.Now().Add(-syncSettleTime))
+	if err != nil {
+		return res, err
+	}
+
+	res.Token = encodeSyncToken(since)
+	res.HasMore = len(listChange) == limit
+	if len(listChange) == 0 {
+		return res, nil
+	}
+
+	res.Token = encodeSyncToken(listChange[len(listChange)-1].Id)
+
+	// only the last change of an entity matters to a client catching up
+	lastAction := map[string]map[int]string{}
+	var order []models.Change
+	for _, change := range listChange {
+		if lastAction[change.EntityType] == nil {
+			lastAction[change.EntityType] = map[int]string{}
+		}
+
+		if _, seen := lastAction[change.EntityType][change.EntityId]; !seen {
+			order = append(order, change)
+		}
+		lastAction[change.EntityType][change.EntityId] = change.Action
+	}
+
+	changedIds := map[string][]int{}
+	for _, change := range order {
+		if lastAction[change.EntityType][change.EntityId] == models.AuditActionDelete {
+			res.Deleted = append(res.Deleted, response.TombstoneResponse{Entity: change.EntityType, Id: change.EntityId})
+			continue
+		}
+
+		changedIds[change.EntityType] = append(changedIds[change.EntityType], change.EntityId)
+	}
+
+	found := map[string]map[int]bool{}
+	markFound := func(entityType string, id int) {
+		if found[entityType] == nil {
+			found[entityType] = map[int]bool{}
+		}
+		found[entityType][id] = true
+	}
+
+	listCategory, err := syncService.categoryRepository.AllByIds(changedIds["category"])
+	if err != nil {
+		return res, err
+	}
+	for _, category := range listCategory {
+		res.Categories = append(res.Categories, newCategoryResponse(category, syncService.storage))
+		markFound("category", category.Id)
+	}
+
+	listGroup, err := syncService.ingredientGroupRepository.AllByIds(changedIds["ingredient_group"])
+	if err != nil {
+		return res, err
+	}
+	for _, group := range listGroup {
+		res.IngredientGroups = append(res.IngredientGroups, newIngredientGroupResponse(group))
+		markFound("ingredient_group", group.Id)
+	}
+
+	listIngredient, err := syncService.ingredientRepository.AllByIds(changedIds["ingredient"])
+	if err != nil {
+		return res, err
+	}
+	for _, ingredient := range listIngredient {
+		res.Ingredients = append(res.Ingredients, newIngredientResponse(ingredient))
+		markFound("ingredient", ingredient.Id)
+	}
+
+	listMenu, err := syncService.menuRepository.AllByIds(changedIds["menu"])
+	if err != nil {
+		return res, err
+	}
+	for _, menu := range listMenu {
+		menuResponse := newMenuResponse(menu, menu.Category, syncService.storage)
+		rollUpDietary(&menuResponse, menu)
+		rollUpNutrition(&menuResponse, menu)
+		res.Menus = append(res.Menus, menuResponse)
+		markFound("menu", menu.Id)
+	}
+
+	listRecipe, err := syncService.recipeRepository.AllByIds(changedIds["recipe"])
+	if err != nil {
+		return res, err
+	}
+	for _, recipe := range listRecipe {
+		res.Recipes = append(res.Recipes, newRecipeResponse(recipe))
+		markFound("recipe", recipe.Id)
+	}
+
+	// an entity deleted after this page was read is already gone; tell the
+	// client now instead of sending nothing, the later delete is idempotent
+	for _, change := range order {
+		if lastAction[change.EntityType][change.EntityId] != models.AuditActionDelete && !found[change.EntityType][change.EntityId] {
+			res.Deleted = append(res.Deleted, response.TombstoneResponse{Entity: change.EntityType, Id: change.EntityId})
+		}
+	}
+
+	return res, nil
+}
+
+func encodeSyncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+// decodeSyncToken turns a token back into a change sequence number. An empty
+// token starts a full sync.
+func decodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidSyncToken
+	}
+
+	if !strings.HasPrefix(string(raw), syncTokenPrefix) {
+		return 0, ErrInvalidSyncToken
+	}
+
+	seq, err := strconv.ParseInt(strings.TrimPrefix(string(raw), syncTokenPrefix), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidSyncToken
+	}
+
+	return seq, nil
+}
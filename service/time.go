@@ -0,0 +1,15 @@
+package service
+
+import "time"
+
+const dateLayout = "2006-01-02"
+
+// parseTimestamp parses an optional RFC 3339 timestamp from a query string,
+// returning the zero time when it is empty.
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,9 @@
+package service
+
+import "github.com/erp_app/helper"
+
+// versionMatches reports whether the version a client based its change on is
+// still the current one; an If-Match wildcard matches any version.
+func versionMatches(expected helper.Versions, current int) bool {
+	return expected.Match(current)
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/erp_app/helper"
+	"github.com/erp_app/models"
+)
+
+// recipeYield is the edible share of a recipe line: the override on the line,
+// otherwise the yield of its ingredient.
+func recipeYield(recipe models.MenuIngredient) float64 {
+	if recipe.YieldPercent != nil && *recipe.YieldPercent > 0 {
+		return *recipe.YieldPercent
+	}
+
+	if recipe.Ingredient.YieldPercent > 0 {
+		return recipe.Ingredient.YieldPercent
+	}
+
+	return 100
+}
+
+// asPurchased turns the edible portion quantity of a recipe line, multiplied
+// by factor, into the quantity to buy. The result is not rounded so totals
+// stay exact; round it with ScaleUp(1). Recipe quantities are edible portions,
+// so anything computed from them in purchase terms, like purchasing, costing
+// or stock deduction, has to go through here.
+func asPurchased(quantity helper.Quantity, factor float64, recipe models.MenuIngredient) helper.Quantity {
+	return helper.Quantity{Amount: quantity.Amount * factor * 100 / recipeYield(recipe), Unit: quantity.Unit}
+}
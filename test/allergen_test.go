@@ -0,0 +1,134 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func createExampleAllergenMenus(db *gorm.DB) {
+	createBulkExampleCategory(db)
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/ingredient", setupIngredientController(db).Create)
+	for _, body := range []string{
+		`{"name": "kacang tanah", "allergens": ["peanut"], "vegan": true, "halal": true, "gluten_free": true}`,
+		`{"name": "tepung terigu", "allergens": ["gluten"], "vegan": true, "halal": true}`,
+		`{"name": "beras", "vegan": true, "halal": true, "gluten_free": true}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/ingredient", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	for _, name := range []string{"gado gado", "mie goreng", "nasi putih"} {
+		db.Create(&models.Menu{Name: name, CategoryId: 1})
+	}
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "50 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 3, Qty: "100 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 2, IngredientId: 2, Qty: "100 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 3, IngredientId: 3, Qty: "150 gr"})
+}
+
+// test allergen dan flag diet dijumlahkan ke menu
+func TestMenuAllergenRollUp(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleAllergenMenus(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id", setupMenuController(db).Get)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"peanut"}, data["allergens"])
+	assert.Equal(t, true, data["vegan"])
+	assert.Equal(t, true, data["gluten_free"])
+}
+
+// test filter menu tanpa allergen tertentu
+func TestGetAllMenuExcludeAllergens(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleAllergenMenus(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu", setupMenuController(db).GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu?exclude_allergens=peanut,gluten", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].([]interface{})
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "nasi putih", data[0].(map[string]interface{})["name"])
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu?exclude_allergens=kerupuk", nil)
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 400, rec.Result().StatusCode)
+}
+
+// test bahan mengandung gluten tidak boleh gluten free
+func TestCreateIngredientGlutenFreeWithGluten(t *testing.T) {
+	db := database.SetDbTest()
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/ingredient", setupIngredientController(db).Create)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/ingredient", strings.NewReader(`{"name": "roti", "allergens": ["gluten"], "gluten_free": true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 400, rec.Result().StatusCode)
+}
+
+// test perubahan allergen bahan ikut menandai menu yang memakainya
+func TestUpdateIngredientAllergenMarksMenus(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleAllergenMenus(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/ingredient/:id", setupIngredientController(db).Update)
+
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/ingredient/3", `{"name": "beras", "allergens": ["kerupuk"]}`)
+	assert.Equal(t, 422, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/ingredient/3", `{"name": "beras", "allergens": ["sesame"]}`)
+	assert.Equal(t, 201, status)
+
+	var menuIds []int
+	db.Model(&models.Change{}).Where("entity_type = ? AND action = ?", "menu", models.AuditActionUpdate).Order("entity_id").Pluck("entity_id", &menuIds)
+	assert.Equal(t, []int{1, 3}, menuIds)
+}
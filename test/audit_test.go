@@ -0,0 +1,141 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupAuditService(db *gorm.DB) service.AuditService {
+	auditRepository := repository.NewAuditRepository(db)
+	return service.NewAuditService(auditRepository)
+}
+
+func setupAuditController(db *gorm.DB) *controllers.AuditController {
+	return controllers.NewAuditController(setupAuditService(db))
+}
+
+// test perubahan category tercatat di audit log
+func TestGetAllAuditAfterUpdateCategory(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+
+	categoryController := setupCategoryController(db)
+	auditController := setupAuditController(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/category/:id", categoryController.Update)
+	router.GET("api/v1/audit", auditController.GetAll)
+
+	updateRequestJson := `{
+  "name" : "minuman"
+}`
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/category/1", strings.NewReader(updateRequestJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderActor, "budi")
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 201, rec.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/audit?entity=category&id=1", nil)
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data struct {
+		Data []struct {
+			Actor   string                            `json:"actor"`
+			Action  string                            `json:"action"`
+			Changes map[string]map[string]interface{} `json:"changes"`
+		} `json:"data"`
+	}
+
+	err := json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	assert.Len(t, data.Data, 1)
+	assert.Equal(t, "budi", data.Data[0].Actor)
+	assert.Equal(t, "update", data.Data[0].Action)
+	assert.Equal(t, "category 1", data.Data[0].Changes["name"]["before"])
+	assert.Equal(t, "minuman", data.Data[0].Changes["name"]["after"])
+
+	fmt.Println(data)
+}
+
+// test filter entity tidak dikenal
+func TestGetAllAuditFailValidation(t *testing.T) {
+	db := database.SetDbTest()
+
+	auditController := setupAuditController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/audit", auditController.GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/audit?entity=stock&from=2023-13-01", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 422, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data map[string]interface{}
+
+	err := json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	fmt.Println(data)
+}
+
+// test perubahan dibatalkan kalau audit log gagal ditulis
+func TestAuditFailureRollsBackChange(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+
+	categoryController := setupCategoryController(db)
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/category", categoryController.Create)
+	router.PUT("api/v1/category/:id", categoryController.Update)
+
+	db.Exec("DROP TABLE audit_logs")
+
+	status, _ := requestDisplayOrder(router, http.MethodPost, "/api/v1/category", `{"name": "jajanan"}`)
+	assert.NotEqual(t, 201, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/category/1", `{"name": "jajanan"}`)
+	assert.NotEqual(t, 201, status)
+
+	var count int64
+	db.Model(&models.Category{}).Where("name = ?", "jajanan").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	category := models.Category{}
+	db.First(&category, 1)
+	assert.Equal(t, 1, category.Version)
+
+	db.Model(&models.Change{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bulkResponseBody struct {
+	Data struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+		Items     []struct {
+			Index  int      `json:"index"`
+			Status string   `json:"status"`
+			Errors []string `json:"errors"`
+		} `json:"items"`
+	} `json:"data"`
+}
+
+func setupBulkController(db *gorm.DB) *controllers.BulkController {
+	auditService := setupAuditService(db)
+	categoryRepository := repository.NewCategoryRepository(db)
+	ingredientRepository := repository.NewIngredientRepository(db)
+	menuRepository := repository.NewMenuRepository(db)
+	recipeRepository := repository.NewRecipeRepository(db)
+	transactionManager := repository.NewTransactionManager(db)
+
+	bulkService := service.NewBulkService(
+		transactionManager,
+		service.NewCategoryService(transactionManager, categoryRepository, auditService, setupStorage()),
+		service.NewIngredientService(transactionManager, ingredientRepository, repository.NewIngredientGroupRepository(db), auditService),
+		service.NewMenuService(transactionManager, menuRepository, categoryRepository, auditService, setupStorage()),
+		service.NewRecipeService(transactionManager, recipeRepository, repository.NewRecipeVersionRepository(db), menuRepository, ingredientRepository, auditService, service.DuplicateLinesReject),
+	)
+	return controllers.NewBulkController(bulkService)
+}
+
+func sendBulk(t *testing.T, router *echo.Echo, method string, url string, body string, expectedStatus int) bulkResponseBody {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, expectedStatus, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data bulkResponseBody
+	err := json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	fmt.Println(data)
+
+	return data
+}
+
+func countIngredients(db *gorm.DB) int64 {
+	var total int64
+	db.Model(&models.Ingredient{}).Count(&total)
+	return total
+}
+
+// test bulk create ingredient berhasil dalam satu transaksi
+func TestBulkCreateIngredientSuccess(t *testing.T) {
+	db := database.SetDbTest()
+	router := libraries.SetRouter()
+	router.POST("api/v1/ingredient/bulk", setupBulkController(db).CreateIngredients)
+
+	data := sendBulk(t, router, http.MethodPost, "http://localhost:8000/api/v1/ingredient/bulk", `{
+  "items" : [{"name" : "gula"}, {"name" : "garam"}, {"name" : "kopi"}]
+}`, 200)
+
+	assert.Equal(t, 3, data.Data.Succeeded)
+	assert.Equal(t, 0, data.Data.Failed)
+	assert.Equal(t, int64(3), countIngredients(db))
+}
+
+// test bulk create ingredient gagal validasi, tidak ada yang tersimpan
+func TestBulkCreateIngredientFailValidation(t *testing.T) {
+	db := database.SetDbTest()
+	router := libraries.SetRouter()
+	router.POST("api/v1/ingredient/bulk", setupBulkController(db).CreateIngredients)
+
+	data := sendBulk(t, router, http.MethodPost, "http://localhost:8000/api/v1/ingredient/bulk", `{
+  "items" : [{"name" : "gula"}, {"name" : ""}, {"name" : "kopi"}]
+}`, 422)
+
+	assert.Equal(t, 1, data.Data.Failed)
+	assert.Equal(t, "skipped", data.Data.Items[0].Status)
+	assert.Equal(t, "error", data.Data.Items[1].Status)
+	assert.NotEmpty(t, data.Data.Items[1].Errors)
+	assert.Equal(t, int64(0), countIngredients(db))
+}
+
+// test bulk create ingredient best effort tetap menyimpan item yang valid
+func TestBulkCreateIngredientBestEffort(t *testing.T) {
+	db := database.SetDbTest()
+	router := libraries.SetRouter()
+	router.POST("api/v1/ingredient/bulk", setupBulkController(db).CreateIngredients)
+
+	data := sendBulk(t, router, http.MethodPost, "http://localhost:8000/api/v1/ingredient/bulk", `{
+  "best_effort" : true,
+  "items" : [{"name" : "gula"}, {"name" : ""}, {"name" : "kopi"}]
+}`, 207)
+
+	assert.Equal(t, 2, data.Data.Succeeded)
+	assert.Equal(t, 1, data.Data.Failed)
+	assert.Equal(t, int64(2), countIngredients(db))
+}
+
+// test bulk update category di-rollback jika salah satu item konflik versi
+func TestBulkUpdateCategoryRollback(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/category/bulk", setupBulkController(db).UpdateCategories)
+
+	data := sendBulk(t, router, http.MethodPut, "http://localhost:8000/api/v1/category/bulk", `{
+  "items" : [{"id" : 1, "name" : "makanan", "version" : 1}, {"id" : 2, "name" : "minuman", "version" : 5}]
+}`, 400)
+
+	assert.Equal(t, "rolled_back", data.Data.Items[0].Status)
+	assert.Equal(t, "error", data.Data.Items[1].Status)
+
+	category := models.Category{}
+	db.First(&category, 1)
+	assert.Equal(t, "category 1", category.Name)
+	assert.Equal(t, 1, category.Version)
+}
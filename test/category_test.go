@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/erp_app/controllers"
 	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
 	"github.com/erp_app/libraries"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
@@ -18,16 +19,17 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func setupCategoryController(db *gorm.DB) *controllers.CategoryController {
 	categoryRepository := repository.NewCategoryRepository(db)
-	categoryService := service.NewCategoryService(categoryRepository)
+	categoryService := service.NewCategoryService(repository.NewTransactionManager(db), categoryRepository, setupAuditService(db), setupStorage())
 	return controllers.NewCategoryController(categoryService)
 }
 
 func truncateDataCategory(db *gorm.DB) {
-	err := db.Exec("TRUNCATE TABLE CATEGORIES").Error
+	err := database.Truncate(db, "categories")
 	if err != nil {
 		panic(err.Error())
 	}
@@ -275,6 +277,7 @@ func TestUpdateSuccess(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/categories/1", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -309,6 +312,7 @@ func TestUpdateFailInvalidParameter(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/categories/ss", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -343,6 +347,7 @@ func TestUpdateFailNotParameter(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/categories", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -377,6 +382,7 @@ func TestUpdateFailNotFoundDataForUpdate(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/categories/99", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -407,6 +413,7 @@ func TestDeleteSuccess(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/categories/10", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -437,6 +444,7 @@ func TestDeleteFailNotFoundData(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/categories/11", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -467,6 +475,7 @@ func TestDeleteFailNotExistsParameter(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/categories/", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -484,3 +493,89 @@ func TestDeleteFailNotExistsParameter(t *testing.T) {
 
 	fmt.Println(data)
 }
+
+// testing pencarian nama tanpa membedakan huruf besar kecil
+func TestGetAllWithStringQueryCaseInsensitive(t *testing.T) {
+	db := database.SetDbTest()
+	truncateDataCategory(db)
+	createBulkExampleCategory(db)
+
+	categorycontroller := setupCategoryController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/categories", categorycontroller.GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/categories?name=CATEGORY+1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	body := result.Body
+
+	responseBody, _ := io.ReadAll(body)
+	var data map[string]interface{}
+
+	err := json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	// category 1 dan category 10
+	assert.Len(t, data["data"], 2)
+
+	fmt.Println(data)
+}
+
+// test filter updated_since hanya mengembalikan category yang berubah
+func TestGetAllWithUpdatedSince(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+
+	lastSync := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := db.Model(&models.Category{}).Where("1 = 1").UpdateColumn("updated_at", lastSync.Add(-time.Hour)).Error
+	assert.NoError(t, err)
+
+	categorycontroller := setupCategoryController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/categories", categorycontroller.GetAll)
+	router.PUT("api/v1/categories/:id", categorycontroller.Update)
+
+	updateRequestJson := `{
+  "name" : "category 99"
+}`
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/categories/3", strings.NewReader(updateRequestJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 201, rec.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/categories?updated_since="+lastSync.Format(time.RFC3339), nil)
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data struct {
+		Data []struct {
+			Id        int       `json:"id"`
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"data"`
+	}
+
+	err = json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	assert.Len(t, data.Data, 1)
+	assert.Equal(t, 3, data.Data[0].Id)
+	assert.True(t, data.Data[0].UpdatedAt.After(lastSync))
+
+	fmt.Println(data)
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func parentId(id int) *int {
+	return &id
+}
+
+// Drinks -> Hot -> Coffee, Drinks -> Cold, Food
+func createExampleCategoryTree(db *gorm.DB) {
+	db.Create(&models.Category{Name: "Drinks"})
+	db.Create(&models.Category{Name: "Hot", ParentId: parentId(1)})
+	db.Create(&models.Category{Name: "Coffee", ParentId: parentId(2)})
+	db.Create(&models.Category{Name: "Cold", ParentId: parentId(1)})
+	db.Create(&models.Category{Name: "Food"})
+}
+
+func requestCategoryTree(db *gorm.DB, method string, target string, body string) (int, map[string]interface{}) {
+	categoryController := setupCategoryController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/category/tree", categoryController.Tree)
+	router.GET("api/v1/category/:id", categoryController.Get)
+	router.POST("api/v1/category", categoryController.Create)
+	router.PUT("api/v1/category/:id", categoryController.Update)
+	router.DELETE("api/v1/category/:id", categoryController.Delete)
+
+	req := httptest.NewRequest(method, "http://localhost:8000"+target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return result.StatusCode, responseBodyMap
+}
+
+// test pohon kategori
+func TestCategoryTree(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleCategoryTree(db)
+
+	status, responseBodyMap := requestCategoryTree(db, http.MethodGet, "/api/v1/category/tree", "")
+	assert.Equal(t, 200, status)
+
+	roots := responseBodyMap["data"].([]interface{})
+	assert.Equal(t, 2, len(roots))
+	drinks := roots[0].(map[string]interface{})
+	assert.Equal(t, "Drinks", drinks["name"])
+
+	children := drinks["children"].([]interface{})
+	hot := children[0].(map[string]interface{})
+	assert.Equal(t, "Hot", hot["name"])
+	assert.Equal(t, "Cold", children[1].(map[string]interface{})["name"])
+	assert.Equal(t, "Coffee", hot["children"].([]interface{})[0].(map[string]interface{})["name"])
+}
+
+// test breadcrumb kategori
+func TestCategoryBreadcrumb(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleCategoryTree(db)
+
+	status, responseBodyMap := requestCategoryTree(db, http.MethodGet, "/api/v1/category/3", "")
+	assert.Equal(t, 200, status)
+
+	breadcrumb := responseBodyMap["data"].(map[string]interface{})["breadcrumb"].([]interface{})
+	assert.Equal(t, 3, len(breadcrumb))
+	assert.Equal(t, "Drinks", breadcrumb[0].(map[string]interface{})["name"])
+	assert.Equal(t, "Hot", breadcrumb[1].(map[string]interface{})["name"])
+	assert.Equal(t, "Coffee", breadcrumb[2].(map[string]interface{})["name"])
+}
+
+// test kategori tidak boleh dipindah ke bawah dirinya sendiri
+func TestUpdateCategoryCycle(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleCategoryTree(db)
+
+	status, _ := requestCategoryTree(db, http.MethodPut, "/api/v1/category/1", `{"name": "Drinks", "parent_id": 3}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = requestCategoryTree(db, http.MethodPut, "/api/v1/category/1", `{"name": "Drinks", "parent_id": 1}`)
+	assert.Equal(t, 400, status)
+
+	status, responseBodyMap := requestCategoryTree(db, http.MethodPut, "/api/v1/category/2", `{"name": "Hot", "parent_id": 0}`)
+	assert.Equal(t, 201, status)
+	assert.Nil(t, responseBodyMap["data"].(map[string]interface{})["parent_id"])
+}
+
+// test kategori yang masih punya sub kategori tidak boleh dihapus
+func TestDeleteCategoryWithChildren(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleCategoryTree(db)
+
+	status, _ := requestCategoryTree(db, http.MethodDelete, "/api/v1/category/2", "")
+	assert.Equal(t, 400, status)
+
+	status, _ = requestCategoryTree(db, http.MethodDelete, "/api/v1/category/3", "")
+	assert.Equal(t, 200, status)
+}
+
+// test filter menu dengan sub kategori
+func TestGetAllMenuIncludeDescendants(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleCategoryTree(db)
+	db.Create(&models.Menu{Name: "teh manis", CategoryId: 2})
+	db.Create(&models.Menu{Name: "kopi tubruk", CategoryId: 3})
+	db.Create(&models.Menu{Name: "es jeruk", CategoryId: 4})
+	db.Create(&models.Menu{Name: "nasi goreng", CategoryId: 5})
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu", setupMenuController(db).GetAll)
+
+	for target, expected := range map[string]int{
+		"/api/v1/menu?category_id=2":                          1,
+		"/api/v1/menu?category_id=2&include_descendants=true": 2,
+		"/api/v1/menu?category_id=1&include_descendants=true": 3,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8000"+target, nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		assert.Equal(t, 200, result.StatusCode)
+
+		responseBody, _ := io.ReadAll(result.Body)
+		var responseBodyMap map[string]interface{}
+		json.Unmarshal(responseBody, &responseBodyMap)
+
+		assert.Equal(t, expected, len(responseBodyMap["data"].([]interface{})), target)
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupMenuCloneController(db *gorm.DB) *controllers.MenuCloneController {
+	menuCloneService := service.NewMenuCloneService(repository.NewTransactionManager(db), repository.NewMenuRepository(db), repository.NewCategoryRepository(db), repository.NewIngredientRepository(db), repository.NewRecipeRepository(db), repository.NewStepRepository(db), setupAuditService(db), setupStorage())
+	return controllers.NewMenuCloneController(menuCloneService)
+}
+
+func createExampleMenuForClone(db *gorm.DB) {
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+	db.Create(&models.Menu{Name: "nasi goreng", CategoryId: 1, YieldQty: "400 gr", Portions: 2})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "300 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "2 butir"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 3, Qty: "secukupnya"})
+	db.Create(&models.MenuStep{MenuId: 1, Position: 1, Text: "tumis bumbu"})
+	db.Create(&models.MenuStep{MenuId: 1, Position: 2, Text: "masukkan nasi"})
+}
+
+func cloneMenu(db *gorm.DB, body string) (int, map[string]interface{}) {
+	router := libraries.SetRouter()
+	router.POST("api/v1/menu/:id/clone", setupMenuCloneController(db).Clone)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/menu/1/clone", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return result.StatusCode, responseBodyMap
+}
+
+// test clone menu dengan skala dan ganti bahan
+func TestCloneMenu(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleMenuForClone(db)
+
+	status, responseBodyMap := cloneMenu(db, `{"name": "nasi goreng seafood", "category_id": 2, "portions": 4, "swaps": [{"from": 2, "to": 5}]}`)
+	assert.Equal(t, 201, status)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	assert.Equal(t, float64(2), data["id"])
+	assert.Equal(t, "nasi goreng seafood", data["name"])
+	assert.Equal(t, float64(2), data["category_id"])
+	assert.Equal(t, float64(4), data["portions"])
+	assert.Equal(t, "800 g", data["yield_qty"])
+
+	ingredients := data["ingredients"].([]interface{})
+	assert.Equal(t, 3, len(ingredients))
+	assert.Equal(t, "600 g", ingredients[0].(map[string]interface{})["qty"])
+	assert.Equal(t, float64(5), ingredients[1].(map[string]interface{})["id"])
+	assert.Equal(t, "4 butir", ingredients[1].(map[string]interface{})["qty"])
+	assert.Equal(t, "secukupnya", ingredients[2].(map[string]interface{})["qty"])
+
+	steps := data["steps"].([]interface{})
+	assert.Equal(t, 2, len(steps))
+	assert.Equal(t, "masukkan nasi", steps[1].(map[string]interface{})["text"])
+
+	// menu asli tidak berubah
+	var count int64
+	db.Model(&models.MenuIngredient{}).Where("menu_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+// test clone menu dengan bahan yang tidak ada di resep
+func TestCloneMenuSwapNotInMenu(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleMenuForClone(db)
+
+	status, _ := cloneMenu(db, `{"name": "nasi goreng kampung", "swaps": [{"from": 7, "to": 5}]}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = cloneMenu(db, `{"name": "nasi goreng kampung", "swaps": [{"from": 2, "to": 1}]}`)
+	assert.Equal(t, 400, status)
+
+	var count int64
+	db.Model(&models.Menu{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+// test yield khusus resep ikut di-clone kecuali untuk bahan yang diganti
+func TestCloneMenuSwapDropsYieldOverride(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleMenuForClone(db)
+	yield := 80.0
+	db.Model(&models.MenuIngredient{}).Where("menu_id = ?", 1).Update("yield_percent", yield)
+
+	status, _ := cloneMenu(db, `{"name": "nasi goreng seafood", "category_id": 2, "swaps": [{"from": 2, "to": 5}]}`)
+	assert.Equal(t, 201, status)
+
+	var lines []models.MenuIngredient
+	db.Where("menu_id = ?", 2).Order("id").Find(&lines)
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, yield, *lines[0].YieldPercent)
+	assert.Equal(t, 5, lines[1].IngredientId)
+	assert.Nil(t, lines[1].YieldPercent)
+	assert.Equal(t, yield, *lines[2].YieldPercent)
+}
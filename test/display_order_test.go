@@ -0,0 +1,165 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupDisplayOrderRouter(db *gorm.DB) *echo.Echo {
+	categoryController := setupCategoryController(db)
+	menuController := setupMenuController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/category", categoryController.GetAll)
+	router.POST("api/v1/category", categoryController.Create)
+	router.PUT("api/v1/category/order", categoryController.Reorder)
+	router.GET("api/v1/menu", menuController.GetAll)
+	router.POST("api/v1/menu", menuController.Create)
+	router.PUT("api/v1/menu/order", menuController.Reorder)
+	router.PUT("api/v1/menu/:id", menuController.Update)
+	return router
+}
+
+func requestDisplayOrder(router *echo.Echo, method string, target string, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, "http://localhost:8000"+target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return result.StatusCode, responseBodyMap
+}
+
+func listNames(responseBodyMap map[string]interface{}) []string {
+	var names []string
+	for _, item := range responseBodyMap["data"].([]interface{}) {
+		names = append(names, item.(map[string]interface{})["name"].(string))
+	}
+
+	return names
+}
+
+func createExampleDisplayOrder(router *echo.Echo) {
+	for _, name := range []string{"makanan", "minuman", "snack"} {
+		requestDisplayOrder(router, http.MethodPost, "/api/v1/category", `{"name": "`+name+`"}`)
+	}
+	for _, body := range []string{
+		`{"name": "nasi goreng", "category_id": 1}`,
+		`{"name": "es teh", "category_id": 2}`,
+		`{"name": "mie goreng", "category_id": 1}`,
+		`{"name": "kopi", "category_id": 2}`,
+		`{"name": "kentang goreng", "category_id": 3}`,
+	} {
+		requestDisplayOrder(router, http.MethodPost, "/api/v1/menu", body)
+	}
+}
+
+// test urutan tampilan kategori
+func TestReorderCategory(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupDisplayOrderRouter(db)
+	createExampleDisplayOrder(router)
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPut, "/api/v1/category/order", `{"category_ids": [2, 3, 1]}`)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, []string{"minuman", "snack", "makanan"}, listNames(responseBodyMap))
+
+	_, responseBodyMap = requestDisplayOrder(router, http.MethodGet, "/api/v1/category", "")
+	assert.Equal(t, []string{"minuman", "snack", "makanan"}, listNames(responseBodyMap))
+
+	// semua kategori harus disebut
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/category/order", `{"category_ids": [2, 1]}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/category/order", `{"category_ids": [2, 2, 1]}`)
+	assert.Equal(t, 400, status)
+}
+
+// test urutan tampilan menu mengikuti urutan kategori
+func TestReorderMenu(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupDisplayOrderRouter(db)
+	createExampleDisplayOrder(router)
+
+	_, responseBodyMap := requestDisplayOrder(router, http.MethodGet, "/api/v1/menu", "")
+	assert.Equal(t, []string{"nasi goreng", "mie goreng", "es teh", "kopi", "kentang goreng"}, listNames(responseBodyMap))
+
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/order", `{"category_id": 1, "menu_ids": [3, 1]}`)
+	assert.Equal(t, 200, status)
+	requestDisplayOrder(router, http.MethodPut, "/api/v1/category/order", `{"category_ids": [2, 1, 3]}`)
+
+	_, responseBodyMap = requestDisplayOrder(router, http.MethodGet, "/api/v1/menu", "")
+	assert.Equal(t, []string{"es teh", "kopi", "mie goreng", "nasi goreng", "kentang goreng"}, listNames(responseBodyMap))
+
+	// menu yang pindah kategori ditaruh paling akhir
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/5", `{"name": "kentang goreng", "category_id": 2}`)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, float64(3), responseBodyMap["data"].(map[string]interface{})["position"])
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/order", `{"category_id": 2, "menu_ids": [2, 4]}`)
+	assert.Equal(t, 400, status)
+}
+
+// test urutan tampilan mengikuti pohon kategori, termasuk export
+func TestDisplayOrderFollowsCategoryTree(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupDisplayOrderRouter(db)
+
+	// makanan -> nasi, minuman -> kopi; posisi sub kategori mulai dari 1 lagi
+	for _, body := range []string{
+		`{"name": "makanan"}`,
+		`{"name": "minuman"}`,
+		`{"name": "kopi", "parent_id": 2}`,
+		`{"name": "nasi", "parent_id": 1}`,
+	} {
+		requestDisplayOrder(router, http.MethodPost, "/api/v1/category", body)
+	}
+	for _, body := range []string{
+		`{"name": "espresso", "category_id": 3}`,
+		`{"name": "es teh", "category_id": 2}`,
+		`{"name": "nasi uduk", "category_id": 4}`,
+		`{"name": "kerupuk", "category_id": 1}`,
+	} {
+		requestDisplayOrder(router, http.MethodPost, "/api/v1/menu", body)
+	}
+
+	_, responseBodyMap := requestDisplayOrder(router, http.MethodGet, "/api/v1/category", "")
+	assert.Equal(t, []string{"makanan", "nasi", "minuman", "kopi"}, listNames(responseBodyMap))
+
+	_, responseBodyMap = requestDisplayOrder(router, http.MethodGet, "/api/v1/menu", "")
+	assert.Equal(t, []string{"kerupuk", "nasi uduk", "es teh", "espresso"}, listNames(responseBodyMap))
+
+	// export memakai urutan yang sama dengan daftar
+	requestDisplayOrder(router, http.MethodPut, "/api/v1/category/order", `{"category_ids": [2, 1]}`)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu", nil)
+	req.Header.Set(echo.HeaderAccept, helper.MIMEApplicationNdjson)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		menu := map[string]interface{}{}
+		json.Unmarshal([]byte(line), &menu)
+		names = append(names, menu["name"].(string))
+	}
+	assert.Equal(t, []string{"es teh", "espresso", "kerupuk", "nasi uduk"}, names)
+}
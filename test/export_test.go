@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// test export category ke csv lewat query format
+func TestExportCategoryCsv(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/category", setupCategoryController(db).GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/category?format=csv", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, helper.MIMETextCSV, result.Header.Get(echo.HeaderContentType))
+	assert.Contains(t, result.Header.Get(echo.HeaderContentDisposition), "categories.csv")
+
+	records, err := csv.NewReader(result.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 11)
+	assert.Equal(t, []string{"id", "name", "version", "created_at", "updated_at"}, records[0])
+	assert.Equal(t, "category 1", records[1][1])
+
+	fmt.Println(records)
+}
+
+// test export menu ke ndjson lewat header accept, satu baris per menu
+func TestExportMenuNdjson(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+
+	menu := models.Menu{Name: "nasi goreng", CategoryId: 1}
+	db.Create(&menu)
+	db.Create(&models.MenuIngredient{MenuId: menu.Id, IngredientId: 1, Qty: "1"})
+	db.Create(&models.MenuIngredient{MenuId: menu.Id, IngredientId: 2, Qty: "2"})
+	db.Create(&models.Menu{Name: "es teh", CategoryId: 2})
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu", setupMenuController(db).GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu", nil)
+	req.Header.Set(echo.HeaderAccept, helper.MIMEApplicationNdjson)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(result.Body)
+	for scanner.Scan() {
+		line := map[string]interface{}{}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		assert.NoError(t, err)
+		lines = append(lines, line)
+	}
+
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "nasi goreng", lines[0]["name"])
+	assert.Len(t, lines[0]["ingredients"], 2)
+
+	fmt.Println(lines)
+}
+
+// test export ingredient ke xlsx
+func TestExportIngredientXlsx(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleIngredient(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/ingredient", setupIngredientController(db).GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/ingredient?format=xlsx&name=ingredient+1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	file, err := excelize.OpenReader(result.Body)
+	assert.NoError(t, err)
+
+	rows, err := file.GetRows("ingredients")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "ingredient 10", rows[2][1])
+
+	fmt.Println(rows)
+}
+
+// test export audit memakai urutan yang sama dengan daftar json, terbaru dulu
+func TestExportAuditNewestFirst(t *testing.T) {
+	db := database.SetDbTest()
+	now := time.Now()
+	db.Create(&models.AuditLog{Actor: "budi", EntityType: "category", EntityId: 1, Action: models.AuditActionCreate, Changes: "{}", CreatedAt: now.Add(-2 * time.Hour)})
+	db.Create(&models.AuditLog{Actor: "budi", EntityType: "category", EntityId: 1, Action: models.AuditActionDelete, Changes: "{}", CreatedAt: now})
+	db.Create(&models.AuditLog{Actor: "budi", EntityType: "category", EntityId: 1, Action: models.AuditActionUpdate, Changes: "{}", CreatedAt: now.Add(-time.Hour)})
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/audit", setupAuditController(db).GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/audit?format=csv", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+	assert.Equal(t, []string{"2", "3", "1"}, []string{records[1][0], records[2][0], records[3][0]})
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/audit", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var responseBodyMap map[string][]map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &responseBodyMap)
+	assert.Len(t, responseBodyMap["data"], 3)
+	for i, auditLog := range responseBodyMap["data"] {
+		assert.Equal(t, records[i+1][0], fmt.Sprint(auditLog["id"]))
+	}
+}
+
+// test export gagal karena database sebelum ada data terkirim
+func TestExportFailDatabase(t *testing.T) {
+	db := database.SetDbTest()
+	db.Migrator().DropTable("categories")
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/category", setupCategoryController(db).GetAll)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/category?format=csv", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 500, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+
+	// writer xlsx dilepas tanpa menulis file setengah jadi
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/category?format=xlsx", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 500, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+
+	// filter yang salah tetap 400
+	router.GET("api/v1/menu", setupMenuController(db).GetAll)
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu?format=csv&exclude_allergens=kerikil", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 400, rec.Code)
+}
@@ -0,0 +1,262 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/request"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupStorage is the storage of tests that do not upload images themselves.
+func setupStorage() libraries.Storage {
+	return libraries.NewLocalStorage(filepath.Join(os.TempDir(), "erp_app_uploads"), "/uploads")
+}
+
+func setupImageRouter(db *gorm.DB, dir string) *echo.Echo {
+	storage := libraries.NewLocalStorage(dir, "/uploads")
+	imageService := service.NewImageService(repository.NewTransactionManager(db), storage, repository.NewMenuRepository(db), repository.NewCategoryRepository(db), setupAuditService(db), 64<<10, 320)
+	imageController := controllers.NewImageController(imageService, 64<<10)
+
+	router := libraries.SetRouter()
+	router.Static("/uploads", dir)
+	router.PUT("api/v1/menu/:id/image", imageController.PutMenu)
+	router.DELETE("api/v1/menu/:id/image", imageController.DeleteMenu)
+	router.PUT("api/v1/category/:id/image", imageController.PutCategory)
+	router.DELETE("api/v1/category/:id/image", imageController.DeleteCategory)
+	return router
+}
+
+func examplePng(width int, height int) []byte {
+	picture := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			picture.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+
+	content := &bytes.Buffer{}
+	png.Encode(content, picture)
+	return content.Bytes()
+}
+
+func requestImage(router *echo.Echo, method string, target string, version int, field string, content []byte) (int, map[string]interface{}) {
+	body := &bytes.Buffer{}
+	contentType := ""
+	if content != nil {
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile(field, "foto.png")
+		part.Write(content)
+		writer.Close()
+		contentType = writer.FormDataContentType()
+	}
+
+	req := httptest.NewRequest(method, "http://localhost:8000"+target, body)
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	if version > 0 {
+		req.Header.Set(helper.HeaderIfMatch, helper.ETag(version))
+	}
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return result.StatusCode, responseBodyMap
+}
+
+func storedFile(dir string, url string) string {
+	return filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(url, "/uploads/")))
+}
+
+// test upload foto menu menyimpan file asli dan thumbnail
+func TestPutMenuImage(t *testing.T) {
+	db := database.SetDbTest()
+	dir := t.TempDir()
+	router := setupImageRouter(db, dir)
+	createBulkExampleCategory(db)
+	db.Create(&models.Menu{Name: "nasi goreng", CategoryId: 1})
+
+	status, responseBodyMap := requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "image", examplePng(640, 480))
+	assert.Equal(t, 201, status)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	imageUrl := data["image_url"].(string)
+	thumbnailUrl := data["thumbnail_url"].(string)
+	assert.True(t, strings.HasPrefix(imageUrl, "/uploads/menus/1/"))
+	assert.True(t, strings.HasSuffix(imageUrl, ".png"))
+	assert.True(t, strings.HasSuffix(thumbnailUrl, "_thumb.jpg"))
+	assert.Equal(t, float64(2), data["version"])
+
+	// thumbnail diperkecil ke lebar 320 dengan perbandingan yang sama
+	thumbnail, err := os.Open(storedFile(dir, thumbnailUrl))
+	assert.Nil(t, err)
+	config, err := jpeg.DecodeConfig(thumbnail)
+	thumbnail.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 320, config.Width)
+	assert.Equal(t, 240, config.Height)
+
+	// file bisa diambil lewat url yang dikembalikan
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000"+imageUrl, nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+
+	// upload ulang mengganti foto lama dan menghapus filenya
+	status, responseBodyMap = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 2, "image", examplePng(100, 50))
+	assert.Equal(t, 201, status)
+	assert.NotEqual(t, imageUrl, responseBodyMap["data"].(map[string]interface{})["image_url"])
+
+	_, err = os.Stat(storedFile(dir, imageUrl))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(storedFile(dir, thumbnailUrl))
+	assert.True(t, os.IsNotExist(err))
+
+	var audits int64
+	db.Model(&models.AuditLog{}).Where("entity_type = ? AND action = ?", "menu", models.AuditActionUpdate).Count(&audits)
+	assert.Equal(t, int64(2), audits)
+}
+
+// test upload yang bukan gambar, terlalu besar atau tanpa If-Match ditolak
+func TestPutMenuImageRejected(t *testing.T) {
+	db := database.SetDbTest()
+	dir := t.TempDir()
+	router := setupImageRouter(db, dir)
+	createBulkExampleCategory(db)
+	db.Create(&models.Menu{Name: "nasi goreng", CategoryId: 1})
+
+	status, _ := requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "image", []byte("bukan gambar"))
+	assert.Equal(t, 415, status)
+
+	// nama file .png tidak cukup, isi file yang diperiksa
+	status, _ = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "image", []byte("\x89PNG\r\n\x1a\nrusak"))
+	assert.Equal(t, 400, status)
+
+	status, _ = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "image", bytes.Repeat([]byte("a"), 65<<10))
+	assert.Equal(t, 413, status)
+
+	status, _ = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "photo", examplePng(10, 10))
+	assert.Equal(t, 400, status)
+
+	// isian lain sebelum gambar tetap dihitung dalam batas ukuran
+	status, _ = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "photo", bytes.Repeat([]byte("a"), 200<<10))
+	assert.Equal(t, 413, status)
+
+	status, _ = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 0, "image", examplePng(10, 10))
+	assert.Equal(t, 428, status)
+
+	status, _ = requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 5, "image", examplePng(10, 10))
+	assert.Equal(t, 412, status)
+
+	// tidak ada file yang tertinggal dari upload yang gagal
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 0, len(entries))
+
+	menu := models.Menu{}
+	db.First(&menu, 1)
+	assert.Equal(t, "", menu.ImageKey)
+	assert.Equal(t, 1, menu.Version)
+}
+
+// test hapus foto kategori
+func TestDeleteCategoryImage(t *testing.T) {
+	db := database.SetDbTest()
+	dir := t.TempDir()
+	router := setupImageRouter(db, dir)
+	createBulkExampleCategory(db)
+
+	status, responseBodyMap := requestImage(router, http.MethodPut, "/api/v1/category/1/image", 1, "image", examplePng(40, 40))
+	assert.Equal(t, 201, status)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	imageUrl := data["image_url"].(string)
+	assert.True(t, strings.HasPrefix(imageUrl, "/uploads/categories/1/"))
+
+	status, responseBodyMap = requestImage(router, http.MethodDelete, "/api/v1/category/1/image", 2, "", nil)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "", responseBodyMap["data"].(map[string]interface{})["image_url"])
+	assert.Equal(t, "", responseBodyMap["data"].(map[string]interface{})["thumbnail_url"])
+
+	_, err := os.Stat(storedFile(dir, imageUrl))
+	assert.True(t, os.IsNotExist(err))
+
+	status, _ = requestImage(router, http.MethodDelete, "/api/v1/category/1/image", 3, "", nil)
+	assert.Equal(t, 400, status)
+}
+
+// test hapus menu dan kategori ikut menghapus foto dan thumbnail, kecuali transaksinya dibatalkan
+func TestDeleteRecordRemovesImage(t *testing.T) {
+	db := database.SetDbTest()
+	dir := t.TempDir()
+	router := setupImageRouter(db, dir)
+	createBulkExampleCategory(db)
+	db.Create(&models.Menu{Name: "nasi goreng", CategoryId: 1})
+
+	storage := libraries.NewLocalStorage(dir, "/uploads")
+	transactionManager := repository.NewTransactionManager(db)
+	menuService := service.NewMenuService(transactionManager, repository.NewMenuRepository(db), repository.NewCategoryRepository(db), setupAuditService(db), storage)
+	categoryService := service.NewCategoryService(transactionManager, repository.NewCategoryRepository(db), setupAuditService(db), storage)
+
+	status, responseBodyMap := requestImage(router, http.MethodPut, "/api/v1/menu/1/image", 1, "image", examplePng(40, 40))
+	assert.Equal(t, 201, status)
+	menuData := responseBodyMap["data"].(map[string]interface{})
+
+	status, responseBodyMap = requestImage(router, http.MethodPut, "/api/v1/category/1/image", 1, "image", examplePng(40, 40))
+	assert.Equal(t, 201, status)
+	categoryData := responseBodyMap["data"].(map[string]interface{})
+
+	// hapus di dalam transaksi yang gagal, file harus tetap ada
+	err := transactionManager.Transaction(func(tx *gorm.DB) error {
+		err := menuService.WithTx(tx).Delete(request.DeleteMenuRequest{Id: 1, Version: helper.Versions{2}})
+		if err != nil {
+			return err
+		}
+		return errors.New("batal")
+	})
+	assert.NotNil(t, err)
+	for _, url := range []interface{}{menuData["image_url"], menuData["thumbnail_url"]} {
+		_, err = os.Stat(storedFile(dir, url.(string)))
+		assert.Nil(t, err)
+	}
+
+	err = menuService.Delete(request.DeleteMenuRequest{Id: 1, Version: helper.Versions{2}})
+	assert.Nil(t, err)
+	err = categoryService.Delete(request.DeleteRequestCategory{Id: 1, Version: helper.Versions{2}})
+	assert.Nil(t, err)
+
+	for _, data := range []map[string]interface{}{menuData, categoryData} {
+		_, err = os.Stat(storedFile(dir, data["image_url"].(string)))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(storedFile(dir, data["thumbnail_url"].(string)))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
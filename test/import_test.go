@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type importResponseBody struct {
+	Data struct {
+		DryRun    bool `json:"dry_run"`
+		Created   int  `json:"created"`
+		Updated   int  `json:"updated"`
+		Unchanged int  `json:"unchanged"`
+		Rejected  int  `json:"rejected"`
+		Rows      []struct {
+			Entity string   `json:"entity"`
+			Row    int      `json:"row"`
+			Action string   `json:"action"`
+			Errors []string `json:"errors"`
+		} `json:"rows"`
+	} `json:"data"`
+}
+
+func setupImportController(db *gorm.DB) *controllers.ImportController {
+	auditService := setupAuditService(db)
+	categoryRepository := repository.NewCategoryRepository(db)
+	ingredientRepository := repository.NewIngredientRepository(db)
+	menuRepository := repository.NewMenuRepository(db)
+	recipeRepository := repository.NewRecipeRepository(db)
+	transactionManager := repository.NewTransactionManager(db)
+
+	importService := service.NewImportService(
+		transactionManager,
+		categoryRepository,
+		ingredientRepository,
+		menuRepository,
+		recipeRepository,
+		service.NewCategoryService(transactionManager, categoryRepository, auditService, setupStorage()),
+		service.NewIngredientService(transactionManager, ingredientRepository, repository.NewIngredientGroupRepository(db), auditService),
+		service.NewMenuService(transactionManager, menuRepository, categoryRepository, auditService, setupStorage()),
+		service.NewRecipeService(transactionManager, recipeRepository, repository.NewRecipeVersionRepository(db), menuRepository, ingredientRepository, auditService, service.DuplicateLinesReject),
+	)
+	return controllers.NewImportController(importService)
+}
+
+func sendImport(t *testing.T, db *gorm.DB, query string, filename string, content []byte) importResponseBody {
+	router := libraries.SetRouter()
+	router.POST("api/v1/import", setupImportController(db).Import)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/import"+query, body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data importResponseBody
+	err = json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	fmt.Println(data)
+
+	return data
+}
+
+// test dry run import csv tidak menyimpan data
+func TestImportCsvIngredientDryRun(t *testing.T) {
+	db := database.SetDbTest()
+	db.Create(&models.Ingredient{Name: "Gula"})
+
+	csv := "name\ngula\ngaram\n\nkopi\n"
+	data := sendImport(t, db, "?entity=ingredient&dry_run=true", "ingredients.csv", []byte(csv))
+
+	assert.True(t, data.Data.DryRun)
+	assert.Equal(t, 2, data.Data.Created)
+	assert.Equal(t, 1, data.Data.Updated)
+	assert.Equal(t, 5, data.Data.Rows[2].Row)
+
+	var total int64
+	db.Model(&models.Ingredient{}).Count(&total)
+	assert.Equal(t, int64(1), total)
+}
+
+// test import xlsx dengan beberapa sheet yang saling bergantung
+func TestImportXlsxAllSheets(t *testing.T) {
+	db := database.SetDbTest()
+
+	file := excelize.NewFile()
+	file.SetSheetName("Sheet1", "Categories")
+	file.SetSheetRow("Categories", "A1", &[]interface{}{"Name"})
+	file.SetSheetRow("Categories", "A2", &[]interface{}{"minuman"})
+	file.NewSheet("Menus")
+	file.SetSheetRow("Menus", "A1", &[]interface{}{"Name", "Category"})
+	file.SetSheetRow("Menus", "A2", &[]interface{}{"es kopi", "minuman"})
+	file.SetSheetRow("Menus", "A3", &[]interface{}{"es teh", "tidak ada"})
+	file.NewSheet("Ingredients")
+	file.SetSheetRow("Ingredients", "A1", &[]interface{}{"Name"})
+	file.SetSheetRow("Ingredients", "A2", &[]interface{}{"kopi"})
+	file.NewSheet("Recipes")
+	file.SetSheetRow("Recipes", "A1", &[]interface{}{"Menu", "Ingredient", "Qty"})
+	file.SetSheetRow("Recipes", "A2", &[]interface{}{"es kopi", "kopi", "20 gr"})
+
+	content, err := file.WriteToBuffer()
+	assert.NoError(t, err)
+
+	data := sendImport(t, db, "", "master.xlsx", content.Bytes())
+
+	assert.False(t, data.Data.DryRun)
+	assert.Equal(t, 4, data.Data.Created)
+	assert.Equal(t, 1, data.Data.Rejected)
+	assert.Equal(t, "menu", data.Data.Rows[3].Entity)
+	assert.Equal(t, "reject", data.Data.Rows[3].Action)
+
+	recipe := models.MenuIngredient{}
+	err = db.First(&recipe).Error
+	assert.NoError(t, err)
+	assert.Equal(t, "20 gr", recipe.Qty)
+}
+
+// test import menu dengan nama yang sama di kategori lain membuat menu baru
+func TestImportMenuSameNameOtherCategory(t *testing.T) {
+	db := database.SetDbTest()
+	db.Create(&models.Category{Name: "minuman"})
+	db.Create(&models.Category{Name: "paket"})
+	db.Create(&models.Ingredient{Name: "teh"})
+	db.Create(&models.Menu{Name: "es teh", CategoryId: 1})
+
+	data := sendImport(t, db, "?entity=menu", "menus.csv", []byte("name,category\nes teh,paket\nes teh,minuman\n"))
+	assert.Equal(t, 1, data.Data.Created)
+	assert.Equal(t, 1, data.Data.Unchanged)
+
+	var listMenu []models.Menu
+	db.Order("id").Find(&listMenu)
+	assert.Equal(t, 2, len(listMenu))
+	assert.Equal(t, 1, listMenu[0].CategoryId)
+	assert.Equal(t, 2, listMenu[1].CategoryId)
+
+	// nama menu di resep yang ada di beberapa kategori harus diberi kategori
+	data = sendImport(t, db, "?entity=recipe", "recipes.csv", []byte("menu,ingredient,qty\nes teh,teh,5 gr\n"))
+	assert.Equal(t, 1, data.Data.Rejected)
+	assert.Contains(t, data.Data.Rows[0].Errors[0], "add a category or menu_id column")
+
+	data = sendImport(t, db, "?entity=recipe", "recipes.csv", []byte("menu,category,ingredient,qty\nes teh,paket,teh,5 gr\n"))
+	assert.Equal(t, 1, data.Data.Created)
+
+	recipe := models.MenuIngredient{}
+	db.First(&recipe)
+	assert.Equal(t, 2, recipe.MenuId)
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupIngredientGroupRouter(db *gorm.DB) *echo.Echo {
+	ingredientGroupService := service.NewIngredientGroupService(repository.NewTransactionManager(db), repository.NewIngredientGroupRepository(db), repository.NewIngredientRepository(db), setupAuditService(db))
+	ingredientGroupController := controllers.NewIngredientGroupController(ingredientGroupService)
+	ingredientController := setupIngredientController(db)
+	purchasingController := controllers.NewPurchasingController(service.NewPurchasingService(repository.NewMenuRepository(db)))
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/ingredient-group", ingredientGroupController.GetAll)
+	router.GET("api/v1/ingredient-group/count-sheet", ingredientGroupController.CountSheet)
+	router.POST("api/v1/ingredient-group", ingredientGroupController.Create)
+	router.DELETE("api/v1/ingredient-group/:id", ingredientGroupController.Delete)
+	router.GET("api/v1/ingredient", ingredientController.GetAll)
+	router.POST("api/v1/ingredient", ingredientController.Create)
+	router.PUT("api/v1/ingredient/:id", ingredientController.Update)
+	router.POST("api/v1/purchasing/requirements", purchasingController.Requirements)
+	return router
+}
+
+func requestIngredientGroup(router *echo.Echo, method string, target string, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, "http://localhost:8000"+target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return result.StatusCode, responseBodyMap
+}
+
+// produce dan dairy sudah ada dari migrasi
+func createExampleGroupedIngredients(router *echo.Echo) {
+	for _, body := range []string{
+		`{"name": "bawang merah", "ingredient_group_id": 1}`,
+		`{"name": "susu", "ingredient_group_id": 2}`,
+		`{"name": "tepung", "ingredient_group_id": 3}`,
+		`{"name": "garam"}`,
+	} {
+		requestIngredientGroup(router, http.MethodPost, "/api/v1/ingredient", body)
+	}
+}
+
+// test grup bahan bawaan dan filter bahan per grup
+func TestIngredientGroupFilter(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupIngredientGroupRouter(db)
+
+	status, responseBodyMap := requestIngredientGroup(router, http.MethodGet, "/api/v1/ingredient-group", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 4, len(responseBodyMap["data"].([]interface{})))
+
+	createExampleGroupedIngredients(router)
+
+	status, responseBodyMap = requestIngredientGroup(router, http.MethodGet, "/api/v1/ingredient?ingredient_group_id=2", "")
+	assert.Equal(t, 200, status)
+
+	data := responseBodyMap["data"].([]interface{})
+	assert.Equal(t, 1, len(data))
+	ingredient := data[0].(map[string]interface{})
+	assert.Equal(t, "susu", ingredient["name"])
+	assert.Equal(t, "chilled", ingredient["ingredient_group"].(map[string]interface{})["storage"])
+
+	status, responseBodyMap = requestIngredientGroup(router, http.MethodPut, "/api/v1/ingredient/2", `{"name": "susu", "ingredient_group_id": 0}`)
+	assert.Equal(t, 201, status)
+	assert.Nil(t, responseBodyMap["data"].(map[string]interface{})["ingredient_group"])
+}
+
+// test validasi grup bahan
+func TestIngredientGroupValidation(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupIngredientGroupRouter(db)
+	createExampleGroupedIngredients(router)
+
+	status, _ := requestIngredientGroup(router, http.MethodPost, "/api/v1/ingredient-group", `{"name": "frozen", "storage": "frozen", "min_temp": -10, "max_temp": -18}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = requestIngredientGroup(router, http.MethodPost, "/api/v1/ingredient-group", `{"name": "rak", "storage": "warm"}`)
+	assert.Equal(t, 422, status)
+
+	status, _ = requestIngredientGroup(router, http.MethodDelete, "/api/v1/ingredient-group/1", "")
+	assert.Equal(t, 400, status)
+
+	status, _ = requestIngredientGroup(router, http.MethodDelete, "/api/v1/ingredient-group/4", "")
+	assert.Equal(t, 200, status)
+}
+
+// test lembar stock opname urut per area penyimpanan
+func TestStockCountSheet(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupIngredientGroupRouter(db)
+	createExampleGroupedIngredients(router)
+	requestIngredientGroup(router, http.MethodPost, "/api/v1/ingredient-group", `{"name": "frozen food", "storage": "frozen", "min_temp": -25, "max_temp": -18}`)
+
+	status, responseBodyMap := requestIngredientGroup(router, http.MethodGet, "/api/v1/ingredient-group/count-sheet", "")
+	assert.Equal(t, 200, status)
+
+	var names []string
+	for _, sheet := range responseBodyMap["data"].([]interface{}) {
+		names = append(names, sheet.(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, []string{"frozen food", "dairy", "produce", "beverages", "dry goods", "ungrouped"}, names)
+}
+
+// test kebutuhan belanja dikelompokkan per grup
+func TestPurchasingGroupedByIngredientGroup(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupIngredientGroupRouter(db)
+	createExampleGroupedIngredients(router)
+
+	createBulkExampleCategory(db)
+	db.Create(&models.Menu{Name: "roti susu", CategoryId: 1})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 4, Qty: "5 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 3, Qty: "200 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "100 ml"})
+
+	status, responseBodyMap := requestIngredientGroup(router, http.MethodPost, "/api/v1/purchasing/requirements", `{"items": [{"menu_id": 1, "portions": 1}]}`)
+	assert.Equal(t, 200, status)
+
+	lines := responseBodyMap["data"].(map[string]interface{})["lines"].([]interface{})
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "dairy", lines[0].(map[string]interface{})["group"])
+	assert.Equal(t, "dry goods", lines[1].(map[string]interface{})["group"])
+	assert.Equal(t, "garam", lines[2].(map[string]interface{})["name"])
+	assert.Equal(t, "", lines[2].(map[string]interface{})["group"])
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/erp_app/controllers"
 	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
 	"github.com/erp_app/libraries"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
@@ -22,12 +23,12 @@ import (
 
 func setupIngredientController(db *gorm.DB) *controllers.IngredientController {
 	ingredientRepository := repository.NewIngredientRepository(db)
-	ingredientService := service.NewIngredientService(ingredientRepository)
+	ingredientService := service.NewIngredientService(repository.NewTransactionManager(db), ingredientRepository, repository.NewIngredientGroupRepository(db), setupAuditService(db))
 	return controllers.NewIngredientController(ingredientService)
 }
 
 func truncateDataIngredient(db *gorm.DB) {
-	err := db.Exec("TRUNCATE TABLE INGREDIENTS").Error
+	err := database.Truncate(db, "ingredients")
 	if err != nil {
 		panic(err.Error())
 	}
@@ -269,6 +270,7 @@ func TestUpdateSuccessIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/ingredient/1", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -303,6 +305,7 @@ func TestUpdateFailInvalidParameterIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/ingredient/ss", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -337,6 +340,7 @@ func TestUpdateFailNotParameterIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/ingredient", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -371,6 +375,7 @@ func TestUpdateFailNotFounDataForUpdateIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/ingredient/99", strings.NewReader(updateRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -401,6 +406,7 @@ func TestDeleteSuccessIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/ingredient/10", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -431,6 +437,7 @@ func TestDeleteFailNotFoundDataIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/ingredient/11", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -461,6 +468,7 @@ func TestDeleteFailNotExistsParameterIngredient(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/ingredient/", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
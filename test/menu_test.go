@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/erp_app/controllers"
 	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
 	"github.com/erp_app/libraries"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
@@ -23,13 +24,13 @@ import (
 func setupMenuController(db *gorm.DB) *controllers.MenuController {
 	menuRepository := repository.NewMenuRepository(db)
 	categoryRepository := repository.NewCategoryRepository(db)
-	menuService := service.NewMenuService(menuRepository, categoryRepository)
+	menuService := service.NewMenuService(repository.NewTransactionManager(db), menuRepository, categoryRepository, setupAuditService(db), setupStorage())
 	menuController := controllers.NewMenuController(menuService)
 	return menuController
 }
 
 func truncateDataMenu(db *gorm.DB) {
-	db.Exec("TRUNCATE TABLE MENUS")
+	database.Truncate(db, "menus")
 }
 
 func createBulkExampleMenu(db *gorm.DB) {
@@ -207,6 +208,7 @@ func TestUpdateSuccessMenu(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1", strings.NewReader(createRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -418,8 +420,8 @@ func TestGetAllSuccessMenuWithQuery(t *testing.T) {
 func TestDeleteSuccessMenu(t *testing.T) {
 	db := database.SetDbTest()
 
-	db.Exec("TRUNCATE TABLE MENUS")
-	db.Exec("TRUNCATE TABLE CATEGORIES")
+	truncateDataMenu(db)
+	truncateDataCategory(db)
 
 	// create category
 	category := models.Category{
@@ -462,6 +464,7 @@ func TestDeleteSuccessMenu(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/menu/1", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -479,3 +482,128 @@ func TestDeleteSuccessMenu(t *testing.T) {
 
 	fmt.Println(data)
 }
+
+// test get menu mengirimkan etag
+func TestGetSuccessMenuETag(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+
+	menuController := setupMenuController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id", menuController.Get)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, `"1"`, result.Header.Get(helper.HeaderETag))
+}
+
+// test update tanpa if-match
+func TestUpdateFailMenuWithoutIfMatch(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+
+	createRequestJson := `{
+  "name" : "jus pokat",
+"category_id" : 2
+}`
+
+	menuController := setupMenuController(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/menu/:id", menuController.Update)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1", strings.NewReader(createRequestJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 428, result.StatusCode)
+}
+
+// test update dengan versi yang sudah berubah
+func TestUpdateFailMenuVersionConflict(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+
+	createRequestJson := `{
+  "name" : "jus pokat",
+"category_id" : 2
+}`
+
+	menuController := setupMenuController(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/menu/:id", menuController.Update)
+
+	// update pertama berhasil dan menaikkan versi menjadi 2
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1", strings.NewReader(createRequestJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 201, rec.Result().StatusCode)
+	assert.Equal(t, `"2"`, rec.Result().Header.Get(helper.HeaderETag))
+
+	// update kedua masih memakai versi lama
+	req = httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1", strings.NewReader(createRequestJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 412, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data map[string]interface{}
+
+	err := json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	fmt.Println(data)
+}
+
+// test if-match dengan etag weak dan daftar etag
+func TestUpdateMenuIfMatchList(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+
+	menuController := setupMenuController(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/menu/:id", menuController.Update)
+
+	update := func(ifMatch string) int {
+		req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1", strings.NewReader(`{"name": "jus pokat", "category_id": 2}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(helper.HeaderIfMatch, ifMatch)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// etag weak dari proxy tetap diterima
+	assert.Equal(t, 201, update(`W/"1"`))
+
+	// daftar etag cocok bila salah satunya versi sekarang
+	assert.Equal(t, 201, update(`"1", W/"2"`))
+	assert.Equal(t, 412, update(`"1", "2"`))
+
+	// etag yang tidak valid di dalam daftar
+	assert.Equal(t, 412, update(`"3", 4`))
+	assert.Equal(t, 412, update(`"3",`))
+}
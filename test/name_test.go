@@ -0,0 +1,144 @@
+package test
+
+import (
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+)
+
+func setupNameRouter(db *gorm.DB) *echo.Echo {
+	categoryController := setupCategoryController(db)
+	ingredientController := setupIngredientController(db)
+	menuController := setupMenuController(db)
+	menuCloneController := setupMenuCloneController(db)
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/category", categoryController.Create)
+	router.PUT("api/v1/category/:id", categoryController.Update)
+	router.POST("api/v1/ingredient", ingredientController.Create)
+	router.PUT("api/v1/ingredient/:id", ingredientController.Update)
+	router.POST("api/v1/menu", menuController.Create)
+	router.PUT("api/v1/menu/:id", menuController.Update)
+	router.POST("api/v1/menu/:id/clone", menuCloneController.Clone)
+	return router
+}
+
+func existingId(responseBodyMap map[string]interface{}) interface{} {
+	return responseBodyMap["data"].(map[string]interface{})["existing_id"]
+}
+
+// test nama kategori unik tanpa membedakan huruf besar dan spasi
+func TestCategoryNameUnique(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupNameRouter(db)
+
+	status, _ := requestDisplayOrder(router, http.MethodPost, "/api/v1/category", `{"name": "Minuman"}`)
+	assert.Equal(t, 201, status)
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/category", `{"name": "Makanan"}`)
+	assert.Equal(t, 201, status)
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPost, "/api/v1/category", `{"name": "  minuman "}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPut, "/api/v1/category/2", `{"name": "MINUMAN"}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+
+	// mengganti huruf besar nama sendiri tetap boleh
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPut, "/api/v1/category/1", `{"name": "MINUMAN  dingin"}`)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, "MINUMAN dingin", responseBodyMap["data"].(map[string]interface{})["name"])
+
+	// index unik juga menjaga data yang ditulis tanpa lewat service
+	err := db.Create(&models.Category{Name: "minuman DINGIN"}).Error
+	assert.NotNil(t, err)
+}
+
+// test nama bahan disimpan tanpa spasi berlebih dan unik
+func TestIngredientNameUnique(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupNameRouter(db)
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPost, "/api/v1/ingredient", `{"name": " garam   halus "}`)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, "garam halus", responseBodyMap["data"].(map[string]interface{})["name"])
+
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPost, "/api/v1/ingredient", `{"name": "Garam Halus"}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+
+	var count int64
+	db.Model(&models.Ingredient{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+// test nama menu hanya unik di dalam satu kategori
+func TestMenuNameUniquePerCategory(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupNameRouter(db)
+	createBulkExampleCategory(db)
+
+	status, _ := requestDisplayOrder(router, http.MethodPost, "/api/v1/menu", `{"name": "Es Teh", "category_id": 1}`)
+	assert.Equal(t, 201, status)
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu", `{"name": "es teh", "category_id": 2}`)
+	assert.Equal(t, 201, status)
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPost, "/api/v1/menu", `{"name": "ES TEH", "category_id": 1}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+
+	// pindah ke kategori yang sudah punya menu dengan nama sama ditolak
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/2", `{"name": "es teh", "category_id": 1}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/clone", `{"name": "es  teh"}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/clone", `{"name": "es teh", "category_id": 3}`)
+	assert.Equal(t, 201, status)
+}
+
+// test migrasi name key memakai normalisasi yang sama dengan aplikasi
+func TestMigrateNameKeysBackfill(t *testing.T) {
+	db := database.SetDbTest()
+
+	err := database.Rollback(db, 3)
+	assert.Nil(t, err)
+
+	for _, name := range []string{" Minuman ", "minuman\t", "MINUMAN  dingin", "minuman dingin"} {
+		db.Exec("INSERT INTO categories (name) VALUES (?)", name)
+	}
+
+	err = database.Migrate(db)
+	assert.Nil(t, err)
+
+	var listCategory []models.Category
+	db.Order("id").Find(&listCategory)
+	assert.Equal(t, 4, len(listCategory))
+	assert.Equal(t, "Minuman", listCategory[0].Name)
+	assert.Equal(t, "minuman", listCategory[0].NameKey)
+	assert.Equal(t, "minuman#2", listCategory[1].NameKey)
+	assert.Equal(t, "MINUMAN dingin", listCategory[2].Name)
+	assert.Equal(t, "minuman dingin", listCategory[2].NameKey)
+	assert.Equal(t, "minuman dingin#4", listCategory[3].NameKey)
+
+	// duplikat lama tetap bisa diubah selama namanya tidak diganti
+	router := setupNameRouter(db)
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/category/2", `{"name": "minuman"}`)
+	assert.Equal(t, 201, status)
+
+	category := models.Category{}
+	db.First(&category, 2)
+	assert.Equal(t, "minuman#2", category.NameKey)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/category/4", `{"name": "Minuman"}`)
+	assert.Equal(t, 409, status)
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func nutrient(value float64) *float64 {
+	return &value
+}
+
+func createExampleNutritionMenu(db *gorm.DB) {
+	createBulkExampleCategory(db)
+
+	db.Create(&models.Ingredient{Name: "beras", Calories: nutrient(360), Protein: nutrient(6.8), Fat: nutrient(0.7), Carbs: nutrient(79), Sodium: nutrient(5)})
+	db.Create(&models.Ingredient{Name: "santan", Calories: nutrient(230), Protein: nutrient(2.3), Fat: nutrient(24), Carbs: nutrient(6), Sodium: nutrient(15)})
+	db.Create(&models.Ingredient{Name: "telur"})
+
+	db.Create(&models.Menu{Name: "nasi uduk", CategoryId: 1, Portions: 2})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "0,2 kg"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "100 ml"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 3, Qty: "2 butir"})
+}
+
+// test nutrisi per porsi dihitung dari resep
+func TestMenuNutrition(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleNutritionMenu(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id", setupMenuController(db).Get)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	nutrition := responseBodyMap["data"].(map[string]interface{})["nutrition"].(map[string]interface{})
+	// (200 g beras + 100 ml santan) / 2 porsi
+	assert.Equal(t, float64(475), nutrition["calories"])
+	assert.Equal(t, float64(8), nutrition["protein"])
+	assert.Equal(t, 12.7, nutrition["fat"])
+	assert.Equal(t, float64(82), nutrition["carbs"])
+	assert.Equal(t, float64(13), nutrition["sodium"])
+	assert.Equal(t, false, nutrition["complete"])
+	assert.Equal(t, []interface{}{"telur"}, nutrition["missing"])
+}
+
+// test label nutrisi pdf
+func TestNutritionLabelPdf(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleNutritionMenu(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id/nutrition-label.pdf", setupMenuController(db).NutritionLabel)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1/nutrition-label.pdf", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, helper.MIMEApplicationPdf, result.Header.Get(echo.HeaderContentType))
+
+	responseBody, _ := io.ReadAll(result.Body)
+	assert.Equal(t, "%PDF", string(responseBody[:4]))
+}
+
+// test nutrisi bahan bisa dikosongkan dan menu yang memakainya ikut berubah
+func TestUpdateIngredientClearNutrients(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleNutritionMenu(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/ingredient/:id", setupIngredientController(db).Update)
+
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/ingredient/2", `{"name": "santan", "clear_nutrients": ["energi"]}`)
+	assert.Equal(t, 422, status)
+
+	status, body := requestDisplayOrder(router, http.MethodPut, "/api/v1/ingredient/2", `{"name": "santan", "clear_nutrients": ["calories", "sodium"], "fat": 20}`)
+	assert.Equal(t, 201, status)
+
+	data := body["data"].(map[string]interface{})
+	assert.Nil(t, data["calories"])
+	assert.Nil(t, data["sodium"])
+	assert.Equal(t, float64(20), data["fat"])
+	assert.Equal(t, 2.3, data["protein"])
+
+	var count int64
+	db.Model(&models.Change{}).Where("entity_type = ? AND entity_id = ?", "menu", 1).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
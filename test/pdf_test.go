@@ -0,0 +1,112 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// test cetak recipe card menu
+func TestRecipeCardPdf(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+
+	menu := models.Menu{Name: "nasi goreng spésial", CategoryId: 1}
+	db.Create(&menu)
+	db.Create(&models.MenuIngredient{MenuId: menu.Id, IngredientId: 1, Qty: "200 gr"})
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id/recipe-card.pdf", setupMenuController(db).RecipeCard)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1/recipe-card.pdf", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, helper.MIMEApplicationPdf, result.Header.Get(echo.HeaderContentType))
+
+	responseBody, _ := io.ReadAll(result.Body)
+	assert.Equal(t, "%PDF", string(responseBody[:4]))
+
+	fmt.Println(len(responseBody))
+}
+
+// test cetak recipe card menu yang tidak ada
+func TestRecipeCardPdfNotFound(t *testing.T) {
+	db := database.SetDbTest()
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id/recipe-card.pdf", setupMenuController(db).RecipeCard)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/99/recipe-card.pdf", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 400, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	fmt.Println(string(responseBody))
+}
+
+// test cetak menu book dikelompokkan per category
+func TestMenuBookPdf(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/book.pdf", setupMenuController(db).MenuBook)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/book.pdf", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	assert.Equal(t, "%PDF", string(responseBody[:4]))
+
+	fmt.Println(len(responseBody))
+}
+
+// test menu book memakai urutan tampilan, bukan urutan nama
+func TestMenuBookDisplayOrder(t *testing.T) {
+	db := database.SetDbTest()
+	router := setupDisplayOrderRouter(db)
+	createExampleDisplayOrder(router)
+	requestDisplayOrder(router, http.MethodPut, "/api/v1/category/order", `{"category_ids": [3, 1, 2]}`)
+	requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/order", `{"category_id": 1, "menu_ids": [3, 1]}`)
+
+	menuService := service.NewMenuService(repository.NewTransactionManager(db), repository.NewMenuRepository(db), repository.NewCategoryRepository(db), setupAuditService(db), setupStorage())
+	listRecipeCard, err := menuService.MenuBook()
+	assert.Nil(t, err)
+
+	var names []string
+	for _, card := range listRecipeCard {
+		names = append(names, card.Menu.Name)
+	}
+	assert.Equal(t, []string{"kentang goreng", "mie goreng", "nasi goreng", "es teh", "kopi"}, names)
+
+	content := &bytes.Buffer{}
+	err = helper.WriteMenuBook(content, listRecipeCard)
+	assert.Nil(t, err)
+	assert.Equal(t, "%PDF", content.String()[:4])
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// test kebutuhan belanja memperhitungkan yield bahan dan override per recipe
+func TestPurchasingRequirements(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+
+	onion := models.Ingredient{Name: "bawang", YieldPercent: 80}
+	egg := models.Ingredient{Name: "telur"}
+	db.Create(&onion)
+	db.Create(&egg)
+
+	friedRice := models.Menu{Name: "nasi goreng", CategoryId: 1, Portions: 4}
+	omelette := models.Menu{Name: "telur dadar", CategoryId: 1, Portions: 1}
+	db.Create(&friedRice)
+	db.Create(&omelette)
+
+	halfYield := 50.0
+	db.Create(&models.MenuIngredient{MenuId: friedRice.Id, IngredientId: onion.Id, Qty: "800 gr"})
+	db.Create(&models.MenuIngredient{MenuId: friedRice.Id, IngredientId: egg.Id, Qty: "2 butir"})
+	db.Create(&models.MenuIngredient{MenuId: omelette.Id, IngredientId: onion.Id, Qty: "200 gr", YieldPercent: &halfYield})
+	db.Create(&models.MenuIngredient{MenuId: omelette.Id, IngredientId: egg.Id, Qty: "secukupnya"})
+
+	purchasingController := controllers.NewPurchasingController(service.NewPurchasingService(repository.NewMenuRepository(db)))
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/purchasing/requirements", purchasingController.Requirements)
+
+	body := `{"items": [{"menu_id": 1, "portions": 10}, {"menu_id": 2, "portions": 2}]}`
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/purchasing/requirements", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	lines := data["lines"].([]interface{})
+	assert.Equal(t, 2, len(lines))
+
+	// bawang: 2 kg + 400 gr bersih, dibeli 2.5 kg (yield 80%) + 800 gr (yield 50%)
+	assert.Equal(t, "2.4 kg", lines[0].(map[string]interface{})["edible_qty"])
+	assert.Equal(t, "3.3 kg", lines[0].(map[string]interface{})["purchase_qty"])
+	assert.Equal(t, "5 butir", lines[1].(map[string]interface{})["purchase_qty"])
+
+	assert.Equal(t, 1, len(data["unparsed"].([]interface{})))
+}
@@ -0,0 +1,181 @@
+package test
+
+import (
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+)
+
+func setupRecipeDuplicateRouter(db *gorm.DB, duplicateLines string) *echo.Echo {
+	recipeService := service.NewRecipeService(repository.NewTransactionManager(db), repository.NewRecipeRepository(db), repository.NewRecipeVersionRepository(db), repository.NewMenuRepository(db), repository.NewIngredientRepository(db), setupAuditService(db), duplicateLines)
+	recipeController := controllers.NewRecipeController(recipeService)
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/menu/:menu_id/recipe/", recipeController.Add)
+	router.PUT("api/v1/menu/:menu_id/recipe/:id", recipeController.Update)
+	router.DELETE("api/v1/menu/:menu_id/recipe/:id", recipeController.Delete)
+	return router
+}
+
+func createExampleRecipeDuplicate(db *gorm.DB) {
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+	createBulkExampleMenu(db)
+}
+
+func countRecipeLines(db *gorm.DB, menuId int) int64 {
+	var count int64
+	db.Model(&models.MenuIngredient{}).Where("menu_id = ?", menuId).Count(&count)
+	return count
+}
+
+// test bahan yang sudah ada di resep ditolak
+func TestAddRecipeDuplicateRejected(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+	router := setupRecipeDuplicateRouter(db, service.DuplicateLinesReject)
+
+	status, _ := requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 1, "qty": "200 gr"}`)
+	assert.Equal(t, 201, status)
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 1, "qty": "100 gr"}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+	assert.Equal(t, int64(1), countRecipeLines(db, 1))
+
+	// bahan yang sama di menu lain tetap boleh
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/2/recipe/", `{"ingredient_id": 1, "qty": "100 gr"}`)
+	assert.Equal(t, 201, status)
+
+	// mengganti bahan ke bahan yang sudah ada di resep juga ditolak
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 2, "qty": "1 sdm"}`)
+	assert.Equal(t, 201, status)
+	status, responseBodyMap = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe/3", `{"ingredient_id": 1, "qty": "1 sdm"}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, float64(1), existingId(responseBodyMap))
+}
+
+// test bahan yang sudah ada digabung jumlahnya
+func TestAddRecipeDuplicateMerged(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+	router := setupRecipeDuplicateRouter(db, service.DuplicateLinesMerge)
+
+	requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 1, "qty": "800 gr"}`)
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 1, "qty": "0,4 kg"}`)
+	assert.Equal(t, 201, status)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	assert.Equal(t, float64(1), data["Id"])
+	assert.Equal(t, "1.2 kg", data["Qty"])
+	assert.Equal(t, int64(1), countRecipeLines(db, 1))
+
+	// satuan yang tidak bisa dijumlahkan tidak digabung
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 1, "qty": "2 butir"}`)
+	assert.Equal(t, 409, status)
+
+	// yield yang berbeda juga tidak digabung
+	status, _ = requestDisplayOrder(router, http.MethodPost, "/api/v1/menu/1/recipe/", `{"ingredient_id": 1, "qty": "100 gr", "yield_percent": 50}`)
+	assert.Equal(t, 409, status)
+
+	recipe := models.MenuIngredient{}
+	db.First(&recipe, 1)
+	assert.Equal(t, "1.2 kg", recipe.Qty)
+	assert.Equal(t, 2, recipe.Version)
+}
+
+// test ubah dan hapus baris resep lewat menu lain ditolak
+func TestRecipeBelongsToMenu(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+	router := setupRecipeDuplicateRouter(db, service.DuplicateLinesReject)
+
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "200 gr"})
+
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/2/recipe/1", `{"ingredient_id": 1, "qty": "300 gr"}`)
+	assert.Equal(t, 404, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodDelete, "/api/v1/menu/2/recipe/1", ``)
+	assert.Equal(t, 404, status)
+	assert.Equal(t, int64(1), countRecipeLines(db, 1))
+
+	status, _ = requestDisplayOrder(router, http.MethodDelete, "/api/v1/menu/1/recipe/1", ``)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, int64(0), countRecipeLines(db, 1))
+}
+
+// test migrasi menggabungkan baris resep ganda sebelum index unik dibuat
+func TestMigrateMergesDuplicateRecipeLines(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+
+	err := database.Rollback(db, 1)
+	assert.Nil(t, err)
+
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "200 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "0,3 kg"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "2 butir"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "1 butir"})
+	db.Create(&models.MenuIngredient{MenuId: 2, IngredientId: 1, Qty: "100 gr"})
+
+	err = database.Migrate(db)
+	assert.Nil(t, err)
+
+	var listRecipe []models.MenuIngredient
+	db.Order("id").Find(&listRecipe)
+	assert.Equal(t, 3, len(listRecipe))
+	assert.Equal(t, 1, listRecipe[0].Id)
+	assert.Equal(t, "500 g", listRecipe[0].Qty)
+	assert.Equal(t, 3, listRecipe[1].Id)
+	assert.Equal(t, "3 butir", listRecipe[1].Qty)
+	assert.Equal(t, "100 gr", listRecipe[2].Qty)
+
+	// index unik menolak baris ganda baru
+	_, err = repository.NewRecipeRepository(db).Create(models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "1 gr"})
+	assert.NotNil(t, err)
+}
+
+// test migrasi gagal tanpa menghapus apa pun bila baris ganda tidak bisa dijumlahkan
+func TestMigrateRejectsUnmergeableRecipeLines(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+
+	err := database.Rollback(db, 1)
+	assert.Nil(t, err)
+
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "200 g"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "1 cup"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "2 butir"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "secukupnya"})
+	db.Create(&models.MenuIngredient{MenuId: 2, IngredientId: 1, Qty: "100 g"})
+	db.Create(&models.MenuIngredient{MenuId: 2, IngredientId: 1, Qty: "50 g"})
+
+	err = database.Migrate(db)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "menu 1 ingredient 1, menu 1 ingredient 2")
+	assert.NotContains(t, err.Error(), "menu 2")
+
+	// tidak ada baris yang diubah atau dihapus
+	var listRecipe []models.MenuIngredient
+	db.Order("id").Find(&listRecipe)
+	assert.Equal(t, 6, len(listRecipe))
+	assert.Equal(t, "100 g", listRecipe[4].Qty)
+
+	// setelah dibereskan manual migrasi bisa diulang
+	db.Where("qty IN ?", []string{"1 cup", "secukupnya"}).Delete(&models.MenuIngredient{})
+	err = database.Migrate(db)
+	assert.Nil(t, err)
+
+	db.Order("id").Find(&listRecipe)
+	assert.Equal(t, 3, len(listRecipe))
+	assert.Equal(t, "150 g", listRecipe[2].Qty)
+}
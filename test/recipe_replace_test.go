@@ -0,0 +1,148 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupRecipeReplaceRouter(db *gorm.DB) *echo.Echo {
+	recipeController := setupRecipeController(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/menu/:menu_id/recipe", recipeController.Replace)
+	return router
+}
+
+func replaceRecipe(router *echo.Echo, body string, ifMatch string) (int, map[string]interface{}, string) {
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1/recipe", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if ifMatch != "" {
+		req.Header.Set(helper.HeaderIfMatch, ifMatch)
+	}
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	responseBody, _ := io.ReadAll(rec.Result().Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return rec.Result().StatusCode, responseBodyMap, rec.Result().Header.Get(helper.HeaderETag)
+}
+
+// test ganti seluruh resep: bahan yang tetap diubah di tempat, yang baru ditambah, sisanya dihapus
+func TestReplaceRecipe(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+	router := setupRecipeReplaceRouter(db)
+
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "200 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "1 sdm"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 3, Qty: "2 butir"})
+	db.Create(&models.MenuIngredient{MenuId: 2, IngredientId: 1, Qty: "100 gr"})
+
+	status, responseBodyMap := requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe", `{"lines": [
+		{"ingredient_id": 4, "qty": "50 ml"},
+		{"ingredient_id": 1, "qty": "250 gr", "yield_percent": 80},
+		{"ingredient_id": 2, "qty": "1 sdm"}
+	]}`)
+	assert.Equal(t, 201, status)
+
+	data := responseBodyMap["data"].([]interface{})
+	assert.Equal(t, 3, len(data))
+
+	// baris yang tetap menyimpan id-nya, urutan mengikuti id
+	first := data[0].(map[string]interface{})
+	assert.Equal(t, float64(1), first["Id"])
+	assert.Equal(t, "250 gr", first["Qty"])
+	assert.Equal(t, float64(80), first["YieldPercent"])
+	assert.Equal(t, float64(2), first["Version"])
+
+	// baris yang tidak berubah tidak ditulis ulang
+	second := data[1].(map[string]interface{})
+	assert.Equal(t, float64(2), second["Id"])
+	assert.Equal(t, float64(1), second["Version"])
+
+	third := data[2].(map[string]interface{})
+	assert.Equal(t, float64(4), third["IngredientId"])
+	assert.Equal(t, "50 ml", third["Qty"])
+
+	var count int64
+	db.Model(&models.MenuIngredient{}).Where("id = ?", 3).Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, int64(1), countRecipeLines(db, 2))
+
+	var audits int64
+	db.Model(&models.AuditLog{}).Where("entity_type = ?", "recipe").Count(&audits)
+	assert.Equal(t, int64(3), audits)
+
+	// versi menu naik sekali untuk satu penggantian resep
+	menu := models.Menu{}
+	db.First(&menu, 1)
+	assert.Equal(t, 2, menu.Version)
+
+	// penggantian dengan versi lama ditolak
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe", `{"lines": []}`)
+	assert.Equal(t, 412, status)
+	assert.Equal(t, int64(3), countRecipeLines(db, 1))
+
+	status, _, _ = replaceRecipe(router, `{"lines": []}`, "")
+	assert.Equal(t, 428, status)
+
+	// daftar kosong mengosongkan resep
+	status, _, etag := replaceRecipe(router, `{"lines": []}`, helper.ETag(2))
+	assert.Equal(t, 201, status)
+	assert.Equal(t, helper.ETag(3), etag)
+	assert.Equal(t, int64(0), countRecipeLines(db, 1))
+}
+
+// test ganti resep yang gagal tidak mengubah resep sama sekali
+func TestReplaceRecipeRejected(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeDuplicate(db)
+	router := setupRecipeReplaceRouter(db)
+
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "200 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "1 sdm"})
+
+	// bahan yang tidak ada membatalkan baris yang sudah diproses sebelumnya
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe", `{"lines": [
+		{"ingredient_id": 1, "qty": "300 gr"},
+		{"ingredient_id": 99, "qty": "1 sdt"}
+	]}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe", `{"lines": [
+		{"ingredient_id": 1, "qty": "300 gr"},
+		{"ingredient_id": 1, "qty": "100 gr"}
+	]}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe", `{}`)
+	assert.Equal(t, 422, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe", `{"lines": [{"ingredient_id": 1}]}`)
+	assert.Equal(t, 422, status)
+
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/99/recipe", `{"lines": []}`)
+	assert.Equal(t, 400, status)
+
+	recipe := models.MenuIngredient{}
+	db.First(&recipe, 1)
+	assert.Equal(t, "200 gr", recipe.Qty)
+	assert.Equal(t, 1, recipe.Version)
+	assert.Equal(t, int64(2), countRecipeLines(db, 1))
+}
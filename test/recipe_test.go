@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/erp_app/controllers"
 	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
 	"github.com/erp_app/libraries"
 	"github.com/erp_app/models"
 	"github.com/erp_app/repository"
@@ -21,14 +22,14 @@ import (
 )
 
 func truncateDataRecipes(db *gorm.DB) {
-	db.Exec("TRUNCATE TABLE RECIPES")
+	database.Truncate(db, "recipes")
 }
 
 func setupRecipeController(db *gorm.DB) *controllers.RecipeController {
 	recipeRepository := repository.NewRecipeRepository(db)
 	menuRepository := repository.NewMenuRepository(db)
 	ingredientRepository := repository.NewIngredientRepository(db)
-	recipeService := service.NewRecipeService(recipeRepository, menuRepository, ingredientRepository)
+	recipeService := service.NewRecipeService(repository.NewTransactionManager(db), recipeRepository, repository.NewRecipeVersionRepository(db), menuRepository, ingredientRepository, setupAuditService(db), service.DuplicateLinesReject)
 	recipeController := controllers.NewRecipeController(recipeService)
 	return recipeController
 }
@@ -235,6 +236,7 @@ func TestUpdateSuccessRecipe(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/"+strconv.Itoa(recipe.MenuId)+"/recipes/"+strconv.Itoa(recipe.Id), strings.NewReader(createRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -286,6 +288,7 @@ func TestUpdateFailRecipeIdNotFound(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/"+strconv.Itoa(recipe.MenuId)+"/recipes/100", strings.NewReader(createRequestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
@@ -332,6 +335,7 @@ func TestDeleteSuccessRecipe(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/menu/"+strconv.Itoa(recipe.MenuId)+"/recipes/1", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
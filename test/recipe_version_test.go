@@ -0,0 +1,277 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupRecipeVersionService(db *gorm.DB) service.RecipeVersionService {
+	return service.NewRecipeVersionService(repository.NewTransactionManager(db), repository.NewRecipeVersionRepository(db), repository.NewMenuRepository(db), repository.NewIngredientRepository(db), setupAuditService(db))
+}
+
+func setupRecipeVersionRouter(db *gorm.DB) *echo.Echo {
+	recipeVersionController := controllers.NewRecipeVersionController(setupRecipeVersionService(db))
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:menu_id/versions", recipeVersionController.GetAll)
+	router.POST("api/v1/menu/:menu_id/versions", recipeVersionController.Create)
+	router.GET("api/v1/menu/:menu_id/versions/effective", recipeVersionController.Effective)
+	router.PUT("api/v1/menu/:menu_id/versions/:id", recipeVersionController.Update)
+	router.POST("api/v1/menu/:menu_id/versions/:id/activate", recipeVersionController.Activate)
+	router.GET("api/v1/menu/:menu_id/versions/:id/diff", recipeVersionController.Diff)
+	return router
+}
+
+func createExampleRecipeForVersion(db *gorm.DB) {
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+	createBulkExampleMenu(db)
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 1, Qty: "200 gr"})
+	db.Create(&models.MenuIngredient{MenuId: 1, IngredientId: 2, Qty: "2 butir"})
+}
+
+func serveRecipeVersion(router *echo.Echo, method string, target string, body string, ifMatch string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, "http://localhost:8000/api/v1/menu/1/versions"+target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if ifMatch != "" {
+		req.Header.Set(helper.HeaderIfMatch, ifMatch)
+	}
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	responseBody, _ := io.ReadAll(rec.Result().Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	return rec.Result().StatusCode, responseBodyMap
+}
+
+// test draft baru menyalin recipe yang sedang dipakai
+func TestCreateRecipeVersionCopiesLiveRecipe(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	router := setupRecipeVersionRouter(db)
+
+	status, body := serveRecipeVersion(router, http.MethodPost, "", `{"note": "resep musim hujan"}`, "")
+	assert.Equal(t, 201, status)
+
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, "draft", data["status"])
+	assert.Equal(t, float64(1), data["number"])
+	assert.Equal(t, 2, len(data["lines"].([]interface{})))
+}
+
+// test aktivasi langsung mengganti recipe dan menyimpan resep lama
+func TestActivateRecipeVersion(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	router := setupRecipeVersionRouter(db)
+
+	status, _ := serveRecipeVersion(router, http.MethodPost, "", `{"lines": [{"ingredient_id": 1, "qty": "250 gr"}, {"ingredient_id": 3, "qty": "1 sdm"}]}`, "")
+	assert.Equal(t, 201, status)
+
+	status, body := serveRecipeVersion(router, http.MethodPost, "/1/activate", `{}`, `"1"`)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, true, body["data"].(map[string]interface{})["in_effect"])
+
+	var listRecipe []models.MenuIngredient
+	db.Where("menu_id = ?", 1).Order("ingredient_id").Find(&listRecipe)
+	assert.Equal(t, 2, len(listRecipe))
+	assert.Equal(t, "250 gr", listRecipe[0].Qty)
+	assert.Equal(t, 3, listRecipe[1].IngredientId)
+
+	// resep sebelum versioning tersimpan sebagai versi yang diarsipkan
+	status, body = serveRecipeVersion(router, http.MethodGet, "", "", "")
+	assert.Equal(t, 200, status)
+	versions := body["data"].([]interface{})
+	assert.Equal(t, 2, len(versions))
+	assert.Equal(t, float64(0), versions[0].(map[string]interface{})["number"])
+	assert.Equal(t, "archived", versions[0].(map[string]interface{})["status"])
+	assert.Equal(t, "2 butir", versions[0].(map[string]interface{})["lines"].([]interface{})[1].(map[string]interface{})["qty"])
+
+	// versi yang sudah aktif tidak bisa diubah
+	status, _ = serveRecipeVersion(router, http.MethodPut, "/1", `{"lines": []}`, `"3"`)
+	assert.Equal(t, 400, status)
+
+	status, body = serveRecipeVersion(router, http.MethodGet, "/1/diff", "", "")
+	assert.Equal(t, 200, status)
+	diff := body["data"].(map[string]interface{})
+	assert.Equal(t, 1, len(diff["added"].([]interface{})))
+	assert.Equal(t, 1, len(diff["removed"].([]interface{})))
+	assert.Equal(t, 1, len(diff["changed"].([]interface{})))
+}
+
+// test versi dengan tanggal efektif di masa depan baru dipakai saat waktunya tiba
+func TestScheduleRecipeVersion(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	router := setupRecipeVersionRouter(db)
+
+	serveRecipeVersion(router, http.MethodPost, "", `{"lines": [{"ingredient_id": 1, "qty": "300 gr"}]}`, "")
+
+	effectiveFrom := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	status, body := serveRecipeVersion(router, http.MethodPost, "/1/activate", `{"effective_from": "`+effectiveFrom.Format(time.RFC3339)+`"}`, `"1"`)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, false, body["data"].(map[string]interface{})["in_effect"])
+
+	recipe, _ := repository.NewRecipeRepository(db).FindByMenuAndIngredient(1, 1)
+	assert.Equal(t, "200 gr", recipe.Qty)
+
+	applied, err := setupRecipeVersionService(db).ActivateDue(time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, applied)
+
+	applied, err = setupRecipeVersionService(db).ActivateDue(effectiveFrom.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, applied)
+
+	recipe, _ = repository.NewRecipeRepository(db).FindByMenuAndIngredient(1, 1)
+	assert.Equal(t, "300 gr", recipe.Qty)
+
+	// untuk perhitungan historis, versi yang berlaku besok adalah resep lama
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	status, body = serveRecipeVersion(router, http.MethodGet, "/effective?at="+tomorrow, "", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, 2, len(body["data"].(map[string]interface{})["lines"].([]interface{})))
+}
+
+// test resep yang diubah langsung tetap tercatat di riwayat versi
+func TestDirectRecipeEditKeepsVersionHistory(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	router := setupRecipeVersionRouter(db)
+
+	recipeController := setupRecipeController(db)
+	router.PUT("api/v1/menu/:menu_id/recipe/:id", recipeController.Update)
+	router.DELETE("api/v1/menu/:menu_id/recipe/:id", recipeController.Delete)
+
+	status, _ := requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe/1", `{"ingredient_id": 1, "qty": "250 gr"}`)
+	assert.Equal(t, 201, status)
+
+	status, body := serveRecipeVersion(router, http.MethodGet, "", "", "")
+	assert.Equal(t, 200, status)
+	versions := body["data"].([]interface{})
+	assert.Equal(t, 2, len(versions))
+
+	baseline := versions[0].(map[string]interface{})
+	assert.Equal(t, float64(0), baseline["number"])
+	assert.Equal(t, "200 gr", baseline["lines"].([]interface{})[0].(map[string]interface{})["qty"])
+
+	edited := versions[1].(map[string]interface{})
+	assert.Equal(t, true, edited["in_effect"])
+	assert.Equal(t, "250 gr", edited["lines"].([]interface{})[0].(map[string]interface{})["qty"])
+
+	status, _ = requestDisplayOrder(router, http.MethodDelete, "/api/v1/menu/1/recipe/2", ``)
+	assert.Equal(t, 201, status)
+
+	// versi yang berlaku sekarang sama dengan resep yang dipakai
+	status, body = serveRecipeVersion(router, http.MethodGet, "/effective", "", "")
+	assert.Equal(t, 200, status)
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, float64(2), data["number"])
+	assert.Equal(t, 1, len(data["lines"].([]interface{})))
+
+	recipeVersion := models.RecipeVersion{}
+	db.Where("menu_id = ? AND number = ?", 1, 1).First(&recipeVersion)
+	assert.Equal(t, models.RecipeVersionArchived, recipeVersion.Status)
+
+	// perubahan yang gagal tidak membuat versi baru
+	status, _ = requestDisplayOrder(router, http.MethodPut, "/api/v1/menu/1/recipe/1", `{"ingredient_id": 99, "qty": "1 gr"}`)
+	assert.NotEqual(t, 201, status)
+
+	var count int64
+	db.Model(&models.RecipeVersion{}).Where("menu_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+// test versi resep ikut terhapus bersama menunya
+func TestDeleteMenuRemovesRecipeVersions(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	router := setupRecipeVersionRouter(db)
+
+	serveRecipeVersion(router, http.MethodPost, "", `{"lines": [{"ingredient_id": 1, "qty": "300 gr"}]}`, "")
+	status, _ := serveRecipeVersion(router, http.MethodPost, "/1/activate", `{}`, `"1"`)
+	assert.Equal(t, 200, status)
+
+	menuRepository := repository.NewMenuRepository(db)
+	menu, _ := menuRepository.Find(1)
+	err := menuRepository.Delete(menu)
+	assert.Nil(t, err)
+
+	var count int64
+	db.Model(&models.RecipeVersion{}).Where("menu_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.RecipeVersionLine{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+// test tanggal efektif di masa lalu ditolak agar riwayat resep tidak berubah
+func TestActivateRecipeVersionInThePast(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	router := setupRecipeVersionRouter(db)
+
+	serveRecipeVersion(router, http.MethodPost, "", `{"lines": [{"ingredient_id": 1, "qty": "300 gr"}]}`, "")
+
+	yesterday := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	status, _ := serveRecipeVersion(router, http.MethodPost, "/1/activate", `{"effective_from": "`+yesterday+`"}`, `"1"`)
+	assert.Equal(t, 422, status)
+
+	recipeVersion := models.RecipeVersion{}
+	db.First(&recipeVersion, 1)
+	assert.Equal(t, models.RecipeVersionDraft, recipeVersion.Status)
+
+	recipe, _ := repository.NewRecipeRepository(db).FindByMenuAndIngredient(1, 1)
+	assert.Equal(t, "200 gr", recipe.Qty)
+
+	// detik sekarang dianggap sekarang dan langsung berlaku
+	before := time.Now()
+	current := before.UTC().Format(time.RFC3339)
+	status, body := serveRecipeVersion(router, http.MethodPost, "/1/activate", `{"effective_from": "`+current+`"}`, `"1"`)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, true, body["data"].(map[string]interface{})["in_effect"])
+
+	db.First(&recipeVersion, 1)
+	assert.False(t, recipeVersion.EffectiveFrom.Before(before))
+}
+
+// test versi resep yang dipanggil lewat menu lain dianggap tidak ada
+func TestRecipeVersionNotInMenu(t *testing.T) {
+	db := database.SetDbTest()
+	createExampleRecipeForVersion(db)
+	db.Create(&models.Menu{Name: "mie goreng", CategoryId: 1})
+	router := setupRecipeVersionRouter(db)
+
+	serveRecipeVersion(router, http.MethodPost, "", `{"lines": [{"ingredient_id": 1, "qty": "300 gr"}]}`, "")
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/2/versions/1/diff", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 404, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/menu/2/versions/1/activate", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(helper.HeaderIfMatch, `"1"`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 404, rec.Code)
+}
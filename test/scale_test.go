@@ -0,0 +1,105 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/database"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// test hitung ulang recipe untuk jumlah porsi lain
+func TestScaleMenu(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+
+	menu := models.Menu{Name: "nasi goreng", CategoryId: 1, YieldQty: "1,2 kg", Portions: 4}
+	db.Create(&menu)
+	for i, qty := range []string{"800 gr", "2 butir", "2 sdt", "secukupnya", "1/2 cup"} {
+		db.Create(&models.MenuIngredient{MenuId: menu.Id, IngredientId: i + 1, Qty: qty})
+	}
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id/scale", setupMenuController(db).Scale)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1/scale?portions=10", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	assert.Equal(t, 2.5, data["factor"])
+	assert.Equal(t, "3 kg", data["scaled_yield_qty"])
+
+	var scaled []string
+	for _, line := range data["ingredients"].([]interface{}) {
+		scaled = append(scaled, line.(map[string]interface{})["scaled_qty"].(string))
+	}
+	assert.Equal(t, []string{"2 kg", "5 butir", "1.75 tbsp", "secukupnya", "1.25 cup"}, scaled)
+}
+
+// test jumlah porsi wajib diisi
+func TestScaleMenuWithoutPortions(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id/scale", setupMenuController(db).Scale)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1/scale", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, 422, rec.Result().StatusCode)
+}
+
+// test koma dengan tepat tiga angka dibaca sebagai pemisah ribuan
+func TestScaleMenuThousandsSeparator(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleIngredient(db)
+
+	menu := models.Menu{Name: "nasi goreng", CategoryId: 1, YieldQty: "1,200 g", Portions: 4}
+	db.Create(&menu)
+	for i, qty := range []string{"1,200 g", "1,5 kg", "1,2345 kg", "2,500ml", "1,000,000 mg"} {
+		db.Create(&models.MenuIngredient{MenuId: menu.Id, IngredientId: i + 1, Qty: qty})
+	}
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id/scale", setupMenuController(db).Scale)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1/scale?portions=8", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	assert.Equal(t, "2.4 kg", data["scaled_yield_qty"])
+
+	var scaled []string
+	for _, line := range data["ingredients"].([]interface{}) {
+		scaled = append(scaled, line.(map[string]interface{})["scaled_qty"].(string))
+	}
+	assert.Equal(t, []string{"2.4 kg", "3 kg", "2.47 kg", "5 l", "2 kg"}, scaled)
+}
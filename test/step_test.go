@@ -0,0 +1,152 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupStepController(db *gorm.DB) *controllers.StepController {
+	stepRepository := repository.NewStepRepository(db)
+	menuRepository := repository.NewMenuRepository(db)
+	stepService := service.NewStepService(repository.NewTransactionManager(db), stepRepository, menuRepository, setupAuditService(db))
+	return controllers.NewStepController(stepService)
+}
+
+func createExampleSteps(db *gorm.DB) {
+	stepRepository := repository.NewStepRepository(db)
+	for _, text := range []string{"cuci beras", "masak nasi", "goreng nasi"} {
+		stepRepository.Create(models.MenuStep{MenuId: 1, Text: text})
+	}
+}
+
+// test tambah step ke menu, posisi otomatis di akhir
+func TestCreateStep(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+	createExampleSteps(db)
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/menu/:menu_id/steps", setupStepController(db).Create)
+
+	body := `{"text": "sajikan", "duration_minutes": 2, "station": "pass", "image_url": "https://example.com/sajikan.jpg"}`
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/menu/1/steps", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 201, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].(map[string]interface{})
+	assert.Equal(t, float64(4), data["position"])
+	assert.Equal(t, "pass", data["station"])
+}
+
+// test ubah urutan step
+func TestReorderStep(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+	createExampleSteps(db)
+
+	router := libraries.SetRouter()
+	router.PUT("api/v1/menu/:menu_id/steps/order", setupStepController(db).Reorder)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1/steps/order", strings.NewReader(`{"step_ids": [3, 1, 2]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+	fmt.Println(responseBodyMap)
+
+	data := responseBodyMap["data"].([]interface{})
+	assert.Equal(t, "goreng nasi", data[0].(map[string]interface{})["text"])
+	assert.Equal(t, "cuci beras", data[1].(map[string]interface{})["text"])
+
+	// urutan harus menyebut semua step
+	req = httptest.NewRequest(http.MethodPut, "http://localhost:8000/api/v1/menu/1/steps/order", strings.NewReader(`{"step_ids": [3, 1]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 400, rec.Result().StatusCode)
+}
+
+// test hapus step, posisi step setelahnya bergeser
+func TestDeleteStep(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+	createExampleSteps(db)
+
+	router := libraries.SetRouter()
+	router.DELETE("api/v1/menu/:menu_id/steps/:id", setupStepController(db).Delete)
+
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/menu/1/steps/1", nil)
+	req.Header.Set(helper.HeaderIfMatch, `"1"`)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Result().StatusCode)
+
+	steps, _ := repository.NewStepRepository(db).All(1)
+	assert.Equal(t, 2, len(steps))
+	assert.Equal(t, 1, steps[0].Position)
+	assert.Equal(t, "masak nasi", steps[0].Text)
+}
+
+// test detail menu menampilkan step
+func TestGetMenuWithSteps(t *testing.T) {
+	db := database.SetDbTest()
+	createBulkExampleCategory(db)
+	createBulkExampleMenu(db)
+	createExampleSteps(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/menu/:id", setupMenuController(db).Get)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/menu/1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var responseBodyMap map[string]interface{}
+	json.Unmarshal(responseBody, &responseBodyMap)
+
+	steps := responseBodyMap["data"].(map[string]interface{})["steps"].([]interface{})
+	assert.Equal(t, 3, len(steps))
+	assert.Equal(t, "cuci beras", steps[0].(map[string]interface{})["text"])
+}
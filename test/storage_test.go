@@ -0,0 +1,169 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/erp_app/config"
+	"github.com/erp_app/libraries"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 menyimpan object di memori seperti bucket MinIO
+type fakeS3 struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3() *httptest.Server {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	return httptest.NewServer(fake)
+}
+
+func (fake *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	// setiap request harus ditandatangani dengan signature v4
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=minio/") ||
+		!strings.Contains(authorization, "/us-east-1/s3/aws4_request, SignedHeaders=") ||
+		!strings.Contains(authorization, "host;x-amz-content-sha256;x-amz-date, Signature=") ||
+		r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/menu-photos/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/menu-photos/")
+
+	switch r.Method {
+	case http.MethodPut:
+		fake.objects[key] = body
+		fake.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		content, ok := fake.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", fake.types[key])
+		w.Write(content)
+	case http.MethodDelete:
+		delete(fake.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setupS3Storage(t *testing.T, endpoint string, baseUrl string) libraries.Storage {
+	storage, err := libraries.NewStorage(config.Storage{
+		Driver:    "s3",
+		BaseUrl:   baseUrl,
+		Endpoint:  endpoint,
+		Bucket:    "menu-photos",
+		Region:    "us-east-1",
+		AccessKey: "minio",
+		SecretKey: "minio-secret",
+	})
+	assert.Nil(t, err)
+	return storage
+}
+
+// test simpan, ambil dan hapus file lewat driver s3
+func TestS3Storage(t *testing.T) {
+	server := newFakeS3()
+	defer server.Close()
+
+	storage := setupS3Storage(t, server.URL, "/uploads")
+
+	err := storage.Put("menus/1/foto asli.png", strings.NewReader("isi foto"), "image/png")
+	assert.Nil(t, err)
+
+	// url mengikuti endpoint dan bucket bila base url bukan alamat lengkap
+	url := storage.URL("menus/1/foto asli.png")
+	assert.Equal(t, server.URL+"/menu-photos/menus/1/foto%20asli.png", url)
+
+	response, err := http.Get(url)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	file, err := storage.Get("menus/1/foto asli.png")
+	assert.Nil(t, err)
+	content, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "isi foto", string(content))
+
+	err = storage.Delete("menus/1/foto asli.png")
+	assert.Nil(t, err)
+
+	// file yang sudah dihapus dianggap tidak ada, hapus ulang bukan error
+	_, err = storage.Get("menus/1/foto asli.png")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.Nil(t, storage.Delete("menus/1/foto asli.png"))
+
+	// key di luar bucket ditolak sebelum request dikirim
+	err = storage.Put("../rahasia.png", strings.NewReader("isi"), "image/png")
+	assert.Equal(t, libraries.ErrInvalidStorageKey, err)
+}
+
+// test base url lengkap dipakai untuk url file di s3
+func TestS3StorageBaseUrl(t *testing.T) {
+	storage := setupS3Storage(t, "http://127.0.0.1:9000", "https://cdn.example.com/menu-photos/")
+	assert.Equal(t, "https://cdn.example.com/menu-photos/menus/1/foto.png", storage.URL("menus/1/foto.png"))
+}
+
+// test error dari s3 diteruskan ke pemanggil
+func TestS3StorageError(t *testing.T) {
+	server := newFakeS3()
+	defer server.Close()
+
+	storage, _ := libraries.NewStorage(config.Storage{
+		Driver:    "s3",
+		Endpoint:  server.URL,
+		Bucket:    "menu-photos",
+		Region:    "eu-west-1",
+		AccessKey: "minio",
+		SecretKey: "minio-secret",
+	})
+
+	err := storage.Put("menus/1/foto.png", strings.NewReader("isi foto"), "image/png")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+// test konfigurasi s3 wajib lengkap
+func TestS3StorageConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Driver = "s3"
+
+	err := cfg.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "storage endpoint must be an http or https url")
+	assert.Contains(t, err.Error(), "storage bucket is required")
+	assert.Contains(t, err.Error(), "storage access key and secret key are required")
+
+	cfg.Storage.Endpoint = "http://127.0.0.1:9000"
+	cfg.Storage.Bucket = "menu-photos"
+	cfg.Storage.AccessKey = "minio"
+	cfg.Storage.SecretKey = "minio-secret"
+	assert.Nil(t, cfg.Validate())
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erp_app/controllers"
+	"github.com/erp_app/database"
+	"github.com/erp_app/helper"
+	"github.com/erp_app/libraries"
+	"github.com/erp_app/models"
+	"github.com/erp_app/repository"
+	"github.com/erp_app/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type syncResponseBody struct {
+	Data struct {
+		Token      string `json:"token"`
+		HasMore    bool   `json:"has_more"`
+		Categories []struct {
+			Id   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"categories"`
+		Deleted []struct {
+			Entity string `json:"entity"`
+			Id     int    `json:"id"`
+		} `json:"deleted"`
+	} `json:"data"`
+}
+
+func setupSyncController(db *gorm.DB) *controllers.SyncController {
+	syncService := service.NewSyncService(
+		repository.NewChangeRepository(db),
+		repository.NewCategoryRepository(db),
+		repository.NewIngredientGroupRepository(db),
+		repository.NewIngredientRepository(db),
+		repository.NewMenuRepository(db),
+		repository.NewRecipeRepository(db),
+		setupStorage(),
+	)
+	return controllers.NewSyncController(syncService)
+}
+
+func getSync(t *testing.T, router *echo.Echo, query string) syncResponseBody {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/sync"+query, nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 200, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	var data syncResponseBody
+	err := json.Unmarshal(responseBody, &data)
+	assert.NoError(t, err)
+
+	fmt.Println(data)
+
+	return data
+}
+
+// test sync mengirim perubahan dan tombstone sejak token terakhir
+func TestSyncSuccess(t *testing.T) {
+	db := database.SetDbTest()
+
+	categoryController := setupCategoryController(db)
+	syncController := setupSyncController(db)
+
+	router := libraries.SetRouter()
+	router.POST("api/v1/category", categoryController.Create)
+	router.DELETE("api/v1/category/:id", categoryController.Delete)
+	router.GET("api/v1/sync", syncController.Get)
+
+	for _, name := range []string{"makanan", "minuman"} {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:8000/api/v1/category", strings.NewReader(`{"name" : "`+name+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, 201, rec.Result().StatusCode)
+	}
+
+	first := getSync(t, router, "")
+	assert.Len(t, first.Data.Categories, 2)
+	assert.Len(t, first.Data.Deleted, 0)
+	assert.False(t, first.Data.HasMore)
+	assert.NotEmpty(t, first.Data.Token)
+
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8000/api/v1/category/1", nil)
+	req.Header.Set(helper.HeaderIfMatch, helper.ETag(1))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Result().StatusCode)
+
+	second := getSync(t, router, "?since="+first.Data.Token)
+	assert.Len(t, second.Data.Categories, 0)
+	assert.Len(t, second.Data.Deleted, 1)
+	assert.Equal(t, "category", second.Data.Deleted[0].Entity)
+	assert.Equal(t, 1, second.Data.Deleted[0].Id)
+
+	third := getSync(t, router, "?since="+second.Data.Token)
+	assert.Len(t, third.Data.Categories, 0)
+	assert.Len(t, third.Data.Deleted, 0)
+	assert.Equal(t, second.Data.Token, third.Data.Token)
+}
+
+// test sync dengan token yang tidak valid
+func TestSyncFailInvalidToken(t *testing.T) {
+	db := database.SetDbTest()
+	syncController := setupSyncController(db)
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/sync", syncController.Get)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8000/api/v1/sync?since=bukan-token", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	assert.Equal(t, 400, result.StatusCode)
+
+	responseBody, _ := io.ReadAll(result.Body)
+	fmt.Println(string(responseBody))
+}
+
+// test sync berhenti di depan id yang belum ada sampai perubahan sesudahnya lewat masa tunggu
+func TestSyncWaitsForGap(t *testing.T) {
+	db := database.SetDbTest()
+	db.Create(&models.Category{Name: "makanan"})
+	db.Create(&models.Category{Name: "minuman"})
+
+	// id 2 dipakai transaksi yang belum commit
+	db.Create(&models.Change{Id: 1, EntityType: "category", EntityId: 1, Action: models.AuditActionCreate})
+	db.Create(&models.Change{Id: 3, EntityType: "category", EntityId: 2, Action: models.AuditActionCreate})
+
+	router := libraries.SetRouter()
+	router.GET("api/v1/sync", setupSyncController(db).Get)
+
+	first := getSync(t, router, "")
+	assert.Len(t, first.Data.Categories, 1)
+	assert.Equal(t, "makanan", first.Data.Categories[0].Name)
+	assert.False(t, first.Data.HasMore)
+
+	second := getSync(t, router, "?since="+first.Data.Token)
+	assert.Len(t, second.Data.Categories, 0)
+	assert.Equal(t, first.Data.Token, second.Data.Token)
+
+	// transaksinya ternyata rollback, perubahan sesudahnya dikirim setelah masa tunggu
+	db.Model(&models.Change{}).Where("id = ?", 3).Update("created_at", time.Now().Add(-2*time.Minute))
+
+	third := getSync(t, router, "?since="+first.Data.Token)
+	assert.Len(t, third.Data.Categories, 1)
+	assert.Equal(t, "minuman", third.Data.Categories[0].Name)
+}